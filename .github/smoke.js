@@ -0,0 +1,57 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+
+import { detectProject, isWithin } from '../src/detector.js';
+import { expandPaths } from '../src/config.js';
+import { runCommand } from '../src/process.js';
+
+// Platform-specific code paths, run on every OS in CI: detection below a path with
+// spaces (drive letters and backslashes on Windows) or reached through a symlink, and
+// arguments reaching .cmd scripts
+const WINDOWS = process.platform === 'win32';
+
+function check(condition, what) {
+  if (!condition) {
+    throw new Error(`Smoke check failed: ${what}`);
+  }
+  console.log(`ok - ${what}`);
+}
+
+const root = fs.mkdtempSync(path.join(os.tmpdir(), 'jmw smoke '));
+try {
+  const basePath = path.join(root, 'Work', 'Demo');
+  const modulePath = path.join(basePath, 'web');
+  fs.mkdirSync(modulePath, { recursive: true });
+  fs.writeFileSync(path.join(modulePath, 'pom.xml'),
+    '<project><groupId>smoke</groupId><artifactId>demo-web</artifactId><version>1.0</version><packaging>war</packaging></project>');
+
+  const config = { projects: { demo: { base_path: WINDOWS ? basePath.toUpperCase() : basePath } } };
+  const detection = detectProject(config, modulePath);
+  check(detection.project === 'demo', 'project detected by base_path');
+  check(detection.module.artifactId === 'demo-web', 'module read from pom.xml');
+  check(isWithin(modulePath, basePath), 'module path within base_path');
+  check(expandPaths({ dir: '~/x' }).dir === path.join(os.homedir(), 'x'), '~ expanded to the home directory');
+
+  // Workspaces symlinked from elsewhere (a NAS): a linked cwd under the real base_path,
+  // and the real cwd under a linked base_path. Junctions need no privileges on Windows
+  const link = path.join(root, 'Linked');
+  fs.symlinkSync(basePath, link, 'junction');
+  check(detectProject(config, path.join(link, 'web')).project === 'demo', 'symlinked cwd matches the real base_path');
+  const linkedConfig = { projects: { demo: { base_path: link } } };
+  check(detectProject(linkedConfig, modulePath).project === 'demo', 'real cwd matches a symlinked base_path');
+
+  if (WINDOWS) {
+    // A .cmd script printing its arguments as the process behind it receives them
+    const printer = path.join(root, 'print-args.js');
+    const script = path.join(root, 'print args.cmd');
+    fs.writeFileSync(printer, 'console.log(JSON.stringify(process.argv.slice(2)));\n');
+    fs.writeFileSync(script, `@"${process.execPath}" "${printer}" %*\r\n`);
+    const args = ['plain', 'with space', 'a&b|c', '50%', 'x^y', 'quote"d', 'C:\\Program Files\\'];
+    const lines = [];
+    await runCommand(script, args, { onLine: line => lines.push(line) });
+    check(lines.at(-1) === JSON.stringify(args), '.cmd script receives its arguments unchanged');
+  }
+} finally {
+  fs.rmSync(root, { recursive: true, force: true });
+}
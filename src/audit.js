@@ -0,0 +1,108 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import chalk from 'chalk';
+
+import { getConfigDir } from './config.js';
+import { sha256File } from './history.js';
+import { runRemote, shellQuote } from './remote.js';
+
+/**
+ * Path of the audit log (one JSON record per line)
+ * `audit.file` may point at a shared location so the whole team appends to one log
+ */
+function getAuditPath(auditConfig = {}) {
+  return auditConfig.file || path.join(getConfigDir(), 'audit.jsonl');
+}
+
+/**
+ * Start an audit trail for one artifact; returns record(action, target, result, error)
+ * The checksum is computed once and reused for every record. With checksum: false the
+ * artifact is only named, e.g. a deployment enabled or disabled on the server
+ */
+async function createAuditTrail(auditConfig, detection, artifactPath, { checksum = true } = {}) {
+  const base = {
+    user: os.userInfo().username,
+    machine: os.hostname(),
+    project: detection.project,
+    module: detection.module.artifactId,
+    artifact: path.basename(artifactPath),
+    sha256: checksum && fs.existsSync(artifactPath) ? await sha256File(artifactPath) : null
+  };
+
+  return (action, target, result, error) => recordAudit(auditConfig, {
+    ...base,
+    action,
+    client: target.client || null,
+    env: target.env || null,
+    host: target.host,
+    result,
+    ...(error ? { error } : {})
+  });
+}
+
+/**
+ * Append a record to the audit log, and to the remote audit file when configured
+ * A failing remote append only warns, the deployment itself already happened
+ */
+async function recordAudit(auditConfig = {}, entry) {
+  const line = JSON.stringify({ timestamp: new Date().toISOString(), ...entry });
+
+  const auditPath = getAuditPath(auditConfig);
+  fs.mkdirSync(path.dirname(auditPath), { recursive: true });
+  fs.appendFileSync(auditPath, line + '\n');
+
+  const remote = auditConfig.remote;
+  if (remote) {
+    try {
+      await runRemote(remote, `printf '%s\\n' ${shellQuote(line)} >> ${shellQuote(remote.path)}`);
+    } catch (error) {
+      console.log(chalk.yellow(`Could not append to audit log on ${remote.host}: ${error.message}`));
+    }
+  }
+}
+
+/**
+ * Read audit records, newest first, optionally filtered
+ */
+function readAudit(auditConfig, filter = {}) {
+  const auditPath = getAuditPath(auditConfig);
+  if (!fs.existsSync(auditPath)) {
+    return [];
+  }
+
+  return fs.readFileSync(auditPath, 'utf8')
+    .split('\n')
+    .filter(line => line.trim())
+    .map(line => {
+      try {
+        return JSON.parse(line);
+      } catch (error) {
+        return null;
+      }
+    })
+    .filter(entry => entry && Object.entries(filter).every(([key, value]) => value === undefined || entry[key] === value))
+    .reverse();
+}
+
+/**
+ * Display audit records
+ */
+function showAudit(entries) {
+  const colors = { deployed: chalk.green, undeployed: chalk.yellow, rolled_back: chalk.yellow, queued: chalk.yellow, failed: chalk.red, skipped: chalk.gray };
+
+  for (const entry of entries) {
+    const color = colors[entry.result] || chalk.white;
+    const target = entry.client ? `${entry.client}${entry.env ? `/${entry.env}` : ''}@${entry.host}` : entry.host;
+    console.log(`${chalk.white.bold(new Date(entry.timestamp).toLocaleString())}  ${entry.action.padEnd(8)} ${color(entry.result.padEnd(11))} ${target}  ${entry.user}@${entry.machine}`);
+    console.log(`  ${entry.sha256 || 'no checksum'}  ${entry.project}/${entry.module} ${entry.artifact}${entry.error ? chalk.red(` - ${entry.error}`) : ''}`);
+  }
+}
+
+export {
+  getAuditPath,
+  createAuditTrail,
+  recordAudit,
+  readAudit,
+  showAudit
+};
@@ -1,35 +1,91 @@
 import path from 'path';
 import { $ } from 'bun';
 import chalk from 'chalk';
-import readline from 'readline';
 import fs from 'fs';
+import os from 'os';
+import { runCommand } from './process.js';
+import { parsePom, getDependencies, findDependents, getModuleMap, findModuleEntry, getReactorModules, readPomInfo, getFinalName, isWithin, getRequiredJavaVersion, parseJavaMajor } from './detector.js';
+import { getMachineContext, getJdkVersion, resolveActiveProfiles, showProfiles, findUndefinedProfiles } from './profiles.js';
+import { suggestKey } from './schema.js';
+import { checksumArtifacts, getGitSha, isGitDirty, recordBuild, readHistory } from './history.js';
+import { reportReproducibility } from './reproducible.js';
+import { confirmAction, select, isInteractive } from './confirm.js';
+import { emitProgress } from './progress.js';
+import { isQuiet } from './output.js';
+import { readZipEntries } from './zip.js';
+import { getGradleCommand, getTaskPrefix } from './gradle.js';
+import { getMavenCommand } from './maven.js';
+
+// Packagings deployed to WildFly as they are; other modules are installed for their dependents
+const DEPLOYABLE_PACKAGINGS = ['war', 'ear', 'rar'];
 
 /**
- * Build a Maven module
+ * Build a Maven or Gradle module
  */
 async function buildModule(detection, profile, options = {}) {
-  const { project, projectConfig, restartRules, module: moduleInfo } = detection;
+  const { project, projectConfig, restartRules } = detection;
+  let moduleInfo = detection.module;
   const skipTests = options.skipTests || projectConfig.skip_tests || false;
 
   console.log(chalk.blue('=== Build Plan ==='));
   console.log(`Project: ${project}`);
   console.log(`Module: ${moduleInfo.artifactId}`);
   console.log(`Packaging: ${moduleInfo.packaging}`);
+  if (moduleInfo.version) {
+    console.log(`Version: ${moduleInfo.version}`);
+  }
   console.log(`Path: ${moduleInfo.path}`);
+  if (projectConfig.unit) {
+    console.log(`Unit: ${projectConfig.unit}`);
+  }
+  if (projectConfig.instance) {
+    console.log(`Instance: ${projectConfig.instance}`);
+  }
+  if (projectConfig.maven_settings) {
+    console.log(`Settings: ${getMavenSettings(projectConfig).settings}`);
+  }
   console.log('');
 
+  // Aggregator POMs build their children from their own directory when asked to, or only install themselves
+  if (moduleInfo.packaging === 'pom' && options.buildChildren) {
+    console.log(`Child modules: ${moduleInfo.modules.join(', ')}`);
+    console.log('');
+    moduleInfo = { ...moduleInfo, buildChildren: true, isMultiModule: false };
+  }
+
   // Show profile
-  const effectiveProfile = profile || projectConfig.default_profile || 'none';
+  const effectiveProfile = resolveProfile(profile, projectConfig);
   console.log(`Profile: ${effectiveProfile}`);
+  warnSnapshots(detection, effectiveProfile);
+  if (moduleInfo.buildSystem === 'maven') {
+    checkProfilesDefined(detection, effectiveProfile);
+    await checkJavaVersion(detection);
+  }
 
-  // Build Maven command
-  const cmdArgs = buildMavenCommand(moduleInfo, effectiveProfile, skipTests, projectConfig);
+  // Build command
+  const { command, args: cmdArgs } = getBuildCommand(moduleInfo, effectiveProfile, skipTests, projectConfig);
 
-  console.log(chalk.yellow('Command:'), 'mvn', cmdArgs.join(' '));
+  console.log(chalk.yellow('Command:'), command, cmdArgs.join(' '));
+  if (projectConfig.maven_opts && moduleInfo.buildSystem === 'maven') {
+    console.log(chalk.yellow('MAVEN_OPTS:'), projectConfig.maven_opts);
+  }
   console.log('');
 
+  // Show profiles Maven will actually activate, including <activation>-triggered ones
+  if (moduleInfo.buildSystem === 'maven') {
+    const resolution = await resolveProfilesForBuild(detection, effectiveProfile, cmdArgs);
+    if (resolution.profiles.length > 0) {
+      console.log(chalk.blue('=== Active Profiles ==='));
+      showProfiles(resolution);
+      console.log('');
+    }
+  }
+
   // Confirm build
-  const confirmed = await confirm('Proceed with build?');
+  const confirmed = await confirmAction(detection.confirmations, 'build', {
+    message: 'Proceed with build?',
+    environment: effectiveProfile
+  });
   if (!confirmed) {
     console.log(chalk.red('Build cancelled'));
     return;
@@ -37,25 +93,294 @@ async function buildModule(detection, profile, options = {}) {
 
   // Execute build
   try {
-    const cwd = moduleInfo.isMultiModule ? projectConfig.base_path : moduleInfo.path;
+    const cwd = moduleInfo.isMultiModule ? moduleInfo.reactorRoot : moduleInfo.path;
 
-    // Execute Maven command with Bun's $ shell
-    await $`cd ${cwd} && mvn ${cmdArgs}`;
+    // Execute the build in its own process group so Ctrl-C takes down forked JVMs too
+    const runBuild = () => runCommand(command, cmdArgs, { cwd, env: getMavenEnv(projectConfig) });
+    emitProgress('build', 0, `Building ${moduleInfo.artifactId}`);
+    await runBuild();
 
     console.log(chalk.green('Build completed successfully'));
+    emitProgress('build', 100, 'Build completed');
 
     // Show artifacts, restart guidance, and get artifact path
-    const artifactPath = await showArtifactsAndGuidance(moduleInfo, restartRules);
+    const artifactPath = await showArtifactsAndGuidance(moduleInfo, restartRules, projectConfig.shortcuts);
+
+    // Record artifact checksums in build history
+    const artifacts = await recordArtifacts(detection, effectiveProfile);
+
+    if (options.verifyReproducible) {
+      const reproducible = await verifyReproducible(detection, effectiveProfile, artifacts, runBuild);
+      if (!reproducible) {
+        process.exitCode = 1;
+      }
+    }
+
+    // Shared modules: the deployables using them likely need a rebuild too
+    await offerDependentsBuild(detection, moduleInfo, effectiveProfile, skipTests);
 
     // Return the artifact path for caller to use
     return artifactPath;
 
   } catch (error) {
     console.error(chalk.red('Build failed:'), error.message);
+    emitProgress('build', null, 'Build failed', { error: error.message });
     throw error;
   }
 }
 
+/**
+ * List the deployable reactor modules depending on a freshly built shared module,
+ * and build them together on confirmation
+ */
+async function offerDependentsBuild(detection, moduleInfo, profile, skipTests) {
+  const { projectConfig } = detection;
+  if (moduleInfo.buildSystem !== 'maven' || moduleInfo.packaging === 'pom' || DEPLOYABLE_PACKAGINGS.includes(moduleInfo.packaging)) {
+    return;
+  }
+
+  const dependents = findDependents(moduleInfo, projectConfig)
+    .filter(module => DEPLOYABLE_PACKAGINGS.includes(module.packaging));
+  if (dependents.length === 0) {
+    return;
+  }
+
+  console.log('');
+  console.log(chalk.blue('=== Dependent Modules ==='));
+  for (const module of dependents) {
+    const note = module.configured ? '' : chalk.gray(' (not in the modules map)');
+    console.log(`  ${module.artifactId} ${chalk.gray(`(${module.packaging})`)}${note}`);
+  }
+
+  const confirmed = await confirmAction(detection.confirmations, 'build_dependents', {
+    message: `Build the ${dependents.length} dependent module(s) now?`,
+    environment: profile
+  });
+  if (!confirmed) {
+    return;
+  }
+
+  const selection = {
+    packaging: 'war',
+    settings: {},
+    isMultiModule: true,
+    relativePath: dependents.map(module => path.relative(moduleInfo.reactorRoot, module.path)).join(',')
+  };
+  const args = buildMavenCommand(selection, profile, skipTests, projectConfig);
+  const command = getMavenCommand(moduleInfo.reactorRoot);
+  console.log(chalk.yellow('Command:'), command, args.join(' '));
+  console.log('');
+
+  await runCommand(command, args, { cwd: moduleInfo.reactorRoot, env: getMavenEnv(projectConfig) });
+  console.log(chalk.green('Dependent modules built'));
+}
+
+/**
+ * Reactor modules with files changed since a base ref (committed, staged, unstaged
+ * or untracked), each file counting for the deepest module containing it
+ */
+async function findChangedModules(reactorRoot, baseRef) {
+  const top = (await $`cd ${reactorRoot} && git rev-parse --show-toplevel`.quiet().text()).trim();
+  const committed = await $`cd ${reactorRoot} && git diff --name-only ${`${baseRef}...HEAD`}`.quiet().nothrow();
+  if (committed.exitCode !== 0) {
+    throw new Error(`Cannot diff against ${baseRef}: ${committed.stderr.toString().trim()}`);
+  }
+  const uncommitted = await $`cd ${reactorRoot} && git diff --name-only HEAD`.quiet().text();
+  const untracked = await $`cd ${reactorRoot} && git ls-files --others --exclude-standard --full-name`.quiet().text();
+
+  const files = [committed.stdout.toString(), uncommitted, untracked]
+    .flatMap(output => output.split('\n'))
+    .filter(Boolean)
+    .map(file => path.resolve(top, file));
+
+  const modules = getReactorModules(path.join(reactorRoot, 'pom.xml'))
+    .sort((a, b) => b.path.length - a.path.length);
+  const changed = new Map();
+  for (const file of files) {
+    const module = modules.find(candidate => isWithin(file, candidate.path));
+    if (module && !changed.has(module.path)) {
+      changed.set(module.path, { ...readPomInfo(path.join(module.path, 'pom.xml')), path: module.path });
+    }
+  }
+  return [...changed.values()].sort((a, b) => a.path.localeCompare(b.path));
+}
+
+/**
+ * Build the deployable reactor modules changed since a base ref (default origin/main)
+ * in one Maven run, with withDependents also the deployable modules depending on them
+ */
+async function buildChangedModules(detection, profile, options = {}) {
+  const { projectConfig, module: moduleInfo } = detection;
+  const reactorRoot = moduleInfo.reactorRoot;
+  const baseRef = options.base || 'origin/main';
+  const skipTests = options.skipTests || projectConfig.skip_tests || false;
+  const relative = module => path.relative(reactorRoot, module.path) || '.';
+
+  console.log(chalk.blue('=== Changed Modules ==='));
+  console.log(`Project: ${detection.project}`);
+  console.log(`Reactor: ${reactorRoot}`);
+  console.log(`Base: ${baseRef}`);
+
+  // Only deployable modules are built: war/ear/ejb/rar packaging, or listed in the modules map
+  const moduleMap = getModuleMap(projectConfig);
+  const isDeployable = module => [...DEPLOYABLE_PACKAGINGS, 'ejb'].includes(module.packaging)
+    || findModuleEntry(moduleMap, [module.artifactId, path.basename(module.path)]) !== undefined;
+
+  const changed = await findChangedModules(reactorRoot, baseRef);
+  if (changed.length === 0) {
+    console.log(chalk.green('No module changed'));
+    return;
+  }
+  changed.forEach(module => console.log(`  ${relative(module)} ${chalk.gray(`(${module.packaging})${isDeployable(module) ? '' : ' not deployable, skipped'}`)}`));
+
+  const selected = new Map(changed.filter(isDeployable).map(module => [module.path, module]));
+  if (selected.size === 0) {
+    console.log(chalk.green('No deployable module changed'));
+    return;
+  }
+  if (options.withDependents) {
+    const dependents = [...selected.values()]
+      .flatMap(module => findDependents({ ...module, reactorRoot }, projectConfig))
+      .filter(module => !selected.has(module.path) && isDeployable(module));
+    dependents.forEach(module => selected.set(module.path, module));
+    if (dependents.length > 0) {
+      console.log('Dependents:');
+      [...new Set(dependents.map(relative))].forEach(dir => console.log(`  ${dir}`));
+    }
+  }
+  console.log('');
+
+  const effectiveProfile = resolveProfile(profile, projectConfig);
+  console.log(`Profile: ${effectiveProfile}`);
+  checkProfilesDefined(detection, effectiveProfile);
+
+  // Installed, so builds of other modules outside this run pick them up
+  const selection = {
+    packaging: 'jar',
+    settings: {},
+    isMultiModule: true,
+    relativePath: [...selected.values()].map(relative).join(',')
+  };
+  const args = buildMavenCommand(selection, effectiveProfile, skipTests, projectConfig);
+  const command = getMavenCommand(reactorRoot);
+  console.log(chalk.yellow('Command:'), command, args.join(' '));
+  console.log('');
+
+  const confirmed = await confirmAction(detection.confirmations, 'build', {
+    message: `Build ${selected.size} module(s)?`,
+    environment: effectiveProfile
+  });
+  if (!confirmed) {
+    console.log(chalk.red('Build cancelled'));
+    return;
+  }
+
+  emitProgress('build', 0, `Building ${selected.size} changed module(s)`);
+  await runCommand(command, args, { cwd: reactorRoot, env: getMavenEnv(projectConfig) });
+  console.log(chalk.green('Build completed successfully'));
+  emitProgress('build', 100, 'Build completed');
+}
+
+/**
+ * Warn when a release profile (release_profiles, default PROD) builds a SNAPSHOT
+ * version or pulls in SNAPSHOT dependencies, which may change under the release
+ */
+function warnSnapshots(detection, profile) {
+  const { projectConfig, pomPath, module: moduleInfo } = detection;
+  const releaseProfiles = projectConfig.release_profiles ?? ['PROD'];
+  if (!releaseProfiles.some(name => name.toLowerCase() === profile.toLowerCase())) {
+    return;
+  }
+
+  // Dependencies are only known for Maven modules
+  const snapshots = (pomPath ? getDependencies(pomPath) : [])
+    .filter(dependency => dependency.version.endsWith('-SNAPSHOT'))
+    .map(dependency => `${dependency.groupId}:${dependency.artifactId}:${dependency.version}`);
+
+  if (moduleInfo.version?.endsWith('-SNAPSHOT')) {
+    console.log(chalk.red.bold(`WARNING: building ${profile} from SNAPSHOT version ${moduleInfo.version}`));
+  }
+  if (snapshots.length > 0) {
+    console.log(chalk.red.bold(`WARNING: ${profile} build depends on ${snapshots.length} SNAPSHOT(s):`));
+    snapshots.forEach(snapshot => console.log(chalk.red(`  ${snapshot}`)));
+  }
+}
+
+/**
+ * Fail on Maven profiles that no POM or settings.xml defines, since Maven would
+ * silently build without them (e.g. a mistyped `jmw build TETS`)
+ */
+function checkProfilesDefined(detection, profile) {
+  const { missing, known } = findUndefinedProfiles(detection, getProfiles(profile, detection.projectConfig));
+  if (missing.length === 0) {
+    return;
+  }
+
+  const problems = missing.map(id => {
+    const suggestion = suggestKey(id, known);
+    return `${id}${suggestion ? ` (did you mean ${suggestion}?)` : ''}`;
+  });
+  const [noun, verb] = missing.length > 1 ? ['Profiles', 'are'] : ['Profile', 'is'];
+  throw new Error(`${noun} ${problems.join(', ')} ${verb} not defined in the POMs or settings.xml`);
+}
+
+/**
+ * Fail before Maven starts when the module compiles for a newer Java than the JDK
+ * Maven would run with; older targets are fine, as javac can still produce them
+ */
+async function checkJavaVersion(detection) {
+  const required = getRequiredJavaVersion(detection.pomPath);
+  const jdkVersion = required ? await getJdkVersion() : null;
+  if (!jdkVersion) {
+    return;
+  }
+
+  const installed = parseJavaMajor(jdkVersion);
+  console.log(`Java: ${required} (JDK ${jdkVersion})`);
+  if (installed < required) {
+    throw new Error(`${detection.module.artifactId} needs Java ${required}, you have ${installed} (set JAVA_HOME to a newer JDK)`);
+  }
+}
+
+/**
+ * Build tool and arguments for a module
+ */
+function getBuildCommand(moduleInfo, profile, skipTests, projectConfig) {
+  if (moduleInfo.buildSystem === 'gradle') {
+    return { command: getGradleCommand(moduleInfo.reactorRoot), args: buildGradleCommand(moduleInfo, profile, skipTests, projectConfig) };
+  }
+  return { command: getMavenCommand(moduleInfo.reactorRoot), args: buildMavenCommand(moduleInfo, profile, skipTests, projectConfig) };
+}
+
+/**
+ * Build Gradle command arguments, mirroring the Maven ones: Gradle has no profiles,
+ * so the profile's Maven profiles are passed as -Pprofile and build properties as -P
+ */
+function buildGradleCommand(moduleInfo, profile, skipTests, projectConfig) {
+  const prefix = getTaskPrefix(moduleInfo);
+  const tasks = moduleInfo.settings?.build_goal?.split(/\s+/).filter(Boolean) ?? ['clean', 'build'];
+  const args = tasks.map(task => task.startsWith(':') ? task : prefix + task);
+
+  const profiles = getProfiles(profile, projectConfig).filter(name => !name.startsWith('!'));
+  if (profiles.length > 0) {
+    args.push(`-Pprofile=${profiles.join(',')}`);
+  }
+
+  if (skipTests) {
+    args.push('-x', `${prefix}test`);
+  }
+
+  for (const [key, value] of Object.entries(getBuildProperties(profile, projectConfig))) {
+    args.push(`-P${key}=${value}`);
+  }
+
+  if (isQuiet()) {
+    args.push('-q');
+  }
+
+  return args;
+}
+
 /**
  * Build Maven command arguments
  */
@@ -65,15 +390,25 @@ function buildMavenCommand(moduleInfo, profile, skipTests, projectConfig) {
   // Always start with clean
   args.push('clean');
 
-  // Lifecycle phase based on packaging type
-  // WAR: final deployable, just package
-  // JAR: library that other modules depend on, install to local repo
-  if (moduleInfo.packaging === 'war') {
+  // Per-project settings.xml (mirrors, server credentials)
+  args.push(...getMavenSettingsArgs(projectConfig));
+
+  // Lifecycle phase based on packaging type, unless the module sets build_goal
+  // WAR/EAR/RAR: final deployable, just package
+  // JAR/EJB/POM: something other modules depend on, install to local repo
+  if (moduleInfo.settings?.build_goal) {
+    args.push(...moduleInfo.settings.build_goal.split(/\s+/).filter(Boolean));
+  } else if (DEPLOYABLE_PACKAGINGS.includes(moduleInfo.packaging)) {
     args.push('package');
   } else {
     args.push('install');
   }
 
+  // An aggregator without its children
+  if (moduleInfo.packaging === 'pom' && !moduleInfo.buildChildren) {
+    args.push('-N');
+  }
+
   // Multi-module specific - use relative path for -pl
   if (moduleInfo.isMultiModule) {
     args.push('-pl', moduleInfo.relativePath);
@@ -91,9 +426,114 @@ function buildMavenCommand(moduleInfo, profile, skipTests, projectConfig) {
     args.push('-DskipTests=true');
   }
 
+  // Only warnings and errors in quiet mode
+  if (isQuiet()) {
+    args.push('-q');
+  }
+
+  // Config-injected build properties
+  for (const [key, value] of Object.entries(getBuildProperties(profile, projectConfig))) {
+    args.push(`-D${key}=${value}`);
+  }
+
   return args;
 }
 
+/**
+ * Get config-injected build properties for a profile
+ * Properties under the empty-string key apply to every profile
+ */
+function getBuildProperties(profile, projectConfig) {
+  const normalizedProfile = (!profile || profile === 'none') ? '' : profile;
+  const properties = projectConfig.build_properties || {};
+
+  return {
+    ...(properties[''] || {}),
+    ...(normalizedProfile ? properties[normalizedProfile] || {} : {})
+  };
+}
+
+/**
+ * Resolve effective profile activation for a build's -P list and -D properties
+ */
+async function resolveProfilesForBuild(detection, profile, cmdArgs) {
+  const properties = {};
+  for (const arg of cmdArgs) {
+    if (arg.startsWith('-D')) {
+      const [key, ...value] = arg.slice(2).split('=');
+      properties[key] = value.join('=');
+    }
+  }
+
+  const pom = parsePom(detection.pomPath);
+  const context = await getMachineContext(detection.module.path, properties);
+  return resolveActiveProfiles(pom, getProfiles(profile, detection.projectConfig), context);
+}
+
+/**
+ * Get environment for Maven, applying per-project MAVEN_OPTS (heap size, GC flags)
+ */
+function getMavenEnv(projectConfig) {
+  const env = { ...process.env };
+  if (projectConfig.maven_opts) {
+    env.MAVEN_OPTS = projectConfig.maven_opts;
+  }
+  return env;
+}
+
+/**
+ * User and global settings.xml a project builds with (maven_settings, global_settings),
+ * relative paths being relative to base_path. Null for the ones Maven picks itself
+ */
+function getMavenSettings(projectConfig) {
+  const resolve = file => file ? path.resolve(projectConfig.base_path || '.', file) : null;
+  return {
+    settings: resolve(projectConfig.maven_settings),
+    globalSettings: resolve(projectConfig.global_settings)
+  };
+}
+
+/**
+ * -s/-gs arguments for a project's settings.xml files
+ */
+function getMavenSettingsArgs(projectConfig) {
+  const { settings, globalSettings } = getMavenSettings(projectConfig);
+  const args = [];
+  for (const [flag, file, key] of [['-s', settings, 'maven_settings'], ['-gs', globalSettings, 'global_settings']]) {
+    if (!file) continue;
+    if (!fs.existsSync(file)) {
+      throw new Error(`${key} ${file} not found`);
+    }
+    args.push(flag, file);
+  }
+  return args;
+}
+
+/**
+ * Profile a build uses: the given one or the default, through profile_aliases
+ */
+function resolveProfile(profile, projectConfig) {
+  const name = profile || projectConfig.default_profile || 'none';
+  return projectConfig.profile_aliases?.[name] ?? name;
+}
+
+/**
+ * Profile to use when none was given: with no default_profile and no "" entry in
+ * maven_profiles, one picked from maven_profiles when it has several, else none
+ * as before. Returns the given profile, the pick, or null when the pick is cancelled
+ */
+async function pickProfile(profile, projectConfig) {
+  const profiles = projectConfig.maven_profiles || {};
+  const candidates = Object.keys(profiles).filter(Boolean);
+  if (profile || projectConfig.default_profile || profiles[''] || candidates.length < 2 || !isInteractive()) {
+    return profile;
+  }
+  return select('Maven profile', [
+    ...candidates.map(name => ({ label: name, value: name, hint: `(${profiles[name].join(' ')})` })),
+    { label: 'none', value: 'none', hint: '(no profile)' }
+  ]);
+}
+
 /**
  * Get Maven profiles for a project
  */
@@ -118,21 +558,51 @@ function getProfiles(profile, projectConfig) {
 
 /**
  * Show restart guidance based on modified files and restart rules
+ * The project's restart shortcut, if any, is shown whenever a restart is needed;
+ * when a reload is enough, jmw restart --reload is suggested instead
  */
-async function showRestartGuidance(moduleInfo, restartRules) {
+async function showRestartGuidance(moduleInfo, restartRules, shortcuts = {}) {
   console.log(chalk.blue('=== Restart Guidance ==='));
 
+  const showShortcut = () => {
+    if (shortcuts.restart) {
+      console.log(`Restart with: ${chalk.cyan(shortcuts.restart)}`);
+    }
+  };
+  const showReload = () => console.log(`Reload with: ${chalk.cyan('jmw restart --reload')}`);
+
   // Check if it's a global module
   if (moduleInfo.isGlobalModule) {
     console.log(chalk.red('Restart required: YES'));
     console.log('Reason: Global module deployment');
+    showShortcut();
+    return;
+  }
+
+  // Module configured with a fixed restart requirement
+  const severity = moduleInfo.settings?.restart_severity;
+  if (severity) {
+    const labels = { required: chalk.red('YES'), recommended: chalk.yellow('RECOMMENDED'), reload: chalk.yellow('RELOAD'), none: chalk.green('NO') };
+    console.log(`Restart required: ${labels[severity]}`);
+    console.log('Reason: restart_severity set for this module');
+    if (severity === 'reload') {
+      showReload();
+    } else if (severity !== 'none') {
+      showShortcut();
+    }
     return;
   }
 
-  // For WAR files, typically hot deployment (no restart needed)
-  if (moduleInfo.packaging === 'war') {
+  // For WAR/EAR/RAR files, typically hot deployment (no restart needed)
+  if (DEPLOYABLE_PACKAGINGS.includes(moduleInfo.packaging)) {
     console.log(chalk.yellow('Restart required: NO'));
-    console.log('Reason: WAR hot-deployment');
+    console.log(`Reason: ${moduleInfo.packaging.toUpperCase()} hot-deployment`);
+    return;
+  }
+
+  if (moduleInfo.packaging === 'pom') {
+    console.log(chalk.green('Restart required: NO'));
+    console.log('Reason: POM packaging, nothing is deployed');
     return;
   }
 
@@ -154,16 +624,10 @@ async function showRestartGuidance(moduleInfo, restartRules) {
       return;
     }
 
-    // Filter to only files in the target module
-    const moduleRelativePath = moduleInfo.relativePath || '';
-    const filteredFiles = modifiedFiles.filter(file => {
-      // For multi-module projects, filter by module's relative path
-      if (moduleRelativePath) {
-        return file.startsWith(moduleRelativePath + '/');
-      }
-      // For single-module, all files in the repo belong to the module
-      return true;
-    });
+    // Filter to only files in the target module; git lists paths from the repository
+    // root, which needn't be base_path or the reactor root
+    const prefix = (await $`cd ${moduleInfo.path} && git rev-parse --show-prefix`.text()).trim();
+    const filteredFiles = modifiedFiles.filter(file => file.startsWith(prefix));
 
     if (filteredFiles.length === 0) {
       console.log(chalk.green('Restart required: NO'));
@@ -173,7 +637,7 @@ async function showRestartGuidance(moduleInfo, restartRules) {
 
     // Check files against restart patterns, deduplicating by file (highest severity wins)
     const matchesByFile = new Map();
-    const severityOrder = { required: 1, recommended: 2 };
+    const severityOrder = { required: 1, recommended: 2, reload: 3 };
 
     for (const file of filteredFiles) {
       for (const rule of restartRules.patterns) {
@@ -198,18 +662,26 @@ async function showRestartGuidance(moduleInfo, restartRules) {
 
     // Show restart requirement
     const hasRequired = matches.some(m => m.severity === 'required');
+    const reloadOnly = matches.every(m => m.severity === 'reload');
     if (hasRequired) {
       console.log(chalk.red('Restart required: YES'));
+    } else if (reloadOnly) {
+      console.log(chalk.yellow('Restart required: RELOAD'));
     } else {
       console.log(chalk.yellow('Restart required: RECOMMENDED'));
     }
 
     // Show matched files and reasons
+    const tags = { required: chalk.red('[REQUIRED]'), recommended: chalk.yellow('[RECOMMENDED]'), reload: chalk.yellow('[RELOAD]') };
     matches.forEach(match => {
-      const severity = match.severity === 'required' ? chalk.red('[REQUIRED]') : chalk.yellow('[RECOMMENDED]');
-      console.log(`  ${severity} ${match.file}`);
+      console.log(`  ${tags[match.severity] || tags.recommended} ${match.file}`);
       console.log(`    Reason: ${match.reason}`);
     });
+    if (reloadOnly) {
+      showReload();
+    } else {
+      showShortcut();
+    }
     console.log('');
 
   } catch (error) {
@@ -226,8 +698,12 @@ async function showRestartGuidance(moduleInfo, restartRules) {
 function showArtifacts(moduleInfo) {
   console.log(chalk.blue('=== Artifacts ==='));
 
-  const targetPath = path.join(moduleInfo.path, 'target');
-  const artifacts = findArtifacts(targetPath, moduleInfo.packaging);
+  if (moduleInfo.packaging === 'pom') {
+    console.log('POM packaging, no deployable artifact');
+    return null;
+  }
+
+  const artifacts = findArtifacts(moduleInfo.outputDir, moduleInfo.packaging, getFinalName(moduleInfo));
 
   if (artifacts.length === 0) {
     console.log('No artifacts found');
@@ -236,21 +712,140 @@ function showArtifacts(moduleInfo) {
 
   artifacts.forEach(artifact => {
     console.log(`  ${chalk.green(artifact)}`);
+    if (moduleInfo.packaging === 'ear') {
+      showEarModules(artifact);
+    }
   });
 
   // Return the first artifact path
   return artifacts[0];
 }
 
+/**
+ * List the modules packaged in an EAR (top-level archives) and its library count
+ */
+function showEarModules(earPath) {
+  try {
+    const entries = readZipEntries(earPath).map(entry => entry.name);
+    const modules = entries.filter(name => !name.includes('/') && /\.(war|jar|rar)$/.test(name));
+    const libraries = entries.filter(name => /^(APP-INF\/)?lib\/[^/]+\.jar$/.test(name));
+    modules.forEach(name => console.log(`    ${name}`));
+    if (libraries.length > 0) {
+      console.log(chalk.gray(`    + ${libraries.length} libraries`));
+    }
+  } catch (error) {
+    console.log(chalk.yellow(`    Could not read modules: ${error.message}`));
+  }
+}
+
 /**
  * Show artifacts and restart guidance
  */
-async function showArtifactsAndGuidance(moduleInfo, restartRules) {
+async function showArtifactsAndGuidance(moduleInfo, restartRules, shortcuts) {
   const artifactPath = showArtifacts(moduleInfo);
-  await showRestartGuidance(moduleInfo, restartRules);
+  await showRestartGuidance(moduleInfo, restartRules, shortcuts);
   return artifactPath;
 }
 
+/**
+ * Compute SHA-256 of built artifacts and append them to build history
+ */
+async function recordArtifacts(detection, profile) {
+  const { project, module: moduleInfo } = detection;
+  const artifactPaths = findArtifacts(moduleInfo.outputDir, moduleInfo.packaging, getFinalName(moduleInfo));
+
+  if (artifactPaths.length === 0) {
+    return [];
+  }
+
+  const artifacts = await checksumArtifacts(artifactPaths);
+
+  console.log(chalk.blue('=== Checksums (SHA-256) ==='));
+  artifacts.forEach(artifact => {
+    console.log(`  ${artifact.sha256}  ${artifact.name}`);
+  });
+  console.log('');
+
+  recordBuild({
+    project,
+    module: moduleInfo.artifactId,
+    profile,
+    gitSha: await getGitSha(moduleInfo.path),
+    // Built from uncommitted changes, so not what the commit alone produces
+    dirty: await isGitDirty(moduleInfo.path),
+    artifacts
+  });
+
+  return artifacts;
+}
+
+/**
+ * Verify the build is reproducible
+ * Compares against a recorded build of the same commit, or builds a second time
+ */
+async function verifyReproducible(detection, profile, artifacts, runBuild) {
+  const { project, module: moduleInfo } = detection;
+
+  console.log(chalk.blue('=== Reproducibility Check ==='));
+
+  if (artifacts.length === 0) {
+    console.log(chalk.yellow('No artifacts to verify'));
+    return true;
+  }
+
+  const gitSha = await getGitSha(moduleInfo.path);
+  const dirty = await isGitDirty(moduleInfo.path);
+
+  // Only clean builds of the commit compare; index 0 is the build just recorded
+  const previous = gitSha && !dirty
+    ? readHistory({ type: 'build', project, module: moduleInfo.artifactId, profile, gitSha, dirty: false })[1]
+    : null;
+
+  if (previous) {
+    console.log(`Comparing with build of ${gitSha.slice(0, 10)} from ${new Date(previous.timestamp).toLocaleString()}`);
+    const results = artifacts.map(artifact => {
+      const earlier = previous.artifacts.find(a => a.name === artifact.name);
+      if (!earlier) {
+        console.log(chalk.yellow(`${artifact.name} not present in previous build`));
+        return false;
+      }
+      return reportReproducibility(artifact.name, earlier.sha256, artifact.sha256, null, artifact.path);
+    });
+    return results.every(Boolean);
+  }
+
+  if (dirty) {
+    console.log(chalk.yellow('Working tree has uncommitted changes, rebuilding to compare'));
+  } else {
+    console.log('No previous build of this commit recorded, rebuilding to compare');
+  }
+  console.log('');
+
+  // Keep first build aside since the second build starts with clean
+  const firstDir = fs.mkdtempSync(path.join(os.tmpdir(), 'jmw-repro-'));
+  try {
+    for (const artifact of artifacts) {
+      fs.copyFileSync(artifact.path, path.join(firstDir, artifact.name));
+    }
+
+    await runBuild();
+    console.log('');
+
+    const rebuilt = await checksumArtifacts(artifacts.map(a => a.path).filter(p => fs.existsSync(p)));
+    const results = artifacts.map(artifact => {
+      const second = rebuilt.find(a => a.name === artifact.name);
+      if (!second) {
+        console.log(chalk.yellow(`${artifact.name} not produced by second build`));
+        return false;
+      }
+      return reportReproducibility(artifact.name, artifact.sha256, second.sha256, path.join(firstDir, artifact.name), second.path);
+    });
+    return results.every(Boolean);
+  } finally {
+    fs.rmSync(firstDir, { recursive: true, force: true });
+  }
+}
+
 /**
  * Map Maven packaging type to actual file extension
  */
@@ -260,6 +855,7 @@ function getArtifactExtension(packaging) {
     'war': 'war',
     'jar': 'jar',
     'ear': 'ear',
+    'rar': 'rar',
     'pom': 'pom'
   };
   return extensionMap[packaging] || packaging;
@@ -267,14 +863,21 @@ function getArtifactExtension(packaging) {
 
 /**
  * Find artifacts in target directory
+ * The file named after the POM's finalName is the module's artifact; other files with
+ * the same extension (shaded, sources or test jars) are only listed when it's missing
  */
-function findArtifacts(targetPath, packaging) {
+function findArtifacts(targetPath, packaging, finalName) {
   try {
     if (!fs.existsSync(targetPath)) {
       return [];
     }
 
     const extension = getArtifactExtension(packaging);
+    const expected = finalName ? path.join(targetPath, `${finalName}.${extension}`) : null;
+    if (expected && fs.existsSync(expected)) {
+      return [expected];
+    }
+
     return fs.readdirSync(targetPath)
       .filter(file => file.endsWith('.' + extension))
       .map(file => path.join(targetPath, file));
@@ -283,28 +886,20 @@ function findArtifacts(targetPath, packaging) {
   }
 }
 
-/**
- * Simple confirmation prompt
- */
-function confirm(message) {
-  return new Promise(resolve => {
-    const rl = readline.createInterface({
-      input: process.stdin,
-      output: process.stdout
-    });
-
-    rl.question(message + ' (y/N) ', answer => {
-      rl.close();
-      resolve(answer.toLowerCase() === 'y' || answer.toLowerCase() === 'yes');
-    });
-  });
-}
-
 export {
   buildModule,
+  buildChangedModules,
   buildMavenCommand,
+  getBuildCommand,
+  getMavenEnv,
+  getMavenSettings,
+  getMavenSettingsArgs,
   getProfiles,
+  resolveProfile,
+  pickProfile,
+  resolveProfilesForBuild,
+  getBuildProperties,
   showArtifacts,
-  findArtifacts,
-  confirm
+  recordArtifacts,
+  findArtifacts
 };
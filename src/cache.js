@@ -0,0 +1,64 @@
+import fs from 'fs';
+import path from 'path';
+
+import { getConfigDir } from './config.js';
+
+// Bumped when the shape of cached values changes
+const CACHE_VERSION = 2;
+
+/**
+ * Path of a named cache file under the config dir
+ */
+function getCachePath(name) {
+  return path.join(getConfigDir(), 'cache', `${name}.json`);
+}
+
+function readCacheFile(name) {
+  try {
+    const cache = JSON.parse(fs.readFileSync(getCachePath(name), 'utf8'));
+    return cache.version === CACHE_VERSION ? cache : { version: CACHE_VERSION, entries: {} };
+  } catch (error) {
+    return { version: CACHE_VERSION, entries: {} };
+  }
+}
+
+function getMtime(file) {
+  try {
+    return fs.statSync(file).mtimeMs;
+  } catch (error) {
+    return null;
+  }
+}
+
+/**
+ * Value derived from files, recomputed only when one of them changed since
+ * compute() returns {value, files}: the value and every file it was derived from.
+ * A cache that can't be written only costs the recomputation next time
+ */
+function cached(name, key, compute) {
+  const cache = readCacheFile(name);
+  const entry = cache.entries[key];
+  if (entry && Object.entries(entry.mtimes).every(([file, mtime]) => getMtime(file) === mtime)) {
+    return entry.value;
+  }
+
+  const { value, files } = compute();
+  cache.entries[key] = { value, mtimes: Object.fromEntries(files.map(file => [file, getMtime(file)])) };
+
+  try {
+    const cachePath = getCachePath(name);
+    fs.mkdirSync(path.dirname(cachePath), { recursive: true });
+    // Written aside and renamed so concurrent runs never read a partial file
+    const tmpPath = `${cachePath}.${process.pid}.tmp`;
+    fs.writeFileSync(tmpPath, JSON.stringify(cache));
+    fs.renameSync(tmpPath, cachePath);
+  } catch (error) {
+    // Caching is best effort
+  }
+  return value;
+}
+
+export {
+  getCachePath,
+  cached
+};
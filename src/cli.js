@@ -2,30 +2,165 @@ import { Command } from 'commander';
 import chalk from 'chalk';
 import fs from 'fs';
 
-import { loadConfig, getClientConfig } from './config.js';
-import { detectProject } from './detector.js';
-import { buildModule } from './builder.js';
-import { deployArtifact, getWildflyConfig, showRemoteDeploymentGuide } from './deployer.js';
+import { loadConfig as readConfig, setConfigPath, setInstance, setStrict, checkConfigPaths, findConfigPaths, getClientConfig, requireClientConfig, getClientHosts, getEnvironmentCandidates } from './config.js';
+import { detectProject as detect, requireMaven, getModuleMap, getModuleEntry, findReactorRoot, getReactorModules } from './detector.js';
+import { buildModule, buildChangedModules, buildMavenCommand, resolveProfile, resolveProfilesForBuild, pickProfile } from './builder.js';
+import { showProfiles } from './profiles.js';
+import { deployArtifact, deployRemote, getWildflyConfig, showRemoteDeploymentGuide } from './deployer.js';
+import { verifyAndWarmup } from './health.js';
+import { diffEnvironments } from './envdiff.js';
+import { configureOutput, setQuiet, symbol } from './output.js';
+import { setAssumeYes, setInteractive, isInteractive, select } from './confirm.js';
+import { configureProgress } from './progress.js';
+import { installSignalHandlers } from './process.js';
+import { fetchSources } from './sources.js';
+import { readHistory } from './history.js';
+import { syncWebapp } from './webappsync.js';
+import { runCli, runCliScript } from './jbosscli.js';
+import { getAuditPath, readAudit, showAudit } from './audit.js';
+import { migrateConfigFile, showMigration } from './migrate.js';
+import { addModuleInteractively, scanWorkspace, pickModule } from './wizard.js';
+import { getBackupDir, listLocalBackups, listRemoteBackups, showBackups } from './rollback.js';
+import { retryOutbox, showOutbox, clearOutbox } from './outbox.js';
+import { runIntegrationTests } from './itest.js';
+import { describeRoute } from './ssh.js';
+import { syncGlobalModule } from './globalmodule.js';
+import { findWildflyInstalls, describeWildflyInstalls } from './wildfly.js';
+import { startServer, stopServer, restartServer } from './server.js';
+import { cleanServerCache } from './servercache.js';
+import { listDeployments, setDeploymentEnabled } from './deployments.js';
+import { testDatasources } from './datasources.js';
+import { configureScanners } from './scanner.js';
+import { setLogLevel } from './loglevel.js';
+import { showDomainTopology } from './topology.js';
+import { addManagementUser } from './mgmtuser.js';
+import { showStatus } from './status.js';
+import { runDoctor } from './doctor.js';
+import { resolveVerifyConfig } from './ports.js';
 
 const program = new Command();
 
+installSignalHandlers();
+
+/**
+ * Fall back to the project's defaults block for global flags not given on the command line
+ */
+function applyDefaults(detection) {
+  const defaults = detection.projectConfig.defaults || {};
+  const options = program.opts();
+  setAssumeYes(options.yes ?? defaults.yes ?? false);
+  setQuiet(options.quiet ?? defaults.quiet ?? false);
+  return detection;
+}
+
+// Config and detection pickEnvironment read before the action, handed to it so
+// neither is done twice
+let preloaded = null;
+
+/**
+ * Load the config; the first call of an action gets the one read before it
+ */
+function loadConfig() {
+  if (preloaded && !preloaded.used) {
+    preloaded.used = true;
+    return preloaded.config;
+  }
+  return readConfig();
+}
+
+/**
+ * Detect the project; for the config read before the action, that detection is reused
+ */
+function detectProject(config, cwd) {
+  if (!cwd && preloaded?.config === config) {
+    return preloaded.detection;
+  }
+  return detect(config, cwd);
+}
+
+/**
+ * Before a command with --env runs, let the user pick the environment when --client
+ * names a client with several, none was given and there is none to default to
+ */
+async function pickEnvironment(command) {
+  const options = command.opts();
+  if (!options.client || options.env !== undefined || !isInteractive() || !command.options.some(option => option.long === '--env')) {
+    return;
+  }
+
+  let candidates;
+  try {
+    const config = readConfig();
+    preloaded = { config, detection: detect(config), used: false };
+    candidates = getEnvironmentCandidates(preloaded.detection.projectConfig, options.client);
+  } catch (error) {
+    // The command reports config and detection errors itself
+    return;
+  }
+  if (!candidates) {
+    return;
+  }
+
+  const env = await select(`Environment of ${options.client}`, candidates);
+  if (!env) {
+    console.error(chalk.red('\nError: No environment picked\n'));
+    process.exit(1);
+  }
+  command.setOptionValue('env', env);
+}
+
 /**
  * Main entry point
  */
 program
   .name('jmw')
   .description('Java Maven WildFly - Interactive deployment helper')
-  .version('2.0.0');
+  .version('2.0.0')
+  .option('-C, --path <dir>', 'Run as if jmw was started in this directory (like git -C)')
+  .option('--config <path>', 'Config file (default: JMW_CONFIG, or .jmw.yaml up from cwd merged with ~/.config/jmw/config.yaml)')
+  .option('--instance <name>', 'Local WildFly instance of the project (wildfly_instances)')
+  .option('--strict', 'Fail when configured paths of the detected project (base_path, wildfly_root, module directories) are missing')
+  .option('--plain', 'Plain ASCII output without colors (also via NO_COLOR or when piped)')
+  .option('--progress-fd <fd>', 'Write JSON-lines progress events to this file descriptor')
+  .option('--progress-socket <path>', 'Write JSON-lines progress events to this UNIX socket')
+  .option('-y, --yes', 'Answer yes/no prompts with yes (typed confirmations are still asked)')
+  .option('--no-yes', 'Ask even if the project defaults to --yes')
+  .option('-q, --quiet', 'Only show Maven warnings and errors')
+  .option('--no-quiet', 'Full Maven output even if the project defaults to --quiet')
+  .option('--no-interactive', 'Never ask for a module, environment, profile or server group that wasn\'t given; fail instead')
+  .hook('preAction', () => {
+    // --config stays relative to where jmw was started; everything else follows -C
+    setConfigPath(program.opts().config);
+    if (program.opts().path) {
+      try {
+        process.chdir(program.opts().path);
+      } catch (error) {
+        console.error(chalk.red(`\nError: cannot change to ${program.opts().path}: ${error.code || error.message}\n`));
+        process.exit(1);
+      }
+    }
+    setInstance(program.opts().instance);
+    setStrict(program.opts().strict);
+    configureOutput(program.opts());
+    configureProgress(program.opts());
+    setInteractive(program.opts().interactive);
+  })
+  .hook('preAction', (thisCommand, actionCommand) => pickEnvironment(actionCommand));
 
 /**
  * Build command
  */
 program
   .command('build')
-  .description('Build a Maven module')
+  .description('Build a Maven or Gradle module')
   .argument('[profile]', 'Maven profile (e.g., TEST, PROD)')
   .option('--client <name>', 'Target client (shows remote deployment commands after build)')
+  .option('--env <name>', 'Client environment (e.g., test, staging, prod; default: test)')
   .option('--skip-tests', 'Skip tests during build')
+  .option('--verify-reproducible', 'Check the artifact is byte-identical to a rebuild or recorded build of the same commit')
+  .option('--changed', 'Build the deployable reactor modules changed since --base instead of the current module')
+  .option('--base <ref>', 'Git ref --changed diffs against (default: origin/main)')
+  .option('--with-dependents', 'With --changed, also build the modules depending on the changed ones')
   .action(async (profile, options) => {
     try {
       console.log(chalk.blue.bold('\n=== JMW Build ===\n'));
@@ -34,22 +169,53 @@ program
       const config = loadConfig();
 
       // Detect project
-      const detection = detectProject(config);
+      let detection = applyDefaults(detectProject(config));
+
+      profile = await pickProfile(profile, detection.projectConfig);
+      if (profile === null) {
+        console.log(chalk.red('Build cancelled'));
+        process.exitCode = 1;
+        return;
+      }
+
+      if (options.changed) {
+        requireMaven(detection, 'build --changed');
+        await buildChangedModules(detection, profile, options);
+        console.log('');
+        return;
+      }
+
+      // From an aggregator, pick the module to build (or build all its children, or just the POM)
+      let buildChildren = false;
+      if (detection.module.packaging === 'pom' && detection.module.modules.length > 0 && detection.pomPath) {
+        const picked = await pickModule(detection);
+        if (!picked) {
+          console.log(chalk.red('Build cancelled'));
+          process.exitCode = 1;
+          return;
+        }
+        buildChildren = picked === 'all';
+        if (!buildChildren && picked !== 'self') {
+          detection = applyDefaults(detectProject(config, picked));
+        }
+        console.log('');
+      }
 
       // Get client config if specified, or use default, or use first available
       let clientConfig = null;
       let clientName = null;
 
+      // Only a client named with --client must resolve; a default without a default environment is left out
       if (options.client) {
-        clientConfig = getClientConfig(detection.projectConfig, options.client);
+        clientConfig = requireClientConfig(detection.projectConfig, options.client, options.env);
         clientName = options.client;
       } else if (detection.projectConfig.default_client) {
-        clientConfig = getClientConfig(detection.projectConfig, detection.projectConfig.default_client);
+        clientConfig = getClientConfig(detection.projectConfig, detection.projectConfig.default_client, options.env);
         clientName = detection.projectConfig.default_client;
       } else if (detection.projectConfig.clients && Object.keys(detection.projectConfig.clients).length > 0) {
         // Use first available client if no default specified
         clientName = Object.keys(detection.projectConfig.clients)[0];
-        clientConfig = getClientConfig(detection.projectConfig, clientName);
+        clientConfig = getClientConfig(detection.projectConfig, clientName, options.env);
       }
 
       if (options.client && !clientConfig) {
@@ -68,17 +234,28 @@ program
         } else {
           console.log(chalk.yellow(`Client: ${clientName} (first available)`));
         }
+        if (clientConfig.environment) {
+          console.log(chalk.green(`Environment: ${clientConfig.environment}`));
+        }
       }
 
       console.log('');
 
       // Build
-      const artifactPath = await buildModule(detection, profile, { skipTests: options.skipTests });
+      const artifactPath = await buildModule(detection, profile, {
+        buildChildren,
+        skipTests: options.skipTests,
+        verifyReproducible: options.verifyReproducible
+      });
 
       // Show remote deployment guide if client configured and artifact was built
       if (clientConfig && artifactPath) {
         console.log('');
-        console.log(chalk.blue('=== Remote Deployment Commands ==='));
+        console.log(chalk.blue('=== Remote Deployment ==='));
+        const envFlag = clientConfig.environment ? ` --env ${clientConfig.environment}` : '';
+        console.log(`  jmw deploy ${artifactPath} --client ${clientName}${envFlag}`);
+        console.log('');
+        console.log(chalk.blue('=== Manual Deployment Commands ==='));
         console.log('');
         const wildflyConfig = getWildflyConfig(detection.projectConfig, clientConfig);
         showRemoteDeploymentGuide(artifactPath, wildflyConfig, clientConfig, detection.module);
@@ -98,7 +275,18 @@ program
 program
   .command('deploy')
   .description('Deploy artifact to WildFly')
-  .argument('<artifact>', 'Path to artifact JAR/WAR file')
+  .argument('<artifact>', 'Path to artifact JAR/WAR/EAR/RAR file')
+  .option('--client <name>', 'Deploy to a remote client over SSH instead of the local WildFly (wildfly_root)')
+  .option('--env <name>', 'Client environment (e.g., test, staging, prod; default: test)')
+  .option('--parallel', 'Deploy to all client hosts at once instead of one by one')
+  .option('--auto-rollback', 'Restore the previous artifact without asking if verification fails')
+  .option('--canary <host>', 'Deploy to one client host (domain: host controller) first and promote to the rest on confirmation')
+  .option('--promote', 'With --canary, promote a healthy canary without asking (--yes does not)')
+  .option('--server-group <name>', 'Server group to deploy to in domain mode (default: server_group, or picked from the domain)')
+  .option('--ignore-state', 'Deploy even when WildFly is not running')
+  .option('--force-clean', 'Remove the deployment and its scanner markers first (a stuck .isdeploying, .pending or .failed)')
+  .option('--dry-run', 'Only show the deployment plan')
+  .option('--no-dry-run', 'Deploy even if the project defaults to dry_run_deploy')
   .action(async (artifact, options) => {
     try {
       console.log(chalk.blue.bold('\n=== JMW Deploy ===\n'));
@@ -107,7 +295,7 @@ program
       const config = loadConfig();
 
       // Detect project
-      const detection = detectProject(config);
+      const detection = applyDefaults(detectProject(config));
 
       // Validate artifact path
       if (!fs.existsSync(artifact)) {
@@ -119,8 +307,24 @@ program
       console.log(chalk.green(`Artifact: ${artifact}`));
       console.log('');
 
+      if (options.canary && !options.client) {
+        throw new Error('--canary needs --client');
+      }
+      if (options.promote && !options.canary) {
+        throw new Error('--promote needs --canary');
+      }
+
+      options.dryRun ??= detection.projectConfig.defaults?.dry_run_deploy ?? false;
+
       // Deploy
-      await deployArtifact(artifact, detection);
+      if (options.client) {
+        const deployed = await deployRemote(artifact, detection, options.client, options);
+        if (!deployed) {
+          process.exitCode = 1;
+        }
+      } else {
+        await deployArtifact(artifact, detection, options);
+      }
 
       console.log(chalk.blue.bold('\n=== Deploy Complete ===\n'));
 
@@ -131,37 +335,206 @@ program
   });
 
 /**
- * Show clients command
+ * Integration test command
  */
 program
-  .command('clients')
-  .description('Show available clients for current project')
-  .action(() => {
+  .command('itest')
+  .description('Run failsafe integration tests against containers configured for the module')
+  .option('--test <pattern>', 'Only run matching integration tests (-Dit.test)')
+  .option('--keep', 'Leave containers running after the tests')
+  .action(async (options) => {
     try {
-      console.log(chalk.blue.bold('\n=== Available Clients ===\n'));
+      console.log(chalk.blue.bold('\n=== JMW Integration Tests ===\n'));
 
       const config = loadConfig();
-      const detection = detectProject(config);
+      const detection = applyDefaults(detectProject(config));
+      requireMaven(detection, 'Integration tests');
 
-      const clients = detection.projectConfig.clients;
+      console.log(chalk.green(`Detected project: ${detection.project}`));
+      console.log(chalk.green(`Module: ${detection.module.artifactId}`));
+      console.log('');
 
-      if (!clients || Object.keys(clients).length === 0) {
-        console.log(chalk.yellow('No clients configured for this project'));
+      await runIntegrationTests(detection, options);
+
+      console.log(chalk.blue.bold('\n=== Integration Tests Complete ===\n'));
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Profiles command
+ */
+program
+  .command('profiles')
+  .description('Show which Maven profiles will be active, including auto-activated ones')
+  .argument('[profile]', 'Maven profile (e.g., TEST, PROD)')
+  .action(async (profile) => {
+    try {
+      console.log(chalk.blue.bold('\n=== Maven Profiles ===\n'));
+
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+      requireMaven(detection, 'Profile resolution');
+      const { projectConfig, module: moduleInfo } = detection;
+
+      profile = await pickProfile(profile, projectConfig);
+      if (profile === null) {
+        process.exitCode = 1;
+        return;
+      }
+      const effectiveProfile = resolveProfile(profile, projectConfig);
+      const cmdArgs = buildMavenCommand(moduleInfo, effectiveProfile, projectConfig.skip_tests || false, projectConfig);
+
+      console.log(chalk.green(`Module: ${moduleInfo.artifactId}`));
+      console.log(`Profile: ${effectiveProfile}`);
+      console.log('');
+
+      showProfiles(await resolveProfilesForBuild(detection, effectiveProfile, cmdArgs));
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Sources command
+ */
+program
+  .command('sources')
+  .description('Download dependency sources and javadocs for IDE setup')
+  .option('--missing-only', 'Only fetch dependencies without local sources/javadoc jars')
+  .option('-j, --jobs <n>', 'Parallel download threads (default: CPU count)', parseInt)
+  .action(async (options) => {
+    try {
+      console.log(chalk.blue.bold('\n=== JMW Sources ===\n'));
+
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+      requireMaven(detection, 'Fetching sources');
+
+      console.log(chalk.green(`Detected project: ${detection.project}`));
+      console.log(chalk.green(`Module: ${detection.module.artifactId}`));
+      console.log('');
+
+      await fetchSources(detection, options);
+
+      console.log(chalk.blue.bold('\n=== Sources Complete ===\n'));
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Warm-up command
+ */
+program
+  .command('warmup')
+  .description('Wait for health check and send warm-up requests')
+  .option('--client <name>', 'Use health check/warm-up settings of a client')
+  .option('--env <name>', 'Client environment')
+  .action(async (options) => {
+    try {
+      console.log(chalk.blue.bold('\n=== JMW Warm-up ===\n'));
+
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      // Client-level settings override project-level ones
+      const clientConfig = options.client ? requireClientConfig(detection.projectConfig, options.client, options.env) : null;
+      const verifyConfig = await resolveVerifyConfig({
+        health_check: clientConfig?.health_check ?? detection.projectConfig.health_check,
+        warmup: clientConfig?.warmup ?? detection.projectConfig.warmup
+      }, getWildflyConfig(detection.projectConfig, clientConfig), clientConfig ? getClientHosts(clientConfig)[0] : null);
+
+      if (!verifyConfig.health_check && !verifyConfig.warmup) {
+        console.log(chalk.yellow('No health_check or warmup configured for this project'));
         console.log('');
         return;
       }
 
-      Object.entries(clients).forEach(([name, client]) => {
-        const label = chalk.white.bold(name);
-        const remote = client.host ? `${client.user}@${client.host}` : 'No remote config';
-        console.log(`  ${label}: ${remote}`);
-      });
+      const healthy = await verifyAndWarmup(verifyConfig);
+      if (!healthy) {
+        process.exit(1);
+      }
 
-      if (detection.projectConfig.default_client) {
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Environment commands
+ */
+const envCommand = program
+  .command('env')
+  .description('Inspect deployment environments');
+
+envCommand
+  .command('diff')
+  .description('Compare profile properties, build properties, system properties and endpoints')
+  .argument('<envA>', 'Environment or profile, optionally @client (e.g., test, test@trieste)')
+  .argument('<envB>', 'Environment or profile, optionally @client (e.g., prod, prod@trieste)')
+  .option('--all', 'Show identical values too')
+  .action(async (envA, envB, options) => {
+    try {
+      console.log(chalk.blue.bold(`\n=== Environment Diff: ${envA} vs ${envB} ===\n`));
+
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+      requireMaven(detection, 'Environment diff');
+
+      const differences = await diffEnvironments(envA, envB, detection, options);
+      console.log(`${differences} difference(s) found`);
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Build history command
+ */
+program
+  .command('history')
+  .description('Show recorded builds and artifact checksums')
+  .option('--all', 'Show builds of all modules, not just the current one')
+  .option('-n, --limit <n>', 'Number of entries to show', value => parseInt(value, 10), 10)
+  .action((options) => {
+    try {
+      console.log(chalk.blue.bold('\n=== Build History ===\n'));
+
+      let filter = { type: 'build' };
+      if (!options.all) {
+        const detection = applyDefaults(detectProject(loadConfig()));
+        filter = { ...filter, project: detection.project, module: detection.module.artifactId };
+      }
+
+      const entries = readHistory(filter).slice(0, options.limit);
+      if (entries.length === 0) {
+        console.log(chalk.yellow('No builds recorded'));
         console.log('');
-        console.log(`Default client: ${chalk.green(detection.projectConfig.default_client)}`);
+        return;
       }
 
+      for (const entry of entries) {
+        const sha = entry.gitSha ? `${entry.gitSha.slice(0, 10)}${entry.dirty ? '+dirty' : ''}` : 'no git';
+        console.log(`${chalk.white.bold(new Date(entry.timestamp).toLocaleString())}  ${entry.project}/${entry.module}  ${entry.profile}  ${chalk.gray(sha)}`);
+        for (const artifact of entry.artifacts || []) {
+          console.log(`  ${artifact.sha256}  ${artifact.name}`);
+        }
+      }
       console.log('');
 
     } catch (error) {
@@ -171,16 +544,872 @@ program
   });
 
 /**
- * Show help on error
+ * Audit log command
  */
-const helpText = `
-Examples:
+program
+  .command('audit')
+  .description('Show who deployed or rolled back what, where and with which result')
+  .option('--all', 'Show records of all modules, not just the current one')
+  .option('--client <name>', 'Only show records for a client')
+  .option('-n, --limit <n>', 'Number of entries to show', value => parseInt(value, 10), 20)
+  .action((options) => {
+    try {
+      console.log(chalk.blue.bold('\n=== Audit Log ===\n'));
 
-  $ jmw build
-  $ jmw build TEST
-  $ jmw build TEST --client metrocargo
-  $ jmw deploy ./target/myapp.jar
-  $ jmw clients
+      const config = loadConfig();
+      let auditConfig = config.audit;
+      let filter = { client: options.client };
+      if (!options.all) {
+        const detection = applyDefaults(detectProject(config));
+        auditConfig = detection.audit;
+        filter = { ...filter, project: detection.project, module: detection.module.artifactId };
+      }
+
+      console.log(chalk.gray(getAuditPath(auditConfig)));
+      const entries = readAudit(auditConfig, filter).slice(0, options.limit);
+      if (entries.length === 0) {
+        console.log(chalk.yellow('No deployments recorded'));
+        console.log('');
+        return;
+      }
+
+      showAudit(entries);
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Backups command
+ */
+program
+  .command('backups')
+  .description('List backups of replaced artifacts (local, or on a client\'s hosts)')
+  .option('--client <name>', 'List backups on a client')
+  .option('--env <name>', 'Client environment')
+  .action(async (options) => {
+    try {
+      console.log(chalk.blue.bold('\n=== Artifact Backups ===\n'));
+
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!options.client) {
+        const wildflyConfig = getWildflyConfig(detection.projectConfig, null);
+        const backupDir = getBackupDir(wildflyConfig.root, wildflyConfig.mode);
+        console.log(chalk.gray(backupDir));
+        showBackups(listLocalBackups(backupDir));
+        console.log('');
+        return;
+      }
+
+      const clientConfig = requireClientConfig(detection.projectConfig, options.client, options.env);
+      const wildflyConfig = getWildflyConfig(detection.projectConfig, clientConfig);
+      for (const hostConfig of getClientHosts(clientConfig)) {
+        const backupDir = getBackupDir(hostConfig.wildfly_path, wildflyConfig.mode);
+        console.log(chalk.blue(`--- ${hostConfig.host}:${backupDir} ---`));
+        showBackups(await listRemoteBackups(hostConfig, backupDir));
+        console.log('');
+      }
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Local server commands
+ */
+const serverCommand = program
+  .command('server')
+  .description('Start, stop and clean the local WildFly');
+
+serverCommand
+  .command('start')
+  .description('Start the local WildFly in the background and wait until it is running')
+  .option('--timeout <seconds>', 'How long to wait for the server (default: 120)', parseInt)
+  .action(async (options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await startServer(detection, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+serverCommand
+  .command('stop')
+  .description('Shut the local WildFly down')
+  .option('--timeout <seconds>', 'How long to wait for the shutdown (default: 60)', parseInt)
+  .action(async (options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await stopServer(detection, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+serverCommand
+  .command('clean-cache')
+  .description('Wipe tmp/, orphaned deployment content and undeployed exploded deployments of the local WildFly')
+  .option('--stop', 'Stop a running WildFly first and start it again afterwards')
+  .option('--no-start', 'With --stop, leave WildFly stopped')
+  .option('--dry-run', 'Only show what would be removed')
+  .action(async (options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await cleanServerCache(detection, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Restart command
+ */
+program
+  .command('restart')
+  .description('Reload or restart WildFly, a reload when the servers need no more')
+  .option('--client <name>', 'Restart the client\'s WildFly')
+  .option('--env <name>', 'Client environment')
+  .option('--reload', 'Reload even when a full restart is pending')
+  .option('--full', 'Fully restart even when a reload would do')
+  .option('--timeout <seconds>', 'How long to wait for the servers (default: 120)', parseInt)
+  .action(async (options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await restartServer(detection, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Scanner command
+ */
+program
+  .command('scanner')
+  .description('Show or change the deployment scanner settings of a standalone WildFly')
+  .option('--client <name>', 'Use the client\'s hosts')
+  .option('--env <name>', 'Client environment')
+  .option('--name <scanner>', 'Only this scanner (default: all of them)')
+  .option('--enable', 'Turn scanning on')
+  .option('--disable', 'Turn scanning off')
+  .option('--interval <ms>', 'Set the scan interval in milliseconds', parseInt)
+  .option('--timeout <seconds>', 'Set the deployment timeout in seconds', parseInt)
+  .action(async (options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await configureScanners(detection, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Log level command
+ */
+program
+  .command('loglevel')
+  .description('Show or set logger levels at runtime, without editing the server config or restarting')
+  .argument('[category]', 'Logger category, e.g. com.acme (root for the root logger; default: list the loggers)')
+  .argument('[level]', 'Level to set, e.g. DEBUG (default: show the current level)')
+  .option('--client <name>', 'Use the client\'s hosts')
+  .option('--env <name>', 'Client environment')
+  .action(async (category, level, options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await setLogLevel(detection, category, level, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Disable command
+ */
+program
+  .command('disable')
+  .description('Take a deployment offline, keeping its content to enable it later')
+  .argument('[deployment]', 'Deployment name (default: the current module\'s deployment)')
+  .option('--client <name>', 'Use the client\'s hosts')
+  .option('--env <name>', 'Client environment')
+  .option('--server-group <name>', 'Only this server group in domain mode (default: server_group, or every group with the deployment)')
+  .action(async (deployment, options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await setDeploymentEnabled(detection, deployment, false, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Enable command
+ */
+program
+  .command('enable')
+  .description('Enable a disabled deployment again')
+  .argument('[deployment]', 'Deployment name (default: the current module\'s deployment)')
+  .option('--client <name>', 'Use the client\'s hosts')
+  .option('--env <name>', 'Client environment')
+  .option('--server-group <name>', 'Only this server group in domain mode (default: server_group, or every group with the deployment)')
+  .action(async (deployment, options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await setDeploymentEnabled(detection, deployment, true, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Topology command
+ */
+program
+  .command('topology')
+  .description('Show the hosts, servers, server groups and states of a domain')
+  .option('--client <name>', 'Ask the client\'s domain controller')
+  .option('--env <name>', 'Client environment')
+  .option('--server-group <name>', 'Server group to check as the deploy target (default: server_group)')
+  .action(async (options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await showDomainTopology(detection, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Management commands
+ */
+const mgmtCommand = program
+  .command('mgmt')
+  .description('Set up access to the WildFly management API');
+
+mgmtCommand
+  .command('add-user')
+  .description('Create a management user in mgmt-users.properties (as add-user does), store its password as a secret and add it to the config')
+  .argument('[user]', 'User name (default: jmw)')
+  .option('--client <name>', 'Create the user on the client\'s hosts')
+  .option('--env <name>', 'Client environment')
+  .option('--generate', 'Generate the password instead of asking for it')
+  .option('--store <store>', 'Where to keep the password: keychain or file (default: keychain, file on Windows)')
+  .action(async (user, options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await addManagementUser(detection, user, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Status command
+ */
+program
+  .command('status')
+  .description('Show server state, uptime, heap and threads, and the current module\'s deployment')
+  .option('--client <name>', 'Ask the client\'s hosts')
+  .option('--env <name>', 'Client environment')
+  .action(async (options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await showStatus(detection, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Doctor command
+ */
+program
+  .command('doctor')
+  .description('Check the server\'s own XML config (scanner, management port, server groups) against jmw\'s')
+  .option('--client <name>', 'Check the client\'s hosts')
+  .option('--env <name>', 'Client environment')
+  .action(async (options) => {
+    try {
+      console.log(chalk.blue.bold('\n=== JMW Doctor ===\n'));
+
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await runDoctor(detection, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Deployments command
+ */
+program
+  .command('deployments')
+  .description('List the deployments of the local WildFly or a client')
+  .option('--client <name>', 'List the client\'s deployments')
+  .option('--env <name>', 'Client environment')
+  .action(async (options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await listDeployments(detection, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Datasource commands
+ */
+const dsCommand = program
+  .command('ds')
+  .description('Check the datasources of the local WildFly or a client');
+
+dsCommand
+  .command('test [name]')
+  .description('Test pool connections of the datasources the module uses (or the named one)')
+  .option('--client <name>', 'Test on the client\'s hosts')
+  .option('--env <name>', 'Client environment')
+  .action(async (name, options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (!(await testDatasources(detection, name, options))) {
+        process.exitCode = 1;
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * jboss-cli command
+ */
+program
+  .command('cli <command> [file]')
+  .description('Run a jboss-cli command, or a script with cli run <file.cli>, against the local WildFly or a client')
+  .option('--client <name>', 'Run on the client\'s hosts over SSH')
+  .option('--env <name>', 'Client environment')
+  .option('--var <name=value>', 'Script variable, in addition to ${project}, ${module}, ${artifact}, ${server_group}... (repeatable)',
+    (value, previous) => [...previous, value], [])
+  .action(async (command, file, options) => {
+    try {
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      if (command === 'run' && !file) {
+        throw new Error('cli run needs a script file');
+      }
+      if (command !== 'run' && file) {
+        throw new Error('Quote the jboss-cli command, it is a single argument');
+      }
+      const succeeded = command === 'run'
+        ? await runCliScript(detection, file, options)
+        : await runCli(detection, command, options);
+      if (!succeeded) {
+        process.exitCode = 1;
+      }
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Webapp sync command
+ */
+program
+  .command('sync')
+  .description('Copy changed JSP/XHTML/static files from src/main/webapp into the exploded deployment')
+  .option('--client <name>', 'Sync to a client instead of the local server')
+  .option('--env <name>', 'Client environment')
+  .option('--target <name>', 'Exploded deployment directory name (default: <artifactId>[-version].war)')
+  .option('--dry-run', 'Only show what would be copied')
+  .action(async (options) => {
+    try {
+      console.log(chalk.blue.bold('\n=== JMW Sync ===\n'));
+
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      console.log(chalk.green(`Detected project: ${detection.project}`));
+      console.log(chalk.green(`Module: ${detection.module.artifactId}`));
+      console.log('');
+
+      const completed = await syncWebapp(detection, options);
+      console.log('');
+      if (!completed) {
+        process.exit(1);
+      }
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Global module commands
+ */
+const moduleCommand = program
+  .command('module')
+  .description('Manage WildFly global modules');
+
+moduleCommand
+  .command('sync')
+  .description('Sync the local global module directory to a client, transferring only changed files')
+  .requiredOption('--client <name>', 'Target client')
+  .option('--env <name>', 'Client environment')
+  .option('--source <dir>', 'Local module directory (default: <wildfly_root>/<module path>)')
+  .option('--delete', 'Delete remote files that no longer exist locally')
+  .option('--dry-run', 'Only show what would be transferred')
+  .action(async (options) => {
+    try {
+      console.log(chalk.blue.bold('\n=== JMW Module Sync ===\n'));
+
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      console.log(chalk.green(`Detected project: ${detection.project}`));
+      console.log(chalk.green(`Module: ${detection.module.artifactId}`));
+      console.log('');
+
+      const completed = await syncGlobalModule(detection, options.client, options);
+      console.log('');
+      if (!completed) {
+        process.exit(1);
+      }
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Outbox commands
+ */
+const outboxCommand = program
+  .command('outbox')
+  .description('Remote operations deferred while the client was unreachable')
+  .action(() => {
+    console.log(chalk.blue.bold('\n=== Outbox ===\n'));
+    showOutbox();
+    console.log('');
+  });
+
+outboxCommand
+  .command('retry')
+  .description('Retry queued remote operations')
+  .action(async () => {
+    try {
+      console.log(chalk.blue.bold('\n=== Outbox Retry ===\n'));
+      const completed = await retryOutbox();
+      console.log('');
+      if (!completed) {
+        process.exit(1);
+      }
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+outboxCommand
+  .command('clear')
+  .description('Discard all queued remote operations')
+  .action(() => {
+    clearOutbox();
+    console.log(chalk.green('Outbox cleared'));
+  });
+
+/**
+ * Config commands
+ */
+const configCommand = program
+  .command('config')
+  .description('Inspect and check jmw configuration');
+
+configCommand
+  .command('validate')
+  .description('Check the config files against the schema')
+  .option('--deep', 'Also check that configured paths exist on this machine')
+  .action((options) => {
+    try {
+      console.log(chalk.blue.bold('\n=== Config Validation ===\n'));
+
+      const configPaths = findConfigPaths();
+      if (configPaths.length === 0) {
+        console.log(chalk.gray('Using the embedded config'));
+      }
+      configPaths.forEach(configPath => console.log(chalk.gray(configPath)));
+
+      const config = loadConfig(configPaths);
+      if (options.deep) {
+        checkConfigPaths(config);
+      }
+      console.log(chalk.green('Config is valid'));
+
+      // Hint at local installs for projects that can't deploy locally yet
+      const rootless = Object.entries(config.projects || {})
+        .filter(([, project]) => !project.wildfly_root && !Object.values(project.wildfly_instances || {}).some(instance => instance?.wildfly_root))
+        .map(([name]) => name);
+      if (rootless.length > 0) {
+        const found = describeWildflyInstalls(findWildflyInstalls());
+        console.log(chalk.yellow(`No wildfly_root for: ${rootless.join(', ')}`));
+        found.forEach(line => console.log(chalk.gray(`  WildFly found at ${line}`)));
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+configCommand
+  .command('migrate')
+  .description('Rewrite deprecated config fields (global_modules -> modules)')
+  .option('--dry-run', 'Show what would change without writing')
+  .action((options) => {
+    try {
+      console.log(chalk.blue.bold('\n=== Config Migration ===\n'));
+
+      const configPaths = findConfigPaths();
+      if (configPaths.length === 0) {
+        console.log(chalk.yellow('No config file to migrate (using the embedded config)'));
+        console.log('');
+        return;
+      }
+
+      for (const configPath of configPaths) {
+        showMigration(configPath, migrateConfigFile(configPath, options), options);
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+configCommand
+  .command('add-module')
+  .description('Add the current module to the project\'s modules map interactively')
+  .action(async () => {
+    try {
+      console.log(chalk.blue.bold('\n=== Add Module ===\n'));
+
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      console.log(chalk.green(`Detected project: ${detection.project}`));
+      console.log(chalk.green(`Module: ${detection.module.artifactId}`));
+      console.log('');
+
+      await addModuleInteractively(detection);
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+configCommand
+  .command('scan <dir>')
+  .description('Find Maven projects in a workspace and add them to config')
+  .action(async (dir) => {
+    try {
+      console.log(chalk.blue.bold('\n=== Workspace Scan ===\n'));
+      await scanWorkspace(dir);
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Modules command
+ */
+program
+  .command('modules')
+  .description('Show the configured modules of the current project and how they deploy')
+  .option('--reactor', 'List every module of the Maven reactor instead')
+  .action((options) => {
+    try {
+      console.log(chalk.blue.bold('\n=== Modules ===\n'));
+
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+      const { projectConfig, pomPath, module: moduleInfo } = detection;
+
+      if (!options.reactor) {
+        const moduleMap = getModuleMap(projectConfig);
+        if (Object.keys(moduleMap).length === 0) {
+          console.log(chalk.yellow('No modules configured; every module is a normal deployment'));
+        }
+        for (const [name, entry] of Object.entries(moduleMap)) {
+          const deploymentPath = typeof entry === 'object' ? entry?.path : entry;
+          console.log(`  ${chalk.white.bold(name)}: ${deploymentPath || chalk.gray('normal deployment')}`);
+        }
+        console.log('');
+        return;
+      }
+
+      requireMaven(detection, 'Listing the reactor');
+      const rootPom = findReactorRoot(pomPath, projectConfig.base_path);
+      console.log(chalk.gray(rootPom));
+      for (const module of getReactorModules(rootPom)) {
+        const { deploymentPath } = getModuleEntry(projectConfig, module.artifactId, module.path);
+        const deployment = module.packaging === 'pom' ? chalk.gray('aggregator')
+          : deploymentPath ? chalk.yellow(`global module ${deploymentPath}`) : 'normal deployment';
+        const marker = module.path === moduleInfo.path ? chalk.green(`${symbol('arrow')} `) : '  ';
+        console.log(`${marker}${'  '.repeat(module.depth)}${chalk.white.bold(module.artifactId)} (${module.packaging}) ${deployment}`);
+      }
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Show clients command
+ */
+program
+  .command('clients')
+  .description('Show available clients for current project')
+  .action(() => {
+    try {
+      console.log(chalk.blue.bold('\n=== Available Clients ===\n'));
+
+      const config = loadConfig();
+      const detection = applyDefaults(detectProject(config));
+
+      const clients = detection.projectConfig.clients;
+
+      if (!clients || Object.keys(clients).length === 0) {
+        console.log(chalk.yellow('No clients configured for this project'));
+        console.log('');
+        return;
+      }
+
+      Object.entries(clients).forEach(([name, client]) => {
+        const label = chalk.white.bold(name);
+
+        if (client.environments) {
+          console.log(`  ${label}:`);
+          for (const envName of Object.keys(client.environments)) {
+            const envConfig = getClientConfig(detection.projectConfig, name, envName);
+            const remote = envConfig.host || envConfig.hosts
+              ? getClientHosts(envConfig).map(describeRoute).join(', ')
+              : 'No remote config';
+            console.log(`    ${envName}: ${remote}`);
+          }
+          return;
+        }
+
+        const clientConfig = getClientConfig(detection.projectConfig, name);
+        const remote = clientConfig.host || clientConfig.hosts
+          ? getClientHosts(clientConfig).map(describeRoute).join(', ')
+          : 'No remote config';
+        console.log(`  ${label}: ${remote}`);
+      });
+
+      if (detection.projectConfig.default_client) {
+        console.log('');
+        console.log(`Default client: ${chalk.green(detection.projectConfig.default_client)}`);
+      }
+
+      console.log('');
+
+    } catch (error) {
+      console.error(chalk.red(`\nError: ${error.message}\n`));
+      process.exit(1);
+    }
+  });
+
+/**
+ * Show help on error
+ */
+const helpText = `
+Examples:
+
+  $ jmw build
+  $ jmw build TEST
+  $ jmw build TEST --client metrocargo
+  $ jmw -C ~/Work/mto/EJBMto build TEST
+  $ jmw build TEST --verify-reproducible
+  $ jmw build TEST --changed --with-dependents
+  $ jmw build --changed --base origin/develop
+  $ jmw deploy ./target/myapp.jar
+  $ jmw --instance hotfix deploy ./target/myapp.war
+  $ jmw deploy ./target/myapp.war --client psa
+  $ jmw deploy ./target/myapp.war --client trieste --env staging
+  $ jmw deploy ./target/myapp.war --client trieste --server-group backend-group
+  $ jmw deploy ./target/myapp.war --client psa --auto-rollback
+  $ jmw deploy ./target/myapp.war --client metro --canary node-a
+  $ jmw deploy ./target/myapp.war --client metro --canary node-a --promote
+  $ jmw deploy ./target/myapp.war --client psa --dry-run
+  $ jmw -y -q build TEST
+  $ jmw backups --client trieste
+  $ jmw outbox retry
+  $ jmw module sync --client trieste --dry-run
+  $ jmw cli ":read-attribute(name=server-state)"
+  $ jmw server start
+  $ jmw deployments --client psa
+  $ jmw ds test
+  $ jmw ds test OracleDS --client psa
+  $ jmw server stop --timeout 30
+  $ jmw server clean-cache --stop
+  $ jmw restart
+  $ jmw deploy target/app.war --ignore-state
+  $ jmw deploy target/app.war --client psa --force-clean
+  $ jmw scanner --client psa
+  $ jmw scanner --client psa --enable --interval 5000
+  $ jmw loglevel com.acme DEBUG --client psa
+  $ jmw loglevel --client psa
+  $ jmw disable app-1.2.war --client psa
+  $ jmw enable app-1.3.war --client psa
+  $ jmw topology --client psa
+  $ jmw mgmt add-user --client psa --generate
+  $ jmw status --client psa
+  $ jmw doctor --client psa
+  $ jmw restart --client psa --full
+  $ jmw cli "deployment-info" --client psa
+  $ jmw cli run scripts/post-deploy.cli --client psa --var pool_size=20
+  $ jmw sync
+  $ jmw sync --client trieste
+  $ jmw itest --test '*RepositoryIT'
+  $ jmw profiles PROD
+  $ jmw sources --missing-only
+  $ jmw warmup --client psa
+  $ jmw env diff test prod
+  $ jmw clients
+  $ jmw modules --reactor
+  $ jmw config validate
+  $ jmw config validate --deep
+  $ jmw config migrate --dry-run
+  $ jmw config add-module
+  $ jmw config scan ~/Work
+  $ jmw history --all
+  $ jmw audit --client psa
+  $ jmw build TEST --plain > build.log
+  $ jmw deploy target/app.war --client psa --env test --no-interactive
+  $ jmw --config ./team.yaml deploy ./target/myapp.war --client psa
+  $ JMW_CONFIG=~/jmw-staging.yaml jmw clients
+  $ jmw build TEST --progress-fd 3 3>progress.jsonl
+
+Config is read from --config or JMW_CONFIG, otherwise the shared .jmw.yaml in the current
+directory or a parent is merged with the personal $XDG_CONFIG_HOME/jmw/config.yaml
+(default ~/.config/jmw/config.yaml), whose settings win. Files may also be .toml or .json.
+JMW_* variables override any key, e.g. JMW_PROJECTS_SINFOMAR_SKIP_TESTS=false sets
+projects.sinfomar.skip_tests.
 
 For more information: https://github.com/ppowo/jmw
 `;
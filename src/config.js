@@ -4,35 +4,423 @@ import path from 'path';
 import os from 'os';
 import embeddedConfig from '../config.yaml';
 
+import { CONFIG_SCHEMA, validateConfig, formatValidationError } from './schema.js';
+
+const DEFAULT_ENVIRONMENT = 'test';
+
+// Supported config formats, in lookup order
+const CONFIG_EXTENSIONS = ['.yaml', '.yml', '.toml', '.json'];
+
+// Explicit config file from the --config flag
+let configOverride = null;
+
+// Local WildFly instance picked with --instance
+let selectedInstance = null;
+
+/**
+ * Target this named WildFly instance of the project (--instance)
+ */
+function setInstance(name) {
+  selectedInstance = name || null;
+}
+
+// Also check the configured paths of the detected project exist (--strict)
+let strict = false;
+
+// Files each loaded config came from, to locate problems found after loading
+const configSources = new WeakMap();
+
+function setStrict(value) {
+  strict = !!value;
+}
+
+/**
+ * Apply the selected local WildFly instance to a project's settings
+ * Projects may define wildfly_instances ({dev: {wildfly_root, ...}, hotfix: {...}});
+ * the instance from --instance, else default_instance, overrides the project's settings
+ */
+function applyInstance(projectConfig) {
+  const instances = projectConfig.wildfly_instances;
+  const requested = selectedInstance || projectConfig.default_instance;
+  if (!requested) {
+    return projectConfig;
+  }
+
+  const key = findKey(instances, requested);
+  if (!key) {
+    const available = instances ? Object.keys(instances).join(', ') : 'none';
+    throw new Error(`WildFly instance '${requested}' not found. Available instances: ${available}`);
+  }
+  return { ...projectConfig, ...instances[key], instance: key };
+}
+
+/**
+ * Use this config file instead of discovering one (--config)
+ */
+function setConfigPath(configPath) {
+  configOverride = configPath ? path.resolve(configPath) : null;
+}
+
+/**
+ * Find the config files to load, lowest precedence first
+ * --config or JMW_CONFIG name a single file; otherwise the shared .jmw.yaml in the
+ * current directory or a parent is layered under the personal $XDG_CONFIG_HOME/jmw/config.yaml
+ * Each may be .yaml, .yml, .toml or .json. Returns an empty list when none
+ * exists (the embedded config is used)
+ */
+function findConfigPaths() {
+  const explicit = configOverride || process.env.JMW_CONFIG;
+  if (explicit) {
+    if (!fs.existsSync(explicit)) {
+      throw new Error(`Config file not found: ${explicit}`);
+    }
+    return [explicit];
+  }
+
+  const paths = [];
+  let dir = process.cwd();
+  while (true) {
+    const candidate = findConfigFile(dir, '.jmw');
+    if (candidate) {
+      paths.push(candidate);
+      break;
+    }
+    const parent = path.dirname(dir);
+    if (parent === dir) break;
+    dir = parent;
+  }
+
+  const userConfig = findConfigFile(getConfigDir(), 'config');
+  if (userConfig) {
+    paths.push(userConfig);
+  }
+  return paths;
+}
+
+/**
+ * Config file with the given base name in a directory, in any supported format
+ */
+function findConfigFile(dir, baseName) {
+  return CONFIG_EXTENSIONS.map(ext => path.join(dir, baseName + ext)).find(file => fs.existsSync(file));
+}
+
+/**
+ * Parse config file contents by extension: TOML, JSON, otherwise YAML
+ */
+function parseConfigFile(configPath, text) {
+  switch (path.extname(configPath).toLowerCase()) {
+    case '.toml':
+      return Bun.TOML.parse(text);
+    case '.json':
+      return JSON.parse(text);
+    default:
+      return yaml.load(text);
+  }
+}
+
 /**
- * Load and parse config.yaml
+ * Merge an overriding config into a base one
+ * Maps are merged key by key; lists and scalars are replaced
+ */
+function mergeConfig(base, override) {
+  if (!isPlainObject(base) || !isPlainObject(override)) {
+    return override === undefined ? base : override;
+  }
+
+  const merged = { ...base };
+  for (const [key, value] of Object.entries(override)) {
+    merged[key] = mergeConfig(base[key], value);
+  }
+  return merged;
+}
+
+function isPlainObject(value) {
+  return value !== null && typeof value === 'object' && !Array.isArray(value);
+}
+
+/**
+ * Load, parse, merge and validate the discovered config files
  * Expands ~ paths to home directory
- * Supports client parameter for remote deployments
+ * Falls back to the config embedded at build time
+ */
+function loadConfig(configPaths = findConfigPaths()) {
+  if (configPaths.length === 0) {
+    // Bun's YAML loader automatically parses it
+    return checkConfig(interpolateConfig(applyEnvOverrides(expandPaths(embeddedConfig))), []);
+  }
+
+  const sources = [];
+  const config = configPaths.reduce((merged, configPath) => mergeConfig(merged, loadConfigFile(configPath, sources)), {});
+  return checkConfig(interpolateConfig(applyEnvOverrides(config)), sources);
+}
+
+/**
+ * Config files with their contents, including included ones, lowest precedence first
  */
-function loadConfig(configPath) {
-  if (!configPath) {
-    configPath = path.join(os.homedir(), '.config', 'jmw', 'config.yaml');
+function collectConfigSources(configPaths = findConfigPaths()) {
+  const sources = [];
+  configPaths.forEach(configPath => loadConfigFile(configPath, sources));
+  return sources;
+}
+
+/**
+ * Load one config file with the files it includes
+ * `include: [sinfomar.yaml, mto.yaml]` paths are relative to the including file,
+ * which overrides what it includes
+ */
+function loadConfigFile(configPath, sources, including = []) {
+  if (including.includes(configPath)) {
+    throw new Error(`Config include cycle: ${[...including, configPath].join(' -> ')}`);
   }
 
+  let text;
+  let doc;
   try {
-    // Try user config first
-    if (fs.existsSync(configPath)) {
-      const doc = yaml.load(fs.readFileSync(configPath, 'utf8'));
-      return expandPaths(doc);
+    text = fs.readFileSync(configPath, 'utf8');
+    doc = expandPaths(parseConfigFile(configPath, text) || {});
+  } catch (error) {
+    throw new Error(`Failed to load config ${configPath}: ${error.message}`);
+  }
+
+  const { include, ...rest } = doc;
+  const included = [].concat(include || []).reduce((merged, file) => {
+    const includePath = path.resolve(path.dirname(configPath), file);
+    if (!fs.existsSync(includePath)) {
+      throw new Error(`Config ${configPath} includes missing file ${includePath}`);
     }
+    return mergeConfig(merged, loadConfigFile(includePath, sources, [...including, configPath]));
+  }, {});
 
-    // Fall back to config.yaml in project directory
-    const localConfig = path.join(process.cwd(), 'config.yaml');
-    if (fs.existsSync(localConfig)) {
-      const doc = yaml.load(fs.readFileSync(localConfig, 'utf8'));
-      return expandPaths(doc);
+  // Sources are listed in precedence order; key locations are found by indentation,
+  // which TOML tables don't follow
+  if (path.extname(configPath).toLowerCase() !== '.toml') {
+    sources.push({ file: configPath, text });
+  }
+  return mergeConfig(included, rest);
+}
+
+// ${name} placeholders; ${keychain:...} and ${file:...} are secrets resolved when used
+const PLACEHOLDER = /\$\{([^}]+)\}/g;
+const SECRET_PLACEHOLDER = /^(keychain|file):/;
+
+/**
+ * Replace placeholders in a string: ${env:NAME}, ${project}, or a setting found in
+ * the given scopes (nearest first, e.g. ${base_path} or ${wildfly_path})
+ * Anything else is left as it is: it may be resolved by a later pass, or be meant
+ * literally (a WildFly expression like ${jboss.home.dir})
+ */
+function interpolate(value, scopes, where, depth = 0) {
+  // A whole-value ${env:...} is a secret reference, looked up when used
+  if (/^\$\{env:[^}]+\}$/.test(value)) {
+    return value;
+  }
+
+  return value.replace(PLACEHOLDER, (placeholder, name) => {
+    if (SECRET_PLACEHOLDER.test(name)) {
+      return placeholder;
+    }
+    if (name.startsWith('env:')) {
+      const envValue = process.env[name.slice(4)];
+      if (envValue === undefined) {
+        throw new Error(`${where}: environment variable ${name.slice(4)} is not set`);
+      }
+      return envValue;
+    }
+
+    const scope = scopes.find(candidate => ['string', 'number'].includes(typeof candidate[name]));
+    if (!scope) {
+      return placeholder;
     }
+    if (depth > 10) {
+      throw new Error(`${where}: placeholder ${placeholder} refers to itself`);
+    }
+    return interpolate(String(scope[name]), scopes, where, depth + 1);
+  });
+}
 
-    // Fall back to embedded config (Bun's YAML loader automatically parses it)
-    return expandPaths(embeddedConfig);
+/**
+ * Interpolate every string in a config subtree
+ */
+function interpolateTree(node, scopes, where) {
+  if (typeof node === 'string') {
+    return interpolate(node, scopes, where);
+  }
+  if (Array.isArray(node)) {
+    return node.map((item, i) => interpolateTree(item, scopes, `${where}[${i}]`));
+  }
+  if (isPlainObject(node)) {
+    return Object.fromEntries(Object.entries(node).map(([key, value]) =>
+      [key, interpolateTree(value, scopes, `${where}.${key}`)]));
+  }
+  return node;
+}
+
+/**
+ * Resolve placeholders in project settings against the project's own settings
+ * In client settings only ${project} and ${env:...} are resolved here; the rest may
+ * refer to client keys (${host}, ${wildfly_path}) and is resolved by getClientConfig
+ * once client_defaults and environments are merged
+ */
+function interpolateConfig(config) {
+  if (!isPlainObject(config.projects)) {
+    return config;
+  }
+
+  const projects = {};
+  for (const [name, project] of Object.entries(config.projects)) {
+    const { clients, client_defaults, ...settings } = project;
+    const clientScopes = [{ project: name }];
+    projects[name] = {
+      ...interpolateTree(settings, [{ ...project, project: name }], `projects.${name}`),
+      ...(clients !== undefined ? { clients: interpolateTree(clients, clientScopes, `projects.${name}.clients`) } : {}),
+      ...(client_defaults !== undefined ? { client_defaults: interpolateTree(client_defaults, clientScopes, `projects.${name}.client_defaults`) } : {})
+    };
+  }
+  return { ...config, projects };
+}
+
+/**
+ * Reject configs that don't match the schema, listing every problem with its location
+ */
+function checkConfig(config, sources) {
+  const errors = validateConfig(config, sources);
+  if (errors.length > 0) {
+    throw new Error(`Invalid config:\n${errors.map(error => `  ${formatValidationError(error)}`).join('\n')}`);
+  }
+  configSources.set(config, sources);
+  return config;
+}
+
+/**
+ * Reject configs whose paths (base_path, wildfly_root, module directories...) are
+ * missing on this machine, for the given projects or all of them
+ */
+function checkConfigPaths(config, projectNames = null) {
+  const errors = validateConfig(config, configSources.get(config) || [], { deep: true, projects: projectNames });
+  if (errors.length > 0) {
+    throw new Error(`Invalid config:\n${errors.map(error => `  ${formatValidationError(error)}`).join('\n')}`);
+  }
+}
+
+/**
+ * With --strict, check the paths of the project a command works on; the
+ * others may well not exist on this machine
+ */
+function checkStrictPaths(config, projectName) {
+  if (strict) {
+    checkConfigPaths(config, [projectName]);
+  }
+}
+
+// JMW_* variables that are not config overrides
+const RESERVED_ENV = ['JMW_CONFIG', 'JMW_SSH_PASSWORD', 'JMW_SUDO_PASSWORD'];
+
+/**
+ * Apply JMW_* environment variables on top of the parsed config
+ * JMW_PROJECTS_SINFOMAR_SKIP_TESTS=true sets projects.sinfomar.skip_tests. Only
+ * settings the schema knows are set, matching underscores against setting names and
+ * existing project, client or other map keys; other variables are ignored. Values
+ * are parsed by the setting's type, so JMW_..._EXPECTED_VERSION=26.1 stays a string
+ */
+function applyEnvOverrides(config, env = process.env) {
+  let result = config;
+  for (const [name, raw] of Object.entries(env)) {
+    if (!name.startsWith('JMW_') || RESERVED_ENV.includes(name) || raw === undefined) {
+      continue;
+    }
+
+    const tokens = name.slice(4).toLowerCase().split('_').filter(Boolean);
+    const target = findOverrideTarget(result, CONFIG_SCHEMA, tokens);
+    if (target) {
+      result = setAt(result, target.keys, expandPaths({ value: parseOverride(name, raw, target.schema) }).value);
+    }
+  }
+  return result;
+}
+
+/**
+ * Key path and schema of the setting env var tokens spell, or null when the schema
+ * has none. At each level setting names and existing keys are tried first, longest
+ * first, then a new key of a map (a project or client not in the files)
+ */
+function findOverrideTarget(node, schema, tokens) {
+  if (tokens.length === 0) {
+    return { keys: [], schema };
+  }
+
+  for (const alternative of schema.anyOf ?? [schema]) {
+    if (alternative.type !== 'object') continue;
+    const map = isPlainObject(node) ? node : {};
+    const entries = typeof alternative.additionalProperties === 'object' ? alternative.additionalProperties : null;
+
+    const candidates = [];
+    for (let length = tokens.length; length > 0; length--) {
+      const name = tokens.slice(0, length).join('_');
+      const key = findKey(map, name);
+      if (alternative.properties?.[name]) {
+        candidates.push({ key: key ?? name, schema: alternative.properties[name], length });
+      } else if (entries && key !== undefined) {
+        candidates.push({ key, schema: entries, length });
+      }
+    }
+    for (let length = tokens.length - 1; entries && length > 0; length--) {
+      candidates.push({ key: tokens.slice(0, length).join('_'), schema: entries, length });
+    }
+
+    for (const candidate of candidates) {
+      const rest = findOverrideTarget(map[candidate.key], candidate.schema, tokens.slice(candidate.length));
+      if (rest) {
+        return { keys: [candidate.key, ...rest.keys], schema: rest.schema };
+      }
+    }
+  }
+  return null;
+}
+
+/**
+ * Value of an override for a setting's schema: strings as they are, numbers and
+ * booleans parsed, lists and objects as YAML
+ */
+function parseOverride(name, raw, schema) {
+  let parsed;
+  try {
+    parsed = yaml.load(raw);
   } catch (error) {
-    throw new Error(`Failed to load config: ${error.message}`);
+    parsed = undefined;
   }
+
+  const alternatives = schema.anyOf ?? [schema];
+  for (const alternative of alternatives) {
+    if (alternative.enum?.includes(raw)) return raw;
+    if (alternative.type === 'number' && raw.trim() !== '' && !Number.isNaN(Number(raw))) return Number(raw);
+    if (alternative.type === 'boolean' && typeof parsed === 'boolean') return parsed;
+    if (alternative.type === 'array' && Array.isArray(parsed)) {
+      return alternative.items?.type === 'string' ? parsed.map(String) : parsed;
+    }
+    if (alternative.type === 'object' && isPlainObject(parsed)) return parsed;
+  }
+  if (alternatives.some(alternative => alternative.type === 'string')) {
+    return raw;
+  }
+  throw new Error(`${name}: '${raw}' is not a valid value for this setting`);
+}
+
+/**
+ * Copy of a config with a value set at a key path
+ */
+function setAt(node, keys, value) {
+  if (keys.length === 0) {
+    return value;
+  }
+  const map = isPlainObject(node) ? node : {};
+  return { ...map, [keys[0]]: setAt(map[keys[0]], keys.slice(1), value) };
+}
+
+/**
+ * Directory for user config and jmw state files ($XDG_CONFIG_HOME/jmw, default ~/.config/jmw)
+ */
+function getConfigDir() {
+  return path.join(process.env.XDG_CONFIG_HOME || path.join(os.homedir(), '.config'), 'jmw');
 }
 
 /**
@@ -47,8 +435,8 @@ function expandPaths(obj) {
 
   const expanded = {};
   for (const [key, value] of Object.entries(obj)) {
-    if (typeof value === 'string' && value.startsWith('~')) {
-      expanded[key] = value.replace('~', os.homedir());
+    if (typeof value === 'string' && /^~(?=$|[\\/])/.test(value)) {
+      expanded[key] = path.join(os.homedir(), value.slice(1));
     } else if (typeof value === 'object') {
       expanded[key] = expandPaths(value);
     } else {
@@ -60,8 +448,13 @@ function expandPaths(obj) {
 
 /**
  * Get client configuration for a project
+ * Clients may define named environments (test/staging/prod); the selected
+ * environment's settings override the client's shared ones. Defaults to the
+ * client's default_environment, "test", or its only environment; null when none
+ * of those applies, for callers to ask which one (see requireClientConfig)
+ * Clients inherit the project's client_defaults and only set what differs
  */
-function getClientConfig(project, clientName) {
+function getClientConfig(project, clientName, envName) {
   if (!clientName) return null;
 
   if (!project.clients || !project.clients[clientName]) {
@@ -69,11 +462,105 @@ function getClientConfig(project, clientName) {
     throw new Error(`Client '${clientName}' not found. Available clients: ${available}`);
   }
 
-  return project.clients[clientName];
+  // Settings shared by all clients (user, wildfly_path, restart_cmd...) sit under client_defaults
+  const { environments, ...client } = mergeConfig(project.client_defaults || {}, project.clients[clientName]);
+
+  if (!environments) {
+    if (envName) {
+      throw new Error(`Client '${clientName}' has no environments configured`);
+    }
+    return interpolateClient(client, project, clientName);
+  }
+
+  const names = Object.keys(environments);
+  if (!envName && !client.default_environment && !findKey(environments, DEFAULT_ENVIRONMENT) && names.length !== 1) {
+    return null;
+  }
+  const requested = envName || client.default_environment || (names.length === 1 ? names[0] : DEFAULT_ENVIRONMENT);
+  const envKey = findKey(environments, requested);
+  if (!envKey) {
+    throw new Error(`Environment '${requested}' not found for client '${clientName}'. Available environments: ${Object.keys(environments).join(', ')}`);
+  }
+
+  return interpolateClient({ ...client, ...environments[envKey], environment: envKey }, project, clientName);
+}
+
+/**
+ * Client configuration for commands acting on a client, where a client whose
+ * environment can't be defaulted is an error rather than null
+ */
+function requireClientConfig(project, clientName, envName) {
+  const clientConfig = getClientConfig(project, clientName, envName);
+  if (clientName && !clientConfig) {
+    const names = Object.keys(mergeConfig(project.client_defaults || {}, project.clients[clientName]).environments).join(', ');
+    throw new Error(`Client '${clientName}' has no default environment; choose one with --env (${names})`);
+  }
+  return clientConfig;
+}
+
+/**
+ * Environments to pick from for a client when none is given and getClientConfig
+ * would have none to default to: null unless there are several
+ */
+function getEnvironmentCandidates(project, clientName) {
+  if (!project.clients?.[clientName]) {
+    return null;
+  }
+  const { environments, default_environment } = mergeConfig(project.client_defaults || {}, project.clients[clientName]);
+  const names = Object.keys(environments || {});
+  if (default_environment || findKey(environments, DEFAULT_ENVIRONMENT) || names.length < 2) {
+    return null;
+  }
+  return names;
+}
+
+/**
+ * Resolve the placeholders left in a merged client config, client settings first
+ * A client without its own retry policy gets the project's, so SSH to its hosts uses it too
+ */
+function interpolateClient(client, project, clientName) {
+  const inherited = client.retry === undefined && project.retry !== undefined ? { ...client, retry: project.retry } : client;
+  return interpolateTree(inherited, [{ ...inherited, client: clientName }, project], `clients.${clientName}`);
+}
+
+/**
+ * Find a map key case-insensitively
+ */
+function findKey(map, name) {
+  if (!map || !name) return undefined;
+  return Object.keys(map).find(key => key.toLowerCase() === String(name).toLowerCase());
+}
+
+/**
+ * Expand a client into one config per host
+ * Clients may list several nodes (e.g., behind a load balancer) under hosts
+ */
+function getClientHosts(clientConfig) {
+  const hosts = clientConfig.hosts ?? [clientConfig.host];
+  return hosts.map(host => {
+    const { hosts: _, ...rest } = clientConfig;
+    return { ...rest, host };
+  });
 }
 
 export {
   loadConfig,
   getClientConfig,
+  requireClientConfig,
+  getEnvironmentCandidates,
+  getClientHosts,
+  findKey,
+  getConfigDir,
+  setConfigPath,
+  setInstance,
+  setStrict,
+  checkConfigPaths,
+  checkStrictPaths,
+  applyInstance,
+  findConfigPaths,
+  parseConfigFile,
+  collectConfigSources,
+  mergeConfig,
+  applyEnvOverrides,
   expandPaths
 };
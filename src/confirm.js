@@ -0,0 +1,255 @@
+import readline from 'readline';
+import chalk from 'chalk';
+
+import { findKey } from './config.js';
+import { symbol } from './output.js';
+
+const MODES = ['never', 'always', 'typed'];
+const DEFAULT_MODE = 'always';
+
+// Choices select shows at once; the list scrolls past them
+const SELECT_ROWS = 10;
+
+let assumeYes = false;
+let interactive = true;
+
+/**
+ * Answer yes/no prompts with yes (--yes); typed confirmations are still asked
+ */
+function setAssumeYes(value) {
+  assumeYes = !!value;
+}
+
+/**
+ * Let commands ask for a module, environment, profile or server group that wasn't
+ * given; --no-interactive turns that off for scripts, which then get an error
+ * (a missing profile builds without one). Without a terminal on stdin nothing is asked either
+ */
+function setInteractive(value) {
+  interactive = value !== false;
+}
+
+function isInteractive() {
+  return interactive && Boolean(process.stdin.isTTY);
+}
+
+/**
+ * Resolve confirmation mode for an operation in an environment
+ * A rule is either a mode, or a map of environment (profile/client/environment name) to mode
+ * with optional default. Several candidate names may be given, most specific first
+ */
+function getConfirmationMode(policy, operation, environment) {
+  const rule = policy?.[operation] ?? policy?.default ?? DEFAULT_MODE;
+  const candidates = (Array.isArray(environment) ? environment : [environment]).filter(Boolean);
+
+  let mode = rule;
+  if (typeof rule === 'object') {
+    const key = candidates.map(name => findKey(rule, name)).find(Boolean);
+    mode = key ? rule[key] : (rule.default ?? policy.default ?? DEFAULT_MODE);
+  }
+
+  if (!MODES.includes(mode)) {
+    throw new Error(`Invalid confirmation mode '${mode}' for ${operation} (expected ${MODES.join('|')})`);
+  }
+  return mode;
+}
+
+/**
+ * Ask for confirmation as required by the policy
+ * Typed confirmations require entering the expected value (e.g., client or artifact name)
+ */
+async function confirmAction(policy, operation, { message, environment, expected } = {}) {
+  const mode = getConfirmationMode(policy, operation, environment);
+
+  if (mode === 'never') {
+    return true;
+  }
+
+  if (mode === 'typed' && expected) {
+    return confirmTyped(message, expected);
+  }
+
+  return confirm(message);
+}
+
+/**
+ * Simple confirmation prompt; options.assumeYes: false asks even with --yes
+ */
+function confirm(message, options = {}) {
+  if (assumeYes && options.assumeYes !== false) {
+    console.log(`${message} (y/N) yes`);
+    return Promise.resolve(true);
+  }
+  return ask(message + ' (y/N) ').then(answer =>
+    answer.toLowerCase() === 'y' || answer.toLowerCase() === 'yes');
+}
+
+/**
+ * Confirmation prompt that requires typing a value exactly
+ */
+function confirmTyped(message, expected) {
+  return ask(`${message}\n${chalk.yellow(`Type '${expected}' to confirm:`)} `).then(answer =>
+    answer.trim() === expected);
+}
+
+/**
+ * Prompt for a secret without echoing it
+ */
+function askSecret(question) {
+  return new Promise(resolve => {
+    const rl = readline.createInterface({
+      input: process.stdin,
+      output: process.stdout,
+      terminal: true
+    });
+
+    rl.on('SIGINT', () => {
+      rl.close();
+      process.kill(process.pid, 'SIGINT');
+    });
+
+    rl.question(question, answer => {
+      rl.close();
+      process.stdout.write('\n');
+      resolve(answer);
+    });
+
+    // Show the prompt, then swallow the echo of typed characters
+    rl._writeToOutput = () => {};
+  });
+}
+
+/**
+ * Let the user pick one of several choices ({label, value, hint} or plain strings):
+ * typing filters, arrow keys move, Enter picks and Esc cancels (null)
+ * Without a terminal the choices are numbered and read as a line instead
+ */
+function select(message, choices) {
+  const items = choices.map(choice => typeof choice === 'string' ? { label: choice, value: choice } : choice);
+  if (!process.stdin.isTTY || !process.stdout.isTTY) {
+    return selectByNumber(message, items);
+  }
+
+  return new Promise(resolve => {
+    let filter = '';
+    let index = 0;
+    let drawn = 0;
+    const matching = () => items.filter(item => item.label.toLowerCase().includes(filter.toLowerCase()));
+
+    const clear = () => {
+      readline.moveCursor(process.stdout, 0, -drawn);
+      readline.clearScreenDown(process.stdout);
+    };
+
+    const render = () => {
+      const shown = matching();
+      const start = Math.min(Math.max(index - SELECT_ROWS + 1, 0), Math.max(shown.length - SELECT_ROWS, 0));
+      const lines = [`${chalk.blue('?')} ${message}: ${filter || chalk.gray('type to filter, arrows to move, Enter to pick, Esc to cancel')}`];
+      shown.slice(start, start + SELECT_ROWS).forEach((item, i) => {
+        const current = start + i === index;
+        const hint = item.hint ? chalk.gray(` ${item.hint}`) : '';
+        lines.push(current ? `${chalk.green(symbol('arrow'))} ${chalk.green(item.label)}${hint}` : `  ${item.label}${hint}`);
+      });
+      if (shown.length === 0) {
+        lines.push(chalk.yellow(`  Nothing matches '${filter}'`));
+      } else if (shown.length > SELECT_ROWS) {
+        lines.push(chalk.gray(`  ${shown.length} matches`));
+      }
+      clear();
+      process.stdout.write(`${lines.join('\n')}\n`);
+      drawn = lines.length;
+    };
+
+    const finish = (value, answer) => {
+      process.stdin.off('keypress', onKeypress);
+      process.stdin.setRawMode(false);
+      process.stdin.pause();
+      clear();
+      console.log(`${chalk.blue('?')} ${message}: ${answer}`);
+      resolve(value);
+    };
+
+    const onKeypress = (text, key = {}) => {
+      const shown = matching();
+      if (key.ctrl && key.name === 'c') {
+        // Forward Ctrl-C to the process-wide cancellation handler, as ask does
+        finish(null, chalk.gray('cancelled'));
+        process.kill(process.pid, 'SIGINT');
+      } else if (key.name === 'escape') {
+        finish(null, chalk.gray('cancelled'));
+      } else if (key.name === 'return') {
+        if (shown[index]) {
+          finish(shown[index].value, chalk.green(shown[index].label));
+        }
+      } else if (key.name === 'up' || key.name === 'down') {
+        if (shown.length > 0) {
+          index = (index + (key.name === 'up' ? shown.length - 1 : 1)) % shown.length;
+          render();
+        }
+      } else if (key.name === 'backspace') {
+        filter = filter.slice(0, -1);
+        index = 0;
+        render();
+      } else if (text && !key.ctrl && !key.meta && text >= ' ') {
+        filter += text;
+        index = 0;
+        render();
+      }
+    };
+
+    readline.emitKeypressEvents(process.stdin);
+    process.stdin.setRawMode(true);
+    process.stdin.resume();
+    process.stdin.on('keypress', onKeypress);
+    render();
+  });
+}
+
+/**
+ * select without a terminal: a numbered list, answered by number or name
+ */
+async function selectByNumber(message, items) {
+  items.forEach((item, i) => console.log(`  ${String(i + 1).padStart(2)}) ${item.label}${item.hint ? chalk.gray(` ${item.hint}`) : ''}`));
+  const answer = (await ask(`${message} (number or name, empty to cancel): `)).trim();
+  if (!answer) {
+    return null;
+  }
+  const picked = items[Number(answer) - 1] ?? items.find(item => item.label.toLowerCase() === answer.toLowerCase());
+  if (!picked) {
+    throw new Error(`No choice '${answer}'`);
+  }
+  return picked.value;
+}
+
+function ask(question) {
+  return new Promise(resolve => {
+    const rl = readline.createInterface({
+      input: process.stdin,
+      output: process.stdout
+    });
+
+    // Forward Ctrl-C at the prompt to the process-wide cancellation handler
+    rl.on('SIGINT', () => {
+      rl.close();
+      process.kill(process.pid, 'SIGINT');
+    });
+
+    rl.question(question, answer => {
+      rl.close();
+      resolve(answer);
+    });
+  });
+}
+
+export {
+  setAssumeYes,
+  setInteractive,
+  isInteractive,
+  getConfirmationMode,
+  confirmAction,
+  confirm,
+  confirmTyped,
+  ask,
+  askSecret,
+  select
+};
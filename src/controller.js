@@ -0,0 +1,118 @@
+import { $ } from 'bun';
+import chalk from 'chalk';
+
+import { createManagementClient } from './mgmt.js';
+import { resolveManagementConfig } from './secrets.js';
+import { getCliPath, withControllerArgs, getRemoteCliCommand } from './jbosscli.js';
+import { runRemote, shellQuote } from './remote.js';
+import { resolveManagementPort } from './ports.js';
+import { NO_RETRY, getRetryPolicy } from './retry.js';
+
+/**
+ * Run DMR operations against a WildFly: through the HTTP management API when
+ * credentials are configured, otherwise through jboss-cli (locally, or on the
+ * host over SSH so local authentication works). Without a configured port, the
+ * port of the server's own config (offsets included) is used. Connection failures
+ * are retried with the retry policy unless retry is false
+ * Returns {target, execute(address, operation, params)} resolving to the result
+ */
+async function createController(wildflyConfig, hostConfig = null, { retry = true } = {}) {
+  const configured = hostConfig ? hostConfig.management ?? wildflyConfig.management : wildflyConfig.management;
+  const management = await resolveManagementPort(configured, wildflyConfig, hostConfig);
+
+  if (management?.user) {
+    const client = createManagementClient(management, hostConfig?.host, retry ? getRetryPolicy(wildflyConfig.retry) : NO_RETRY);
+    return {
+      target: client.baseUrl,
+      execute: (address, operation, params = {}) => client.execute({ operation, address, ...params })
+    };
+  }
+
+  const resolved = await resolveManagementConfig(management);
+  if (!hostConfig) {
+    const cliPath = getCliPath(wildflyConfig.root);
+    return {
+      target: 'local jboss-cli',
+      execute: async (address, operation, params = {}) => {
+        const command = toCliCommand(address, operation, params);
+        const result = await withControllerArgs(resolved, controllerArgs =>
+          $`${cliPath} ${controllerArgs} --output-json --command=${command}`.quiet().nothrow());
+        return parseCliOutput(result.stdout.toString() || result.stderr.toString(), command);
+      }
+    };
+  }
+
+  const sshConfig = retry ? hostConfig : { ...hostConfig, retry: NO_RETRY };
+  return {
+    target: `jboss-cli on ${hostConfig.host}`,
+    execute: async (address, operation, params = {}) => {
+      const command = toCliCommand(address, operation, params);
+      const remote = getRemoteCliCommand(hostConfig, resolved, ['--output-json', `--command=${command}`].map(shellQuote).join(' '));
+      const output = await runRemote(sshConfig, `${remote.command}\ntrue`, remote.input);
+      return parseCliOutput(output, command);
+    }
+  };
+}
+
+/**
+ * Run a step on each host of a client a command talks to: only the first in domain
+ * mode, as the domain controller reaches every server, else every host. Each gets a
+ * "=== title (host, controller) ===" header (the WildFly path with connect: false,
+ * where run gets no controller); a failing host is reported and the rest still run
+ * run(controller, hostConfig) may resolve false for a failure. Resolves whether all succeeded
+ */
+async function forEachController(wildflyConfig, hostConfigs, title, run, { connect = true, retry = true } = {}) {
+  let succeeded = true;
+  for (const hostConfig of wildflyConfig.mode === 'domain' ? hostConfigs.slice(0, 1) : hostConfigs) {
+    let header = false;
+    try {
+      const controller = connect ? await createController(wildflyConfig, hostConfig, { retry }) : null;
+      console.log(chalk.blue(`=== ${title} (${hostConfig.host}, ${controller ? controller.target : hostConfig.wildfly_path}) ===`));
+      header = true;
+      succeeded = await run(controller, hostConfig) !== false && succeeded;
+    } catch (error) {
+      if (!header) {
+        console.log(chalk.blue(`=== ${title} (${hostConfig.host}) ===`));
+      }
+      console.log(chalk.red(`  ${error.message}`));
+      succeeded = false;
+    }
+    console.log('');
+  }
+  return succeeded;
+}
+
+/**
+ * jboss-cli form of a DMR operation: [{deployment: 'app.war'}], 'read-resource',
+ * {'include-runtime': true} becomes /deployment=app.war:read-resource(include-runtime=true)
+ */
+function toCliCommand(address, operation, params = {}) {
+  const node = address.map(entry => Object.entries(entry).map(([key, value]) => `/${key}=${value}`).join('')).join('');
+  const args = Object.entries(params).map(([key, value]) => `${key}=${typeof value === 'object' ? JSON.stringify(value) : value}`);
+  return `${node}:${operation}${args.length > 0 ? `(${args.join(',')})` : ''}`;
+}
+
+/**
+ * Result of jboss-cli --output-json output, throwing the failure description
+ */
+function parseCliOutput(output, command) {
+  let body;
+  try {
+    body = JSON.parse(output);
+  } catch (error) {
+    throw new Error(`jboss-cli '${command}' failed: ${output.trim().slice(0, 200) || 'no output'}`);
+  }
+  if (body.outcome !== 'success') {
+    const failure = typeof body['failure-description'] === 'string'
+      ? body['failure-description']
+      : JSON.stringify(body['failure-description']);
+    throw new Error(`${command} failed: ${failure}`);
+  }
+  return body.result;
+}
+
+export {
+  createController,
+  forEachController,
+  toCliCommand
+};
@@ -0,0 +1,158 @@
+import fs from 'fs';
+import path from 'path';
+import chalk from 'chalk';
+
+import { requireClientConfig, getClientHosts } from './config.js';
+import { getWildflyConfig } from './deployer.js';
+import { createController, forEachController } from './controller.js';
+import { symbol } from './output.js';
+
+const DATASOURCE_TYPES = ['data-source', 'xa-data-source'];
+const SCANNED_EXTENSIONS = new Set(['.xml', '.java', '.properties']);
+
+/**
+ * JNDI names (java:...) a module's sources mention: persistence.xml data sources,
+ * @Resource lookups, resource references in deployment descriptors
+ */
+function findReferencedJndiNames(modulePath) {
+  const names = new Set();
+  const visit = dir => {
+    let entries;
+    try {
+      entries = fs.readdirSync(dir, { withFileTypes: true });
+    } catch (error) {
+      return;
+    }
+    for (const entry of entries) {
+      const full = path.join(dir, entry.name);
+      if (entry.isDirectory()) {
+        visit(full);
+      } else if (SCANNED_EXTENSIONS.has(path.extname(entry.name))) {
+        for (const [name] of fs.readFileSync(full, 'utf8').matchAll(/java:[\w/.:-]+/g)) {
+          names.add(normalizeJndiName(name));
+        }
+      }
+    }
+  };
+  visit(path.join(modulePath, 'src', 'main'));
+  return names;
+}
+
+/**
+ * java:/FooDS and java:FooDS name the same binding
+ */
+function normalizeJndiName(name) {
+  return name.replace(/^java:\/?/, 'java:/').replace(/\/$/, '');
+}
+
+/**
+ * Datasources the server defines: [{type, name, jndiName}]
+ */
+async function readDatasources(controller, profileAddress) {
+  const datasources = [];
+  for (const type of DATASOURCE_TYPES) {
+    const entries = await controller.execute([...profileAddress, { subsystem: 'datasources' }, { [type]: '*' }], 'read-resource');
+    for (const { address, result } of entries || []) {
+      const name = Object.values(address[address.length - 1])[0];
+      datasources.push({ type, name, jndiName: result['jndi-name'] });
+    }
+  }
+  return datasources;
+}
+
+/**
+ * Addresses of the running servers a datasource test runs on: the server itself in
+ * standalone mode, each server of the group (or of the domain) in domain mode
+ * Returns [{label, address, profile}] where profile addresses the datasource config
+ */
+async function getTestTargets(controller, wildflyConfig) {
+  if (wildflyConfig.mode !== 'domain') {
+    return [{ label: null, address: [], profile: [] }];
+  }
+
+  const targets = [];
+  for (const host of await controller.execute([], 'read-children-names', { 'child-type': 'host' })) {
+    const configs = await controller.execute([{ host }, { 'server-config': '*' }], 'read-resource', { 'include-runtime': true });
+    for (const { address, result } of configs || []) {
+      const server = address[address.length - 1]['server-config'];
+      if (wildflyConfig.serverGroup && result.group !== wildflyConfig.serverGroup) continue;
+      if (result.status && result.status !== 'STARTED') continue;
+      const profile = await controller.execute([{ 'server-group': result.group }], 'read-attribute', { name: 'profile' });
+      targets.push({ label: `${host}/${server}`, address: [{ host }, { server }], profile: [{ profile }] });
+    }
+  }
+  return targets;
+}
+
+/**
+ * Test the pool connections of datasources through one controller
+ * Without a name, the datasources the module references are tested, or all of them
+ * when it references none the server defines
+ */
+async function testDatasourcesOn(controller, wildflyConfig, moduleInfo, name) {
+  const targets = await getTestTargets(controller, wildflyConfig);
+  if (targets.length === 0) {
+    console.log(chalk.yellow('  No running servers to test on'));
+    return false;
+  }
+
+  const datasources = await readDatasources(controller, targets[0].profile);
+  let selected;
+  if (name) {
+    selected = datasources.filter(ds => ds.name === name || normalizeJndiName(ds.jndiName || '') === normalizeJndiName(name));
+    if (selected.length === 0) {
+      throw new Error(`Datasource '${name}' not found. Available datasources: ${datasources.map(ds => ds.name).join(', ') || 'none'}`);
+    }
+  } else {
+    const referenced = findReferencedJndiNames(moduleInfo.path);
+    selected = datasources.filter(ds => ds.jndiName && referenced.has(normalizeJndiName(ds.jndiName)));
+    if (selected.length === 0) {
+      console.log(chalk.gray(`  ${moduleInfo.artifactId} references none of the server's datasources, testing all`));
+      selected = datasources;
+    }
+  }
+
+  let succeeded = true;
+  for (const target of targets) {
+    if (target.label) {
+      console.log(chalk.white.bold(`  ${target.label}`));
+    }
+    for (const ds of selected) {
+      const indent = target.label ? '    ' : '  ';
+      const label = `${ds.name} ${chalk.gray(`(${ds.jndiName})`)}`;
+      try {
+        await controller.execute([...target.address, { subsystem: 'datasources' }, { [ds.type]: ds.name }], 'test-connection-in-pool');
+        console.log(`${indent}${chalk.green(symbol('check'))} ${label}`);
+      } catch (error) {
+        console.log(`${indent}${chalk.red(symbol('cross'))} ${label}`);
+        console.log(chalk.red(`${indent}  ${error.message}`));
+        succeeded = false;
+      }
+    }
+  }
+  return succeeded;
+}
+
+/**
+ * Test datasource connections on the local WildFly or a client's hosts
+ */
+async function testDatasources(detection, name, options = {}) {
+  const { projectConfig, module: moduleInfo } = detection;
+
+  if (!options.client) {
+    const wildflyConfig = getWildflyConfig(projectConfig, null);
+    const controller = await createController(wildflyConfig);
+    console.log(chalk.blue(`=== Datasource Test (local, ${controller.target}) ===`));
+    return testDatasourcesOn(controller, wildflyConfig, moduleInfo, name);
+  }
+
+  const clientConfig = requireClientConfig(projectConfig, options.client, options.env);
+  const wildflyConfig = getWildflyConfig(projectConfig, clientConfig);
+  return forEachController(wildflyConfig, getClientHosts(clientConfig), 'Datasource Test', controller =>
+    testDatasourcesOn(controller, wildflyConfig, moduleInfo, name));
+}
+
+export {
+  findReferencedJndiNames,
+  testDatasources
+};
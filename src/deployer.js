@@ -1,16 +1,51 @@
 import fs from 'fs';
 import path from 'path';
 import chalk from 'chalk';
-import readline from 'readline';
-
-/**
- * Format file size in human-readable format
- */
-function formatSize(bytes) {
-  if (bytes < 1024) return `${bytes} B`;
-  if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`;
-  return `${(bytes / (1024 * 1024)).toFixed(1)} MB`;
-}
+import { verifyAndWarmup, waitForHealthy } from './health.js';
+import { symbol, formatSize } from './output.js';
+import { getClientConfig, requireClientConfig, getClientHosts } from './config.js';
+import { getSudoPrefix, getRestartCommand } from './remote.js';
+import { describeAuth, describeRoute } from './ssh.js';
+import { executeOperations } from './outbox.js';
+import { confirmAction, confirm, select, isInteractive } from './confirm.js';
+import { emitProgress } from './progress.js';
+import { checkWildflyVersions, findWildflyInstalls, describeWildflyInstalls } from './wildfly.js';
+import { createManagementClient } from './mgmt.js';
+import { getRetryPolicy } from './retry.js';
+import { createController } from './controller.js';
+import { readServerStates, suspendServers, resumeServers } from './server.js';
+import { resolveVerifyConfig } from './ports.js';
+import { suggestKey } from './schema.js';
+import { planPreflightOperation } from './preflight.js';
+import { getCliPath, getScriptPath, runLocalCli } from './jbosscli.js';
+import { createAuditTrail } from './audit.js';
+import {
+  DEFAULT_DEPLOYMENT_TIMEOUT,
+  waitForLocalDeployment,
+  showDeploymentOutcome,
+  findStuckDeployment,
+  showStuckDeployment,
+  cleanDeployment
+} from './scanner.js';
+import { getServerLogPath, waitForLogResult, readLocalLog, showLogResult } from './serverlog.js';
+import {
+  DEFAULT_BACKUP_RETENTION,
+  getBackupDir,
+  planBackupOperation,
+  planRestoreOperation,
+  backupLocalArtifact,
+  restoreLocalArtifact,
+  shouldRollBack
+} from './rollback.js';
+import {
+  getModuleIdentity,
+  getModuleDefinition,
+  getModuleInstallMode,
+  installLocalModuleViaCli,
+  planModuleCliOperation,
+  writeLocalModuleXml,
+  planModuleXmlOperation
+} from './modulexml.js';
 
 /**
  * Create a new deployment result tracker
@@ -59,6 +94,30 @@ function trackMarkerCreated(result, markerPath) {
   });
 }
 
+/**
+ * Track a generated module.xml
+ */
+function trackModuleXmlWritten(result, moduleXmlPath) {
+  result.actions.push({
+    type: 'module_xml_written',
+    path: moduleXmlPath,
+    timestamp: new Date()
+  });
+}
+
+/**
+ * Track a deployment made through the management API
+ */
+function trackManagementDeploy(result, name, target, hash) {
+  result.actions.push({
+    type: 'management_deployed',
+    name,
+    target,
+    hash,
+    timestamp: new Date()
+  });
+}
+
 /**
  * Display deployment summary
  */
@@ -78,11 +137,17 @@ function showDeploymentSummary(result) {
         console.log(`  Created directory: ${action.path}`);
         break;
       case 'file_copied':
-        console.log(`  Copied file: ${path.basename(action.source)} → ${action.dest} (${formatSize(action.size)})`);
+        console.log(`  Copied file: ${path.basename(action.source)} ${symbol('arrow')} ${action.dest} (${formatSize(action.size)})`);
         break;
       case 'marker_created':
         console.log(`  Created marker: ${action.path}`);
         break;
+      case 'module_xml_written':
+        console.log(`  Generated module.xml: ${action.path}`);
+        break;
+      case 'management_deployed':
+        console.log(`  Deployed via management API: ${action.name} ${symbol('arrow')} ${action.target}`);
+        break;
     }
   }
 
@@ -93,7 +158,7 @@ function showDeploymentSummary(result) {
 /**
  * Deploy artifact to WildFly
  */
-async function deployArtifact(artifactPath, detection) {
+async function deployArtifact(artifactPath, detection, options = {}) {
   const { project, projectConfig, module: moduleInfo } = detection;
 
   console.log(chalk.blue('=== Deployment Plan ==='));
@@ -104,15 +169,45 @@ async function deployArtifact(artifactPath, detection) {
 
   // Get WildFly configuration (local deployment)
   const wildflyConfig = getWildflyConfig(projectConfig, null);
+  if (!wildflyConfig.root) {
+    const found = describeWildflyInstalls(findWildflyInstalls());
+    const hint = found.length > 0 ? `; WildFly found at:\n  ${found.join('\n  ')}` : '';
+    throw new Error(`Project ${project} has no wildfly_root${hint}`);
+  }
+  if (wildflyConfig.mode === 'domain' && !moduleInfo.isGlobalModule) {
+    wildflyConfig.serverGroup = await selectServerGroup(wildflyConfig, null, options.serverGroup);
+  }
 
+  if (projectConfig.unit) {
+    console.log(chalk.yellow('Unit:'), projectConfig.unit);
+  }
+  if (projectConfig.instance) {
+    console.log(chalk.yellow('Instance:'), projectConfig.instance);
+  }
   console.log(chalk.yellow('WildFly Root:'), wildflyConfig.root);
   console.log(chalk.yellow('Mode:'), wildflyConfig.mode);
   if (wildflyConfig.mode === 'domain') {
     console.log(chalk.yellow('Server Group:'), wildflyConfig.serverGroup);
   }
+  // Global modules are picked up on the next restart, running or not
+  const serverState = moduleInfo.isGlobalModule ? 'ok' : await checkServerState(wildflyConfig);
+  const scannerDeploy = usesScanner(wildflyConfig, moduleInfo, options);
+  if (scannerDeploy) {
+    showStuckDeployment(path.basename(artifactPath), await findStuckDeployment(wildflyConfig, path.basename(artifactPath)), options.forceClean);
+  }
+
+  if (options.dryRun) {
+    console.log(chalk.gray('\nDry run, nothing deployed'));
+    return;
+  }
+  assertServerUp(serverState, 'the local WildFly', options);
 
   // Confirm deployment
-  const confirmed = await confirm('Proceed with deployment?');
+  const confirmed = await confirmAction(detection.confirmations, 'deploy', {
+    message: 'Proceed with deployment?',
+    environment: 'local',
+    expected: path.basename(artifactPath)
+  });
   if (!confirmed) {
     console.log(chalk.red('Deployment cancelled'));
     return;
@@ -120,15 +215,21 @@ async function deployArtifact(artifactPath, detection) {
 
   // Execute deployment
   const result = createDeploymentResult();
+  const audit = await createAuditTrail(detection.audit, detection, artifactPath);
+  emitProgress('deploy', 0, `Deploying ${path.basename(artifactPath)}`);
 
   try {
+    if (scannerDeploy && options.forceClean) {
+      await cleanDeployment(wildflyConfig, path.basename(artifactPath));
+    }
     if (moduleInfo.isGlobalModule) {
-      await deployGlobalModule(artifactPath, wildflyConfig, moduleInfo, result);
+      await deployGlobalModule(artifactPath, wildflyConfig, moduleInfo, result, getModuleDefinition(projectConfig, moduleInfo.deploymentPath));
     } else {
-      await deployNormal(artifactPath, wildflyConfig, moduleInfo, result);
+      await withSuspendedServers(wildflyConfig, null, () => deployNormal(artifactPath, wildflyConfig, moduleInfo, result));
     }
 
     console.log(chalk.green('Deployment completed'));
+    emitProgress('deploy', 100, 'Deployment completed');
 
     // Show what was done
     showDeploymentSummary(result);
@@ -136,25 +237,539 @@ async function deployArtifact(artifactPath, detection) {
     // Show restart guidance
     showRestartGuidance(wildflyConfig);
 
+    // Hot deployments can be verified right away; global modules wait for a restart
+    if (!moduleInfo.isGlobalModule && (projectConfig.health_check || projectConfig.warmup)) {
+      console.log('');
+      if (!(await verifyAndWarmup(await resolveVerifyConfig(projectConfig, wildflyConfig)))) {
+        throw new Error('Health check failed');
+      }
+    }
+
+    // Recorded once verified, so a failed health check leaves only its 'failed' record
+    await audit('deploy', { host: 'localhost' }, 'deployed');
+
     // Show remote deployment guide if configured (use default client)
     const defaultClientName = projectConfig.default_client;
     if (defaultClientName && projectConfig.clients && projectConfig.clients[defaultClientName]) {
-      const defaultClient = projectConfig.clients[defaultClientName];
-      console.log('');
-      console.log(chalk.blue(`=== Remote Deployment Instructions (Default Client: ${defaultClientName}) ===`));
-      showRemoteDeploymentGuide(artifactPath, wildflyConfig, defaultClient, moduleInfo);
+      // Skipped for a client without a default environment
+      const defaultClient = getClientConfig(projectConfig, defaultClientName);
+      if (defaultClient) {
+        console.log('');
+        console.log(chalk.blue(`=== Remote Deployment Instructions (Default Client: ${defaultClientName}) ===`));
+        showRemoteDeploymentGuide(artifactPath, wildflyConfig, defaultClient, moduleInfo);
+      }
     }
 
   } catch (error) {
     console.error(chalk.red('Deployment failed:'), error.message);
+    emitProgress('deploy', null, 'Deployment failed', { error: error.message });
+
+    await audit('deploy', { host: 'localhost' }, 'failed', error.message);
+
+    if (canRollBack(wildflyConfig, moduleInfo) && await shouldRollBack('local WildFly', options, detection.confirmations, 'local')) {
+      const rolledBack = await rollbackLocal(artifactPath, wildflyConfig, projectConfig);
+      await audit('rollback', { host: 'localhost' }, rolledBack ? 'rolled_back' : 'failed');
+    }
     throw error;
   }
 }
 
+/**
+ * Whether to promote a healthy canary: --promote does so without asking; otherwise
+ * it is asked even with --yes (which a project's defaults may set), and never
+ * assumed without a terminal
+ */
+async function confirmPromotion(message, options) {
+  if (options.promote) {
+    console.log(`${message} yes (--promote)`);
+    return true;
+  }
+  if (!isInteractive()) {
+    console.log(chalk.yellow(`${message} not without --promote`));
+    return false;
+  }
+  return confirm(message, { assumeYes: false });
+}
+
+/**
+ * Deploy artifact to a remote client over SSH
+ * Clients with several hosts are rolled through one by one (stopping at the first
+ * failure) or deployed in parallel. With a canary, one host is deployed and verified
+ * first and the rest only follow on confirmation. Pending steps for unreachable hosts go to the outbox
+ */
+async function deployRemote(artifactPath, detection, clientName, options = {}) {
+  const { project, projectConfig, module: moduleInfo } = detection;
+  const clientConfig = requireClientConfig(projectConfig, clientName, options.env);
+  const wildflyConfig = getWildflyConfig(projectConfig, clientConfig);
+  const hostConfigs = getClientHosts(clientConfig);
+  // Domain controller handles all hosts of the server group, no shell access needed
+  const viaManagement = wildflyConfig.mode === 'domain' && wildflyConfig.management && !moduleInfo.isGlobalModule;
+  // A domain has no deployment scanner, so the marker files of a copy would never be answered
+  if (wildflyConfig.mode === 'domain' && !wildflyConfig.management && !moduleInfo.isGlobalModule) {
+    throw new Error(`Deploying to a remote domain needs the management API (management.user/password for client ${clientName})`);
+  }
+  const canaryConfig = options.canary && !viaManagement ? findCanaryHost(hostConfigs, options.canary) : null;
+  if (wildflyConfig.mode === 'domain' && !moduleInfo.isGlobalModule) {
+    wildflyConfig.serverGroup = await selectServerGroup(wildflyConfig, hostConfigs[0], options.serverGroup);
+  }
+
+  console.log(chalk.blue('=== Remote Deployment Plan ==='));
+  console.log(`Project: ${project}`);
+  console.log(`Artifact: ${artifactPath}`);
+  console.log(`Module: ${moduleInfo.artifactId}`);
+  console.log(`Type: ${moduleInfo.isGlobalModule ? 'Global Module' : 'Normal Deployment'}`);
+  if (projectConfig.unit) {
+    console.log(chalk.yellow('Unit:'), projectConfig.unit);
+  }
+  console.log(chalk.yellow('Client:'), clientName);
+  if (clientConfig.environment) {
+    console.log(chalk.yellow('Environment:'), clientConfig.environment);
+  }
+  hostConfigs.forEach(hostConfig => console.log(chalk.yellow('Host:'), describeRoute(hostConfig)));
+  if (hostConfigs.length > 1) {
+    const rollout = options.parallel ? 'parallel' : 'sequential';
+    console.log(chalk.yellow('Rollout:'), canaryConfig ? `canary ${canaryConfig.host}, then ${rollout}` : rollout);
+  }
+  console.log(chalk.yellow('WildFly Path:'), clientConfig.wildfly_path);
+  if (wildflyConfig.mode === 'domain') {
+    console.log(chalk.yellow('Server Group:'), wildflyConfig.serverGroup);
+  }
+  if (viaManagement && options.canary) {
+    console.log(chalk.yellow('Rollout:'), `canary ${options.canary} (${getCanaryGroup(wildflyConfig.serverGroup)}), then ${wildflyConfig.serverGroup}`);
+  }
+  console.log(chalk.yellow('Auth:'), describeAuth(hostConfigs[0]));
+  if (clientConfig.sudo_user) {
+    console.log(chalk.yellow('Run as:'), clientConfig.sudo_user);
+  }
+  if (clientConfig.compression && clientConfig.compression !== 'none') {
+    console.log(chalk.yellow('Compression:'), clientConfig.compression);
+  }
+  const versionWarnings = await checkWildflyVersions(projectConfig, clientConfig, hostConfigs, wildflyConfig.management);
+  if (versionWarnings > 0 && moduleInfo.isGlobalModule) {
+    console.log(chalk.red('  Global modules built for another WildFly version may fail to load'));
+  }
+  // The domain controller knows every server of the group; standalone hosts are asked one by one
+  const stateHosts = moduleInfo.isGlobalModule ? [] : wildflyConfig.mode === 'domain' ? hostConfigs.slice(0, 1) : hostConfigs;
+  const scannerDeploy = usesScanner(wildflyConfig, moduleInfo, options);
+  const downHosts = [];
+  for (const hostConfig of stateHosts) {
+    if (await checkServerState(wildflyConfig, hostConfig) === 'down') {
+      downHosts.push(hostConfig.host);
+    }
+    if (scannerDeploy) {
+      showStuckDeployment(path.basename(artifactPath), await findStuckDeployment(wildflyConfig, path.basename(artifactPath), hostConfig), options.forceClean);
+    }
+  }
+  console.log('');
+
+  if (options.dryRun) {
+    console.log(chalk.gray('Dry run, nothing deployed'));
+    return true;
+  }
+  if (downHosts.length > 0) {
+    assertServerUp('down', downHosts.join(', '), options);
+  }
+
+  const confirmed = await confirmAction(detection.confirmations, 'deploy', {
+    message: 'Proceed with remote deployment?',
+    environment: [clientConfig.environment, clientName],
+    expected: clientName
+  });
+  if (!confirmed) {
+    console.log(chalk.red('Deployment cancelled'));
+    return false;
+  }
+
+  const audit = await createAuditTrail(detection.audit, detection, artifactPath);
+  const auditHost = (action, result) =>
+    audit(action, { client: clientName, env: clientConfig.environment, host: result.host }, result.status, result.error);
+
+  if (viaManagement && options.canary) {
+    const target = { client: clientName, env: clientConfig.environment, host: options.canary };
+    let result;
+    try {
+      result = await deployDomainCanary(artifactPath, wildflyConfig, hostConfigs, options.canary, options);
+    } catch (error) {
+      await audit('deploy', target, 'failed', error.message);
+      throw error;
+    }
+    await audit('deploy', target, result.status, result.error);
+    showHostResults([result]);
+    return result.status === 'deployed';
+  }
+  if (viaManagement) {
+    const target = { client: clientName, env: clientConfig.environment, host: hostConfigs[0].host };
+    try {
+      await withSuspendedServers(wildflyConfig, hostConfigs[0], () => deployRemoteViaManagement(artifactPath, wildflyConfig, hostConfigs[0]));
+    } catch (error) {
+      await audit('deploy', target, 'failed', error.message);
+      throw error;
+    }
+    await audit('deploy', target, 'deployed');
+    return true;
+  }
+
+  const tagOperations = (operations, hostConfig) =>
+    operations.map(op => ({ ...op, project, unit: projectConfig.unit, client: clientName, env: clientConfig.environment, host: hostConfig.host }));
+
+  const deployToHost = async hostConfig => {
+    const operations = tagOperations(planRemoteOperations(artifactPath, wildflyConfig, hostConfig, moduleInfo, projectConfig), hostConfig);
+
+    console.log('');
+    console.log(chalk.blue(`--- ${hostConfig.host} ---`));
+    try {
+      if (scannerDeploy && options.forceClean) {
+        await cleanDeployment(wildflyConfig, path.basename(artifactPath), hostConfig);
+      }
+      const executed = moduleInfo.isGlobalModule
+        ? await executeOperations(operations, hostConfig)
+        : await withSuspendedServers(wildflyConfig, hostConfig, () => executeOperations(operations, hostConfig));
+      if (!executed) {
+        return { host: hostConfig.host, status: 'queued' };
+      }
+      if (!moduleInfo.isGlobalModule && !(await verifyHost(hostConfig, wildflyConfig))) {
+        return { host: hostConfig.host, status: 'failed', error: 'Health check failed' };
+      }
+      return { host: hostConfig.host, status: 'deployed' };
+    } catch (error) {
+      console.error(chalk.red(`  ${error.message}`));
+      return { host: hostConfig.host, status: 'failed', error: error.message };
+    }
+  };
+
+  const rollbackHost = async (hostConfig, failure) => {
+    const operations = tagOperations(planRollbackOperations(artifactPath, wildflyConfig, hostConfig), hostConfig);
+
+    console.log('');
+    console.log(chalk.blue(`--- ${hostConfig.host} (rollback) ---`));
+    try {
+      if (!(await executeOperations(operations, hostConfig))) {
+        return { ...failure, status: 'queued' };
+      }
+      if (!(await verifyHost(hostConfig, wildflyConfig))) {
+        return { ...failure, error: `${failure.error}; health check failed after rollback` };
+      }
+      return { ...failure, status: 'rolled_back' };
+    } catch (error) {
+      console.error(chalk.red(`  ${error.message}`));
+      return { ...failure, error: `${failure.error}; rollback failed: ${error.message}` };
+    }
+  };
+
+  const rollout = canaryConfig ? [canaryConfig, ...hostConfigs.filter(h => h !== canaryConfig)] : hostConfigs;
+  const results = [];
+
+  // The canary is verified on its own; declining promotion leaves it to the rollback below
+  if (canaryConfig) {
+    const canaryResult = await deployToHost(canaryConfig);
+    await auditHost('deploy', canaryResult);
+    results.push(canaryResult);
+
+    const remaining = rollout.length - 1;
+    const promoted = canaryResult.status === 'deployed' &&
+      await confirmPromotion(`Canary ${canaryConfig.host} is healthy. Promote to the ${remaining} remaining host(s)?`, options);
+    if (!promoted) {
+      if (canaryResult.status === 'deployed') {
+        results[0] = { ...canaryResult, status: 'failed', error: 'Canary not promoted' };
+      }
+      rollout.slice(1).forEach(hostConfig => results.push({ host: hostConfig.host, status: 'skipped' }));
+    }
+  }
+
+  const pending = rollout.slice(results.length);
+  let rolled;
+  if (options.parallel) {
+    rolled = await Promise.all(pending.map(deployToHost));
+  } else {
+    rolled = [];
+    for (const hostConfig of pending) {
+      const failed = [...results, ...rolled].some(r => r.status !== 'deployed');
+      rolled.push(failed ? { host: hostConfig.host, status: 'skipped' } : await deployToHost(hostConfig));
+    }
+  }
+  for (const result of rolled.filter(r => r.status !== 'skipped')) {
+    await auditHost('deploy', result);
+  }
+  results.push(...rolled);
+
+  // Offer rollbacks once the rollout is over so prompts don't interleave with parallel output
+  if (canRollBack(wildflyConfig, moduleInfo)) {
+    for (const [i, result] of results.entries()) {
+      if (result.status === 'failed' && await shouldRollBack(result.host, options, detection.confirmations, [clientConfig.environment, clientName])) {
+        results[i] = await rollbackHost(rollout[i], result);
+        await auditHost('rollback', results[i]);
+      }
+    }
+  }
+
+  showHostResults(results);
+  return results.every(r => r.status === 'deployed');
+}
+
+/**
+ * Host of a client to deploy first as a canary
+ */
+function findCanaryHost(hostConfigs, canary) {
+  const canaryConfig = hostConfigs.find(hostConfig => hostConfig.host === canary);
+  if (!canaryConfig) {
+    throw new Error(`Canary host '${canary}' is not one of the client's hosts: ${hostConfigs.map(h => h.host).join(', ')}`);
+  }
+  if (hostConfigs.length < 2) {
+    throw new Error('Canary deployments need a client with several hosts');
+  }
+  return canaryConfig;
+}
+
+/**
+ * Temporary server group a domain canary runs in
+ */
+function getCanaryGroup(serverGroup) {
+  return `${serverGroup}-canary`;
+}
+
+/**
+ * Canary through a domain controller, where deployments go to whole server groups:
+ * the canary host's servers of the group are moved to a temporary copy of it
+ * (<group>-canary) that runs the new content under the same runtime name, while the
+ * rest of the group keeps the old one. Once the canary is healthy and promotion is
+ * confirmed the group gets the new content; either way the servers move back
+ * Returns a host result: deployed, or rolled_back when the canary was not promoted
+ */
+async function deployDomainCanary(artifactPath, wildflyConfig, hostConfigs, canary, options = {}) {
+  const client = createManagementClient(wildflyConfig.management, hostConfigs[0].host, getRetryPolicy(wildflyConfig.retry));
+  const { serverGroup } = wildflyConfig;
+  const canaryGroup = getCanaryGroup(serverGroup);
+  const name = path.basename(artifactPath);
+  const canaryName = `${name}.canary`;
+
+  const hosts = await client.readChildrenNames([], 'host');
+  if (!hosts.includes(canary)) {
+    throw new Error(`Canary host '${canary}' is not a host of the domain: ${hosts.join(', ')}`);
+  }
+  if ((await client.listServerGroups()).includes(canaryGroup)) {
+    throw new Error(`Server group ${canaryGroup} is left over from an earlier canary; move its servers back to ${serverGroup} and remove it`);
+  }
+  const configs = await client.execute({ operation: 'read-resource', address: [{ host: canary }, { 'server-config': '*' }] });
+  const servers = (configs || [])
+    .filter(({ result }) => result.group === serverGroup)
+    .map(({ address }) => address[address.length - 1]['server-config']);
+  if (servers.length === 0) {
+    throw new Error(`Host '${canary}' has no servers in ${serverGroup}`);
+  }
+  const group = await client.readResource([{ 'server-group': serverGroup }]);
+
+  // Servers only change group while stopped; ones already there are left running
+  const moveServers = async target => {
+    for (const server of servers) {
+      const address = [{ host: canary }, { 'server-config': server }];
+      if (await client.readAttribute(address, 'group') === target) continue;
+      console.log(chalk.gray(`  ${canary}/${server} ${symbol('arrow')} ${target}`));
+      await client.execute({ operation: 'stop', address, blocking: true });
+      await client.execute({ operation: 'write-attribute', address, name: 'group', value: target });
+      await client.execute({ operation: 'start', address, blocking: true });
+    }
+  };
+
+  console.log('');
+  console.log(chalk.blue(`=== Canary ${canary} (${servers.join(', ')}) ===`));
+  console.log(`Controller: ${client.baseUrl}`);
+  emitProgress('deploy', 0, `Deploying ${name} to canary ${canary}`);
+
+  const hash = await client.upload(artifactPath);
+  await client.execute({
+    operation: 'add',
+    address: [{ 'server-group': canaryGroup }],
+    profile: group.profile,
+    'socket-binding-group': group['socket-binding-group']
+  });
+  let promoted = false;
+  try {
+    await client.execute({ operation: 'add', address: [{ deployment: canaryName }], content: [{ hash }], 'runtime-name': name });
+    await client.execute({ operation: 'add', address: [{ 'server-group': canaryGroup }, { deployment: canaryName }], enabled: true });
+    await moveServers(canaryGroup);
+
+    const statuses = await Promise.all(servers.map(server =>
+      client.readAttribute([{ host: canary }, { server }, { deployment: canaryName }], 'status').catch(() => null)));
+    const canaryConfig = hostConfigs.find(hostConfig => hostConfig.host === canary);
+    const healthy = statuses.every(status => status === 'OK') && (!canaryConfig || await verifyHost(canaryConfig, wildflyConfig));
+    if (!healthy) {
+      console.log(chalk.red(`Canary ${canary} is not healthy (deployment status: ${statuses.map(status => status ?? 'unknown').join(', ')})`));
+    }
+
+    promoted = healthy && await confirmPromotion(`Canary ${canary} is healthy. Promote to the rest of ${serverGroup}?`, options);
+    if (promoted) {
+      await withSuspendedServers(wildflyConfig, hostConfigs[0], () => client.deploy(artifactPath, wildflyConfig));
+      console.log(chalk.green(`Deployed ${name} to server group ${serverGroup}`));
+    }
+  } finally {
+    // Back in the group, the canary servers run whatever the group runs
+    await moveServers(serverGroup);
+    await client.undeploy(canaryName, { mode: 'domain', serverGroup: canaryGroup });
+    await client.execute({ operation: 'remove', address: [{ 'server-group': canaryGroup }] });
+  }
+  emitProgress('deploy', 100, promoted ? 'Deployment completed' : 'Canary rolled back');
+
+  return promoted
+    ? { host: canary, status: 'deployed' }
+    : { host: canary, status: 'rolled_back', error: 'Canary not promoted' };
+}
+
+/**
+ * Deploy to a remote domain through its controller's management API
+ */
+async function deployRemoteViaManagement(artifactPath, wildflyConfig, controllerConfig) {
+  const client = createManagementClient(wildflyConfig.management, controllerConfig.host, getRetryPolicy(wildflyConfig.retry));
+
+  console.log('');
+  console.log(chalk.blue('=== Management API Deployment ==='));
+  console.log(`Controller: ${client.baseUrl}`);
+  console.log(`Server Group: ${wildflyConfig.serverGroup}`);
+
+  emitProgress('deploy', 0, `Deploying ${path.basename(artifactPath)} via management API`);
+  const deployed = await client.deploy(artifactPath, wildflyConfig);
+  emitProgress('deploy', 100, 'Deployment completed');
+
+  console.log(chalk.green(`Deployed ${deployed.name} to server group ${wildflyConfig.serverGroup}`));
+  return true;
+}
+
+/**
+ * Run a deployment with the target servers suspended when suspend_timeout is set, so
+ * in-flight requests finish before the artifact is replaced; resumed afterwards even
+ * when the deployment fails. A server that can't be suspended is deployed to anyway
+ */
+async function withSuspendedServers(wildflyConfig, hostConfig, deploy) {
+  if (!wildflyConfig.suspendTimeout) {
+    return deploy();
+  }
+
+  const where = hostConfig ? hostConfig.host : 'the local WildFly';
+  let controller = null;
+  try {
+    controller = await createController(wildflyConfig, hostConfig);
+    console.log(chalk.yellow(`Suspending ${where}, waiting up to ${wildflyConfig.suspendTimeout}s for requests to finish`));
+    await suspendServers(controller, wildflyConfig, wildflyConfig.suspendTimeout);
+  } catch (error) {
+    console.log(chalk.yellow(`Could not suspend ${where} (${error.message}), deploying without draining`));
+    controller = null;
+  }
+
+  try {
+    return await deploy();
+  } finally {
+    if (controller) {
+      try {
+        await resumeServers(controller, wildflyConfig);
+        console.log(chalk.green(`Resumed ${where}`));
+      } catch (error) {
+        console.log(chalk.red(`Could not resume ${where}: ${error.message} (jmw cli :resume)`));
+      }
+    }
+  }
+}
+
+/**
+ * Display per-host deployment results
+ */
+function showHostResults(results) {
+  const colors = { deployed: chalk.green, rolled_back: chalk.yellow, queued: chalk.yellow, failed: chalk.red, skipped: chalk.gray };
+
+  console.log('');
+  console.log(chalk.blue('=== Deployment Results ==='));
+  for (const result of results) {
+    const status = colors[result.status](result.status.toUpperCase().padEnd(11));
+    console.log(`  ${status} ${result.host}${result.error ? ` - ${result.error}` : ''}`);
+  }
+}
+
+/**
+ * Plan remote deployment steps, mirroring showRemoteDeploymentGuide
+ */
+function planRemoteOperations(artifactPath, wildflyConfig, clientConfig, moduleInfo, projectConfig = {}) {
+  const artifactName = path.basename(artifactPath);
+  const sudo = getSudoPrefix(clientConfig);
+
+  if (moduleInfo.isGlobalModule) {
+    const modulesPath = clientConfig.wildfly_path + '/' + moduleInfo.deploymentPath;
+    const moduleDefinition = getModuleDefinition(projectConfig, moduleInfo.deploymentPath);
+    let metadata = moduleDefinition ? [planModuleXmlOperation(modulesPath, moduleDefinition, sudo)] : [];
+    if (wildflyConfig.moduleInstall === 'cli') {
+      // Without a module_xml definition an existing module.xml is kept, as module add would drop its dependencies
+      const definition = moduleDefinition ?? { ...getModuleIdentity(moduleInfo.deploymentPath), dependencies: [] };
+      metadata = [planModuleCliOperation(`${clientConfig.wildfly_path}/bin/jboss-cli.sh`, modulesPath, definition, sudo, !moduleDefinition)];
+    }
+    return [
+      planPreflightOperation(modulesPath, fs.statSync(artifactPath).size),
+      { type: 'upload', source: artifactPath, dest: `${modulesPath}/${artifactName}` },
+      ...metadata,
+      { type: 'exec', command: getRestartCommand(clientConfig), description: 'Restart WildFly (required for global modules)' }
+    ];
+  }
+
+  const deploymentsPath = clientConfig.wildfly_path + '/' + wildflyConfig.mode + '/deployments';
+  const backupDir = getBackupDir(clientConfig.wildfly_path, wildflyConfig.mode);
+  return [
+    planPreflightOperation(deploymentsPath, fs.statSync(artifactPath).size),
+    planBackupOperation(`${deploymentsPath}/${artifactName}`, backupDir, wildflyConfig.backupRetention, sudo),
+    { type: 'upload', source: artifactPath, dest: `${deploymentsPath}/${artifactName}` },
+    ...planActivationOperations(deploymentsPath, artifactName, wildflyConfig, clientConfig)
+  ];
+}
+
+/**
+ * Plan steps restoring the artifact deployed before the current one
+ */
+function planRollbackOperations(artifactPath, wildflyConfig, clientConfig) {
+  const artifactName = path.basename(artifactPath);
+  const deploymentsPath = clientConfig.wildfly_path + '/' + wildflyConfig.mode + '/deployments';
+  const backupDir = getBackupDir(clientConfig.wildfly_path, wildflyConfig.mode);
+  return [
+    planRestoreOperation(`${deploymentsPath}/${artifactName}`, backupDir, getSudoPrefix(clientConfig)),
+    ...planActivationOperations(deploymentsPath, artifactName, wildflyConfig, clientConfig)
+  ];
+}
+
+/**
+ * Plan steps triggering the deployment scanner and waiting for its result
+ * Standalone only: a domain has no scanner
+ */
+function planActivationOperations(deploymentsPath, artifactName, wildflyConfig, clientConfig) {
+  const sudo = getSudoPrefix(clientConfig);
+  return [
+    { type: 'exec', command: `${sudo}touch ${deploymentsPath}/${artifactName}.dodeploy`, description: 'Trigger hot deployment' },
+    {
+      type: 'await_deployment',
+      deploymentsPath,
+      artifactName,
+      timeout: wildflyConfig.deploymentTimeout,
+      logPath: getServerLogPath(clientConfig.wildfly_path, wildflyConfig.mode, clientConfig.server_log)
+    }
+  ];
+}
+
+/**
+ * Run the host's health check and warm-up, whichever are configured
+ */
+async function verifyHost(hostConfig, wildflyConfig) {
+  if (!hostConfig.health_check?.url && !(hostConfig.warmup?.urls?.length > 0)) {
+    return true;
+  }
+  console.log('');
+  return verifyAndWarmup(await resolveVerifyConfig(hostConfig, wildflyConfig, hostConfig));
+}
+
+/**
+ * Rollback restores the backup taken by standalone scanner deployments;
+ * global modules need a restart instead
+ */
+function canRollBack(wildflyConfig, moduleInfo) {
+  return !moduleInfo.isGlobalModule && wildflyConfig.mode === 'standalone';
+}
+
 /**
  * Deploy global module to WildFly modules directory
  */
-function deployGlobalModule(artifactPath, wildflyConfig, moduleInfo, result) {
+async function deployGlobalModule(artifactPath, wildflyConfig, moduleInfo, result, moduleDefinition) {
   // deploymentPath already contains the full path from wildfly_root (e.g., "modules/ejbmto/main")
   const modulePath = path.join(wildflyConfig.root, moduleInfo.deploymentPath);
 
@@ -174,6 +789,21 @@ function deployGlobalModule(artifactPath, wildflyConfig, moduleInfo, result) {
   trackFileCopy(result, artifactPath, destPath);
 
   console.log(chalk.green('Module deployed to: ' + destPath));
+
+  const moduleXmlPath = path.join(modulePath, 'module.xml');
+  if (wildflyConfig.moduleInstall === 'cli' && !moduleDefinition && fs.existsSync(moduleXmlPath)) {
+    // module add would re-create it without the dependencies it declares
+    console.log(chalk.gray(`Kept ${moduleXmlPath}; add module_xml.${getModuleIdentity(moduleInfo.deploymentPath).name} to have jboss-cli re-create it`));
+  } else if (wildflyConfig.moduleInstall === 'cli') {
+    const definition = moduleDefinition ?? { ...getModuleIdentity(moduleInfo.deploymentPath), dependencies: [] };
+    await installLocalModuleViaCli(getCliPath(wildflyConfig.root), modulePath, definition);
+    trackModuleXmlWritten(result, moduleXmlPath);
+    console.log(chalk.green(`Installed module ${definition.name} via jboss-cli`));
+  } else if (moduleDefinition) {
+    writeLocalModuleXml(modulePath, moduleDefinition);
+    trackModuleXmlWritten(result, moduleXmlPath);
+    console.log(chalk.green('Generated: ' + moduleXmlPath));
+  }
 }
 
 /**
@@ -183,16 +813,17 @@ function deployNormal(artifactPath, wildflyConfig, moduleInfo, result) {
   console.log(chalk.blue('=== Normal Deployment ==='));
 
   if (wildflyConfig.mode === 'standalone') {
-    deployStandalone(artifactPath, wildflyConfig, moduleInfo, result);
+    return deployStandalone(artifactPath, wildflyConfig, moduleInfo, result);
   } else {
-    deployDomain(artifactPath, wildflyConfig, moduleInfo, result);
+    return deployDomain(artifactPath, wildflyConfig, moduleInfo, result);
   }
 }
 
 /**
  * Deploy to standalone mode
+ * Triggers the deployment scanner and waits for its result marker
  */
-function deployStandalone(artifactPath, wildflyConfig, moduleInfo, result) {
+async function deployStandalone(artifactPath, wildflyConfig, moduleInfo, result) {
   const deploymentsDir = path.join(wildflyConfig.root, 'standalone', 'deployments');
   const destPath = path.join(deploymentsDir, path.basename(artifactPath));
   const markerPath = path.join(deploymentsDir, path.basename(artifactPath) + '.dodeploy');
@@ -205,6 +836,9 @@ function deployStandalone(artifactPath, wildflyConfig, moduleInfo, result) {
     trackDirCreated(result, deploymentsDir);
   }
 
+  // Keep the current artifact for rollback
+  backupLocalArtifact(destPath, getBackupDir(wildflyConfig.root, 'standalone'), wildflyConfig.backupRetention);
+
   // Copy artifact
   fs.copyFileSync(artifactPath, destPath);
   trackFileCopy(result, artifactPath, destPath);
@@ -215,47 +849,233 @@ function deployStandalone(artifactPath, wildflyConfig, moduleInfo, result) {
 
   console.log(chalk.green('Deployed to: ' + destPath));
   console.log(chalk.green('Marker created: ' + markerPath));
+
+  await awaitStandaloneDeployment(deploymentsDir, path.basename(artifactPath), wildflyConfig);
+}
+
+/**
+ * Wait for the scanner and server.log to report the outcome, throwing on failure
+ */
+async function awaitStandaloneDeployment(deploymentsDir, artifactName, wildflyConfig) {
+  const outcome = await waitForLocalDeployment(deploymentsDir, artifactName, wildflyConfig.deploymentTimeout);
+  showDeploymentOutcome(artifactName, outcome, wildflyConfig.deploymentTimeout);
+  if (outcome.status === 'timeout') {
+    return;
+  }
+
+  const logPath = getServerLogPath(wildflyConfig.root, 'standalone', wildflyConfig.serverLog);
+  const logResult = await waitForLogResult(() => readLocalLog(logPath), artifactName);
+  showLogResult(artifactName, logResult, logPath);
+
+  if (outcome.status === 'failed' || logResult.status === 'failed') {
+    throw new Error(`${artifactName} failed to deploy`);
+  }
+}
+
+/**
+ * Restore the previous local artifact and verify it again
+ * Returns whether the rollback succeeded
+ */
+async function rollbackLocal(artifactPath, wildflyConfig, projectConfig) {
+  const artifactName = path.basename(artifactPath);
+  const deploymentsDir = path.join(wildflyConfig.root, 'standalone', 'deployments');
+
+  console.log('');
+  console.log(chalk.blue('=== Rollback ==='));
+  try {
+    const backup = restoreLocalArtifact(path.join(deploymentsDir, artifactName), getBackupDir(wildflyConfig.root, 'standalone'));
+    console.log(`Restoring backup from ${backup.timestamp.toLocaleString()}`);
+    fs.writeFileSync(path.join(deploymentsDir, artifactName + '.dodeploy'), '');
+    await awaitStandaloneDeployment(deploymentsDir, artifactName, wildflyConfig);
+    if (projectConfig.health_check?.url && !(await waitForHealthy((await resolveVerifyConfig(projectConfig, wildflyConfig)).health_check))) {
+      throw new Error('Health check failed after rollback');
+    }
+    console.log(chalk.yellow('Rolled back to the previous artifact'));
+    return true;
+  } catch (error) {
+    console.error(chalk.red('Rollback failed:'), error.message);
+    return false;
+  }
 }
 
 /**
  * Deploy to domain mode
+ * Uses the HTTP management API when configured, otherwise the local jboss-cli
  */
-function deployDomain(artifactPath, wildflyConfig, moduleInfo, result) {
+async function deployDomain(artifactPath, wildflyConfig, moduleInfo, result) {
   const artifactName = path.basename(artifactPath);
-  const deploymentsDir = path.join(wildflyConfig.root, 'domain', 'deployments');
 
   console.log(`Server Group: ${wildflyConfig.serverGroup}`);
   console.log(`Artifact: ${artifactName}`);
-  console.log(chalk.yellow('Use jboss-cli.sh to deploy:'));
-  console.log(`  deploy ${artifactPath} --server-groups=${wildflyConfig.serverGroup}`);
 
-  // Create directory if needed
-  if (!fs.existsSync(deploymentsDir)) {
-    fs.mkdirSync(deploymentsDir, { recursive: true });
-    trackDirCreated(result, deploymentsDir);
+  if (wildflyConfig.management) {
+    const client = createManagementClient(wildflyConfig.management, 'localhost', getRetryPolicy(wildflyConfig.retry));
+    console.log(`Management API: ${client.baseUrl}`);
+    const deployed = await client.deploy(artifactPath, wildflyConfig);
+    trackManagementDeploy(result, deployed.name, `server-group ${wildflyConfig.serverGroup}`, deployed.hash);
+    console.log(chalk.green(`Deployed ${deployed.name} to server group ${wildflyConfig.serverGroup}`));
+    return;
   }
 
-  // Copy to deployments dir (for reference)
-  const destPath = path.join(deploymentsDir, artifactName);
-  fs.copyFileSync(artifactPath, destPath);
-  trackFileCopy(result, artifactPath, destPath);
+  // Drive the local domain controller through jboss-cli (local authentication)
+  const { root, serverGroup } = wildflyConfig;
+  console.log(`jboss-cli: ${getCliPath(root)}`);
 
-  console.log(chalk.green('Copied to: ' + destPath));
+  const deployments = (await runLocalCli(root, null, 'ls deployment')).split(/\s+/);
+  if (deployments.includes(artifactName)) {
+    // Replaces content wherever the deployment is assigned
+    await runLocalCli(root, null, `deploy ${artifactPath} --force`);
+    const assigned = (await runLocalCli(root, null, `ls /server-group=${serverGroup}/deployment`)).split(/\s+/);
+    if (!assigned.includes(artifactName)) {
+      await runLocalCli(root, null, `deploy --name=${artifactName} --server-groups=${serverGroup}`);
+    }
+  } else {
+    await runLocalCli(root, null, `deploy ${artifactPath} --server-groups=${serverGroup}`);
+  }
+
+  trackManagementDeploy(result, artifactName, `server-group ${serverGroup}`, null);
+  console.log(chalk.green(`Deployed ${artifactName} to server group ${serverGroup}`));
 }
 
 /**
- * Get WildFly configuration (local deployment)
+ * Get WildFly configuration
+ * Client/environment settings override the project's for remote deployments
  */
 function getWildflyConfig(projectConfig, clientConfig) {
   const config = {
     root: projectConfig.wildfly_root,
-    mode: projectConfig.wildfly_mode || 'standalone',
-    serverGroup: projectConfig.server_group
+    mode: clientConfig?.wildfly_mode || projectConfig.wildfly_mode || 'standalone',
+    serverGroup: clientConfig?.server_group ?? projectConfig.server_group,
+    serverConfig: clientConfig?.server_config ?? projectConfig.server_config,
+    management: clientConfig ? clientConfig.management : projectConfig.management,
+    serverLog: clientConfig ? clientConfig.server_log : projectConfig.server_log,
+    deploymentTimeout: clientConfig?.deployment_timeout || projectConfig.deployment_timeout || DEFAULT_DEPLOYMENT_TIMEOUT,
+    moduleInstall: getModuleInstallMode(projectConfig, clientConfig),
+    backupRetention: clientConfig?.backup_retention ?? projectConfig.backup_retention ?? DEFAULT_BACKUP_RETENTION,
+    suspendTimeout: clientConfig?.suspend_timeout ?? projectConfig.suspend_timeout ?? 0,
+    retry: clientConfig?.retry ?? projectConfig.retry,
+    shortcuts: projectConfig.shortcuts || {}
   };
 
   return config;
 }
 
+/**
+ * Server group to deploy to in domain mode: --server-group, else server_group,
+ * checked against the groups the domain controller knows; with neither, the only
+ * group or one picked from the list. A configured group is used unchecked when
+ * the controller can't be asked
+ */
+async function selectServerGroup(wildflyConfig, hostConfig, requested) {
+  const wanted = requested || wildflyConfig.serverGroup;
+
+  let groups;
+  try {
+    const controller = await createController(wildflyConfig, hostConfig);
+    groups = await controller.execute([], 'read-children-names', { 'child-type': 'server-group' });
+  } catch (error) {
+    if (!wanted) {
+      throw new Error(`No server_group configured, and the server groups could not be read: ${error.message}`);
+    }
+    console.log(chalk.yellow(`Could not read the server groups (${error.message}), using ${wanted}`));
+    return wanted;
+  }
+
+  if (wanted) {
+    if (groups.includes(wanted)) {
+      return wanted;
+    }
+    const suggestion = suggestKey(wanted, groups);
+    throw new Error(`Server group '${wanted}'${suggestion ? ` (did you mean ${suggestion}?)` : ''} does not exist. Available server groups: ${groups.join(', ') || 'none'}`);
+  }
+
+  if (groups.length === 0) {
+    throw new Error('The domain has no server groups');
+  }
+  if (groups.length === 1) {
+    return groups[0];
+  }
+  if (!isInteractive()) {
+    throw new Error(`No server_group configured; pass --server-group (${groups.join(', ')})`);
+  }
+
+  const picked = await select('Server group', groups);
+  if (!picked) {
+    throw new Error('No server group picked');
+  }
+  console.log('');
+  return picked;
+}
+
+/**
+ * Print the state of the servers a deployment lands on and return 'ok', 'warn'
+ * (mid-boot, or a reload or restart pending) or 'down' (not answering, or stopping),
+ * as a marker or a hot deployment into a stopped server silently does nothing
+ */
+async function checkServerState(wildflyConfig, hostConfig = null) {
+  const label = hostConfig ? `Server State (${hostConfig.host}):` : 'Server State:';
+
+  let states;
+  try {
+    const controller = await createController(wildflyConfig, hostConfig);
+    states = await readServerStates(controller, wildflyConfig);
+  } catch (error) {
+    // jboss-cli and fetch failing to reach the server; an unreachable SSH host is only unknown
+    if (/connect to the controller|ECONNREFUSED|Unable to connect/i.test(error.message)) {
+      console.log(chalk.yellow(label), chalk.red('not running'));
+      return 'down';
+    }
+    console.log(chalk.yellow(label), chalk.yellow(`unknown (${error.message})`));
+    return 'warn';
+  }
+
+  if (states.length === 0) {
+    console.log(chalk.yellow(label), chalk.red(`no running servers${wildflyConfig.serverGroup ? ` in ${wildflyConfig.serverGroup}` : ''}`));
+    return 'down';
+  }
+
+  const describe = ({ label: server, state }) => `${server ? `${server} ` : ''}${state}`;
+  const notRunning = states.filter(({ state }) => state !== 'running');
+  if (notRunning.length === 0) {
+    console.log(chalk.yellow(label), chalk.green(states.map(describe).join(', ')));
+    return 'ok';
+  }
+  console.log(chalk.yellow(label), chalk.yellow(states.map(describe).join(', ')));
+  if (notRunning.some(({ state }) => state === 'stopping')) {
+    return 'down';
+  }
+  if (notRunning.some(({ state }) => state === 'restart-required' || state === 'reload-required')) {
+    console.log(chalk.yellow('  Configuration changes are pending, consider jmw restart first'));
+  }
+  return 'warn';
+}
+
+/**
+ * Whether a deployment goes through the standalone deployment scanner (and its
+ * markers); --force-clean means nothing for any other kind
+ */
+function usesScanner(wildflyConfig, moduleInfo, options) {
+  const scanner = !moduleInfo.isGlobalModule && wildflyConfig.mode === 'standalone';
+  if (options.forceClean && !scanner) {
+    console.log(chalk.gray('--force-clean only applies to standalone deployments through the scanner, ignored'));
+  }
+  return scanner;
+}
+
+/**
+ * Refuse to deploy to a stopped server unless --ignore-state was given
+ */
+function assertServerUp(state, where, options) {
+  if (state !== 'down') {
+    return;
+  }
+  if (options.ignoreState) {
+    console.log(chalk.yellow(`WildFly is not running on ${where}, deploying anyway`));
+    return;
+  }
+  throw new Error(`WildFly is not running on ${where}; start it first (jmw server start) or use --ignore-state`);
+}
+
 /**
  * Show restart guidance
  */
@@ -275,22 +1095,45 @@ function showRestartGuidance(wildflyConfig) {
   console.log('');
   console.log(chalk.yellow('Restart command:'));
 
-  if (wildflyConfig.mode === 'standalone') {
-    console.log(`  ${wildflyConfig.root}/bin/shutdown.sh --restart`);
+  if (wildflyConfig.shortcuts.restart) {
+    console.log(`  ${wildflyConfig.shortcuts.restart}`);
+  } else if (wildflyConfig.mode === 'standalone') {
+    console.log(`  ${getScriptPath(wildflyConfig.root, 'shutdown')} --restart`);
   } else {
-    console.log(`  ${wildflyConfig.root}/bin/domain.sh --restart`);
+    console.log(`  ${getScriptPath(wildflyConfig.root, 'domain')} --restart`);
+  }
+  console.log(chalk.gray('  (jmw restart reloads instead when the server needs no full restart)'));
+
+  const others = Object.entries(wildflyConfig.shortcuts).filter(([name]) => name !== 'restart');
+  if (others.length > 0) {
+    console.log(chalk.yellow('Shortcuts:'));
+    others.forEach(([name, command]) => console.log(`  ${name}: ${command}`));
   }
 }
 
 /**
- * Show remote deployment guide
+ * Show remote deployment guide, once per client host
  */
 function showRemoteDeploymentGuide(artifactPath, wildflyConfig, clientConfig, moduleInfo) {
+  const hostConfigs = getClientHosts(clientConfig);
+
+  hostConfigs.forEach((hostConfig, i) => {
+    if (hostConfigs.length > 1) {
+      console.log(chalk.blue(`${i > 0 ? '\n' : ''}--- ${hostConfig.host} ---`));
+    }
+    showHostDeploymentGuide(artifactPath, wildflyConfig, hostConfig, moduleInfo);
+  });
+}
+
+/**
+ * Show remote deployment commands for a single host
+ */
+function showHostDeploymentGuide(artifactPath, wildflyConfig, clientConfig, moduleInfo) {
   const artifactName = path.basename(artifactPath);
   const logPath = clientConfig.wildfly_path + '/' + wildflyConfig.mode + '/log/server.log';
 
-  // Use sudo only if not root
-  const sudo = clientConfig.user === 'root' ? '' : 'sudo ';
+  // sudo (as sudo_user) unless connecting as root or use_sudo is off
+  const sudo = getSudoPrefix(clientConfig, { interactive: true });
 
   if (moduleInfo && moduleInfo.isGlobalModule) {
     // Global module deployment - copy to modules directory and restart
@@ -300,7 +1143,7 @@ function showRemoteDeploymentGuide(artifactPath, wildflyConfig, clientConfig, mo
     console.log(`   scp ${artifactPath} ${clientConfig.user}@${clientConfig.host}:${modulesPath}/`);
     console.log('');
     console.log(chalk.yellow('2. Restart WildFly (required for global modules):'));
-    console.log(`   ssh ${clientConfig.user}@${clientConfig.host} "${clientConfig.restart_cmd}"`);
+    console.log(`   ssh ${clientConfig.user}@${clientConfig.host} "${getRestartCommand(clientConfig, { interactive: true })}"`);
     console.log('');
     console.log(chalk.yellow('3. Watch server logs:'));
     console.log(`   ssh ${clientConfig.user}@${clientConfig.host} "${sudo}tail -n 20 -f ${logPath}"`);
@@ -319,31 +1162,17 @@ function showRemoteDeploymentGuide(artifactPath, wildflyConfig, clientConfig, mo
   }
 }
 
-/**
- * Simple confirmation prompt
- */
-function confirm(message) {
-  return new Promise(resolve => {
-    const rl = readline.createInterface({
-      input: process.stdin,
-      output: process.stdout
-    });
-
-    rl.question(message + ' (y/N) ', answer => {
-      rl.close();
-      resolve(answer.toLowerCase() === 'y' || answer.toLowerCase() === 'yes');
-    });
-  });
-}
-
 export {
   deployArtifact,
+  deployRemote,
+  planRemoteOperations,
+  planRollbackOperations,
   getWildflyConfig,
   deployGlobalModule,
   deployNormal,
   deployStandalone,
   deployDomain,
+  deployRemoteViaManagement,
   showRestartGuidance,
-  showRemoteDeploymentGuide,
-  confirm
+  showRemoteDeploymentGuide
 };
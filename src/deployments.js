@@ -0,0 +1,213 @@
+import path from 'path';
+import chalk from 'chalk';
+
+import { requireClientConfig, getClientHosts } from './config.js';
+import { getWildflyConfig } from './deployer.js';
+import { createController, forEachController } from './controller.js';
+import { symbol } from './output.js';
+import { createAuditTrail } from './audit.js';
+import { confirmAction } from './confirm.js';
+
+/**
+ * Deployments of a server, or of every server group of a domain
+ * Returns [{group, name, runtimeName, enabled, enabledTime}]
+ */
+async function readDeployments(controller, mode) {
+  const groups = mode === 'domain'
+    ? await controller.execute([], 'read-children-names', { 'child-type': 'server-group' })
+    : [null];
+
+  const deployments = [];
+  for (const group of groups) {
+    const address = [...(group ? [{ 'server-group': group }] : []), { deployment: '*' }];
+    const entries = await controller.execute(address, 'read-resource', { 'include-runtime': true });
+    for (const { result } of entries || []) {
+      deployments.push({
+        group,
+        name: result.name,
+        runtimeName: result['runtime-name'],
+        enabled: result.enabled,
+        enabledTime: result['enabled-time'] ?? null
+      });
+    }
+  }
+  return deployments.sort((a, b) => (a.group || '').localeCompare(b.group || '') || a.name.localeCompare(b.name));
+}
+
+/**
+ * Whether a deployment is the current module's artifact (with or without its version)
+ */
+function isModuleDeployment(deployment, moduleInfo) {
+  const base = name => path.basename(name || '', path.extname(name || ''));
+  return [deployment.name, deployment.runtimeName].map(base)
+    .some(name => name === moduleInfo.finalName || name === moduleInfo.artifactId || name.startsWith(`${moduleInfo.artifactId}-`));
+}
+
+/**
+ * Print deployments, grouped by server group in domain mode
+ */
+function showDeployments(deployments, moduleInfo) {
+  if (deployments.length === 0) {
+    console.log(chalk.gray('  No deployments'));
+    return;
+  }
+
+  const width = Math.max(...deployments.map(deployment => deployment.name.length));
+  let group;
+  for (const deployment of deployments) {
+    if (deployment.group !== group) {
+      group = deployment.group;
+      if (group) console.log(chalk.white.bold(`  ${group}`));
+    }
+
+    const current = isModuleDeployment(deployment, moduleInfo);
+    const marker = current ? chalk.green(`${symbol('arrow')} `) : '  ';
+    const name = current ? chalk.green.bold(deployment.name.padEnd(width)) : deployment.name.padEnd(width);
+    const state = deployment.enabled ? chalk.green('enabled ') : chalk.yellow('disabled');
+    const runtimeName = deployment.runtimeName && deployment.runtimeName !== deployment.name ? chalk.gray(` as ${deployment.runtimeName}`) : '';
+    const since = deployment.enabledTime ? chalk.gray(`  ${new Date(Number(deployment.enabledTime)).toLocaleString()}`) : '';
+    console.log(`${group ? '  ' : ''}${marker}${name}  ${state}${since}${runtimeName}`);
+  }
+}
+
+/**
+ * List the deployments of the local WildFly, or of a client's hosts
+ */
+async function listDeployments(detection, options = {}) {
+  const { projectConfig, module: moduleInfo } = detection;
+
+  if (!options.client) {
+    const wildflyConfig = getWildflyConfig(projectConfig, null);
+    const controller = await createController(wildflyConfig);
+    console.log(chalk.blue(`=== Deployments (local, ${controller.target}) ===`));
+    showDeployments(await readDeployments(controller, wildflyConfig.mode), moduleInfo);
+    return true;
+  }
+
+  const clientConfig = requireClientConfig(projectConfig, options.client, options.env);
+  const wildflyConfig = getWildflyConfig(projectConfig, clientConfig);
+  return forEachController(wildflyConfig, getClientHosts(clientConfig), 'Deployments', async controller =>
+    showDeployments(await readDeployments(controller, wildflyConfig.mode), moduleInfo));
+}
+
+/**
+ * Deployment an enable or disable applies to: the named one, else the current
+ * module's, preferring those not already in the wanted state
+ */
+function findTargetDeployment(deployments, moduleInfo, name, enabled) {
+  if (name) {
+    const named = deployments.filter(deployment => deployment.name === name || deployment.runtimeName === name);
+    if (named.length === 0) {
+      throw new Error(`No deployment '${name}'. Deployments: ${[...new Set(deployments.map(d => d.name))].join(', ') || 'none'}`);
+    }
+    return named[0].name;
+  }
+
+  const own = [...new Set(deployments.filter(d => isModuleDeployment(d, moduleInfo)).map(d => d.name))];
+  const pending = [...new Set(deployments.filter(d => own.includes(d.name) && d.enabled !== enabled).map(d => d.name))];
+  const candidates = pending.length > 0 ? pending : own;
+  if (candidates.length === 0) {
+    throw new Error(`${moduleInfo.artifactId} is not deployed; name the deployment to ${enabled ? 'enable' : 'disable'}`);
+  }
+  if (candidates.length > 1) {
+    throw new Error(`Several deployments of ${moduleInfo.artifactId} (${candidates.join(', ')}); name the one to ${enabled ? 'enable' : 'disable'}`);
+  }
+  return candidates[0];
+}
+
+/**
+ * Enable (deploy) or disable (undeploy, keeping the content) a deployment through
+ * one controller, in every server group that has it or just the configured one.
+ * Enabling first disables another enabled deployment with the same runtime-name
+ * there (the previous version when A/B switching), which is enabled again when
+ * enabling fails. Every deploy and undeploy goes to the audit log for target
+ */
+async function setEnabledOn(controller, wildflyConfig, detection, target, name, enabled) {
+  const { module: moduleInfo } = detection;
+  const deployments = (await readDeployments(controller, wildflyConfig.mode))
+    .filter(deployment => !wildflyConfig.serverGroup || !deployment.group || deployment.group === wildflyConfig.serverGroup);
+  const selected = findTargetDeployment(deployments, moduleInfo, name, enabled);
+
+  for (const deployment of deployments.filter(d => d.name === selected)) {
+    const where = deployment.group ? ` in ${deployment.group}` : '';
+    if (deployment.enabled === enabled) {
+      console.log(`  ${selected} is already ${enabled ? 'enabled' : 'disabled'}${where}`);
+      continue;
+    }
+    const addressOf = name => [...(deployment.group ? [{ 'server-group': deployment.group }] : []), { deployment: name }];
+    const hostTarget = { ...target, host: deployment.group ? `${target.host} (${deployment.group})` : target.host };
+
+    // Runs one operation and records its outcome
+    const run = async (deploymentName, operation) => {
+      const audit = await createAuditTrail(detection.audit, detection, deploymentName, { checksum: false });
+      try {
+        await controller.execute(addressOf(deploymentName), operation);
+      } catch (error) {
+        await audit(operation, hostTarget, 'failed', error.message);
+        throw error;
+      }
+      await audit(operation, hostTarget, operation === 'deploy' ? 'deployed' : 'undeployed');
+    };
+
+    const runtimeName = deployment.runtimeName || selected;
+    const conflicting = enabled
+      ? deployments.filter(d => d.group === deployment.group && d.name !== selected && d.enabled && (d.runtimeName || d.name) === runtimeName)
+      : [];
+    for (const other of conflicting) {
+      await run(other.name, 'undeploy');
+      console.log(chalk.yellow(`  ${other.name} disabled${where}, it has the same runtime-name ${runtimeName}`));
+    }
+    try {
+      await run(selected, enabled ? 'deploy' : 'undeploy');
+    } catch (error) {
+      for (const other of conflicting) {
+        await run(other.name, 'deploy');
+        console.log(chalk.yellow(`  ${other.name} enabled again${where}`));
+      }
+      throw error;
+    }
+    console.log(chalk.green(`  ${selected} ${enabled ? 'enabled' : 'disabled'}${where}`));
+  }
+  return true;
+}
+
+/**
+ * Enable or disable a deployment without removing its content, on the local WildFly
+ * or a client's hosts. Disabling asks first as the confirmations undeploy rule requires
+ */
+async function setDeploymentEnabled(detection, name, enabled, options = {}) {
+  const { projectConfig, module: moduleInfo } = detection;
+  const title = enabled ? 'Enable Deployment' : 'Disable Deployment';
+  const clientConfig = options.client ? requireClientConfig(projectConfig, options.client, options.env) : null;
+
+  if (!enabled) {
+    const target = name || moduleInfo.artifactId;
+    const confirmed = await confirmAction(detection.confirmations, 'undeploy', {
+      message: `Disable ${target} on ${options.client || 'the local WildFly'}?`,
+      environment: clientConfig ? [clientConfig.environment, options.client] : 'local',
+      expected: target
+    });
+    if (!confirmed) {
+      console.log(chalk.red('Disable cancelled'));
+      return false;
+    }
+  }
+
+  if (!clientConfig) {
+    const wildflyConfig = { ...getWildflyConfig(projectConfig, null), ...(options.serverGroup ? { serverGroup: options.serverGroup } : {}) };
+    const controller = await createController(wildflyConfig);
+    console.log(chalk.blue(`=== ${title} (local, ${controller.target}) ===`));
+    return setEnabledOn(controller, wildflyConfig, detection, { host: 'localhost' }, name, enabled);
+  }
+
+  const wildflyConfig = { ...getWildflyConfig(projectConfig, clientConfig), ...(options.serverGroup ? { serverGroup: options.serverGroup } : {}) };
+  return forEachController(wildflyConfig, getClientHosts(clientConfig), title, (controller, hostConfig) =>
+    setEnabledOn(controller, wildflyConfig, detection, { client: options.client, env: clientConfig.environment, host: hostConfig.host }, name, enabled));
+}
+
+export {
+  readDeployments,
+  isModuleDeployment,
+  listDeployments,
+  setDeploymentEnabled
+};
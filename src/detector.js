@@ -1,10 +1,20 @@
 import fs from 'fs';
+import os from 'os';
 import path from 'path';
+import chalk from 'chalk';
 import { XMLParser } from 'fast-xml-parser';
 
+import { applyInstance, checkStrictPaths } from './config.js';
+import { cached } from './cache.js';
+import { findGradleBuild, findGradleRoot, readGradleInfo } from './gradle.js';
+import { getMavenCommand } from './maven.js';
+import { getSpawnArgs } from './process.js';
+
+// Values stay strings: versions like 1.10 must not turn into numbers
 const parser = new XMLParser({
   ignoreAttributes: false,
-  attributeNamePrefix: ''
+  attributeNamePrefix: '',
+  parseTagValue: false
 });
 
 /**
@@ -16,42 +26,183 @@ function detectProject(config, cwd) {
     cwd = process.cwd();
   }
 
-  const currentPath = path.resolve(cwd);
+  // Workspaces may be reached through symlinks (e.g. from a NAS), so both sides
+  // are compared, and used from here on, in their canonical form
+  const currentPath = canonicalPath(cwd);
 
-  // Find which project this path belongs to
-  let matchedProject = null;
-  for (const [projectName, projectConfig] of Object.entries(config.projects)) {
-    if (currentPath.startsWith(projectConfig.base_path)) {
-      matchedProject = { name: projectName, config: projectConfig };
-      break;
-    }
+  // Find which project this path belongs to; when base paths are nested, the deepest wins
+  let matchedProject = matchByBasePath(config, currentPath);
+
+  if (!matchedProject) {
+    matchedProject = matchByGitRemote(config, currentPath);
   }
 
   if (!matchedProject) {
     throw new Error('Current directory is not within any configured project');
   }
+  checkStrictPaths(config, matchedProject.name);
+  matchedProject.config = applyUnit(matchedProject.config, currentPath);
 
-  // Walk up to find pom.xml
-  const pomPath = findPomXml(currentPath);
-  if (!pomPath) {
-    throw new Error('No pom.xml found in current directory or parent directories');
+  // Walk up to find pom.xml (or a Gradle build script)
+  const buildFile = findBuildFile(currentPath);
+  if (!buildFile) {
+    throw new Error('No pom.xml or build.gradle found in current directory or parent directories');
   }
-
-  // Parse POM
-  const pom = parsePom(pomPath);
+  const pomPath = path.basename(buildFile) === 'pom.xml' ? buildFile : null;
 
   // Detect module
-  const moduleInfo = detectModule(pomPath, pom, matchedProject.config);
+  const moduleInfo = pomPath
+    ? detectModule(pomPath, matchedProject.config)
+    : detectGradleModule(buildFile, matchedProject.config);
+
+  // Per-module skip_tests and health_check take precedence over the project's
+  const { skip_tests, health_check } = moduleInfo.settings;
+  const projectConfig = {
+    ...applyInstance(matchedProject.config),
+    ...(skip_tests !== undefined ? { skip_tests } : {}),
+    ...(health_check !== undefined ? { health_check } : {})
+  };
 
   return {
     project: matchedProject.name,
-    projectConfig: matchedProject.config,
+    projectConfig,
     restartRules: config.restart_rules,
+    confirmations: { ...config.confirmations, ...matchedProject.config.confirmations },
+    audit: { ...config.audit, ...matchedProject.config.audit },
     pomPath,
+    buildFile,
     module: moduleInfo
   };
 }
 
+/**
+ * Fail clearly in commands that only work with Maven modules
+ */
+function requireMaven(detection, what) {
+  if (detection.module.buildSystem !== 'maven') {
+    throw new Error(`${what} is only supported for Maven modules`);
+  }
+}
+
+/**
+ * Absolute path with symlinks resolved, or just resolved when it doesn't exist
+ */
+function canonicalPath(target) {
+  try {
+    return fs.realpathSync.native(path.resolve(target));
+  } catch (error) {
+    return path.resolve(target);
+  }
+}
+
+/**
+ * Whether a path is a directory or lies below it; Windows paths compare
+ * case-insensitively (C:\Work and c:\work are the same directory)
+ */
+function isWithin(target, dir) {
+  const [a, b] = process.platform === 'win32' ? [target.toLowerCase(), dir.toLowerCase()] : [target, dir];
+  return a === b || a.startsWith(b.endsWith(path.sep) ? b : b + path.sep);
+}
+
+/**
+ * Project whose base_path contains a path, the longest base_path winning
+ * Projects sharing that base_path are ambiguous: the first by name is used, with a warning
+ */
+function matchByBasePath(config, currentPath) {
+  const candidates = Object.entries(config.projects)
+    .filter(([, projectConfig]) => projectConfig.base_path)
+    .map(([name, projectConfig]) => ({ name, config: { ...projectConfig, base_path: canonicalPath(projectConfig.base_path) } }))
+    .filter(({ config: { base_path: basePath } }) => isWithin(currentPath, basePath))
+    .sort((a, b) => b.config.base_path.length - a.config.base_path.length || a.name.localeCompare(b.name));
+
+  if (candidates.length === 0) {
+    return null;
+  }
+
+  const tied = candidates.filter(candidate => candidate.config.base_path === candidates[0].config.base_path);
+  if (tied.length > 1) {
+    console.log(chalk.yellow(`Projects ${tied.map(candidate => candidate.name).join(', ')} share base_path ${tied[0].config.base_path}; using ${tied[0].name}`));
+  }
+  return candidates[0];
+}
+
+/**
+ * Apply the deployment unit a path belongs to: projects may declare units
+ * ({admin: {path: web/admin, clients: {...}}, ...}) whose settings override the
+ * project's below their path(s), relative to base_path; the deepest path wins
+ */
+function applyUnit(projectConfig, currentPath) {
+  let match = null;
+  for (const [name, unit] of Object.entries(projectConfig.units || {})) {
+    for (const unitPath of [].concat(unit.path || [])) {
+      const dir = path.resolve(projectConfig.base_path, unitPath);
+      if (isWithin(currentPath, dir) && dir.length > (match?.dir.length ?? -1)) {
+        match = { name, unit, dir };
+      }
+    }
+  }
+  return match ? selectUnit(projectConfig, match.name) : projectConfig;
+}
+
+/**
+ * Project config with a named unit's settings applied, as for a path below it
+ */
+function selectUnit(projectConfig, name) {
+  if (!projectConfig.units?.[name]) {
+    throw new Error(`Unit '${name}' is not configured`);
+  }
+  const { path: unitPath, ...settings } = projectConfig.units[name];
+  return { ...projectConfig, ...settings, unit: name };
+}
+
+/**
+ * Match a checkout outside every base_path by its git remotes against the projects'
+ * git_remote (a URL, or just the repository name). The checkout's root then stands
+ * in for base_path
+ */
+function matchByGitRemote(config, currentPath) {
+  const git = args => {
+    const result = Bun.spawnSync(['git', ...args], { cwd: currentPath, stdout: 'pipe', stderr: 'ignore' });
+    return result.exitCode === 0 ? result.stdout.toString().trim() : '';
+  };
+
+  const remotes = git(['config', '--get-regexp', '^remote\\..*\\.url$'])
+    .split('\n')
+    .map(line => line.split(/\s+/)[1])
+    .filter(Boolean)
+    .map(normalizeRemote);
+  if (remotes.length === 0) {
+    return null;
+  }
+
+  for (const [projectName, projectConfig] of Object.entries(config.projects)) {
+    const wanted = [].concat(projectConfig.git_remote || []).map(normalizeRemote);
+    const matches = wanted.some(remote => remotes.some(actual =>
+      actual === remote || (!remote.includes('/') && actual.endsWith(`/${remote}`))));
+    if (matches) {
+      const root = git(['rev-parse', '--show-toplevel']);
+      console.log(chalk.yellow(`Matched project ${projectName} by git remote; using ${root} instead of ${projectConfig.base_path}`));
+      return { name: projectName, config: { ...projectConfig, base_path: root } };
+    }
+  }
+  return null;
+}
+
+/**
+ * Comparable form of a git URL: git@host:org/repo.git and https://user@host/org/repo
+ * both become host/org/repo
+ */
+function normalizeRemote(url) {
+  return String(url)
+    .trim()
+    .replace(/^[a-z+]+:\/\//i, '')
+    .replace(/^[^@/]+@/, '')
+    .replace(/^([^/:]+):(?!\d)/, '$1/')
+    .replace(/\.git\/?$/, '')
+    .replace(/\/+$/, '')
+    .toLowerCase();
+}
+
 /**
  * Walk up directory tree to find pom.xml
  */
@@ -70,6 +221,29 @@ function findPomXml(startPath) {
   return null;
 }
 
+/**
+ * Walk up directory tree to find the nearest pom.xml or Gradle build script
+ * A directory with both is treated as Maven
+ */
+function findBuildFile(startPath) {
+  let currentDir = startPath;
+  const rootDir = path.parse(currentDir).root;
+
+  while (currentDir !== rootDir) {
+    const pomPath = path.join(currentDir, 'pom.xml');
+    if (fs.existsSync(pomPath)) {
+      return pomPath;
+    }
+    const gradleBuild = findGradleBuild(currentDir);
+    if (gradleBuild) {
+      return gradleBuild;
+    }
+    currentDir = path.dirname(currentDir);
+  }
+
+  return null;
+}
+
 /**
  * Parse pom.xml file
  */
@@ -82,51 +256,517 @@ function parsePom(pomPath) {
   }
 }
 
+// Whether the global_modules deprecation was already reported this run
+let warnedDeprecated = false;
+
 /**
- * Detect module information from POM
+ * Find the modules map entry for a module, trying each name in turn
+ * Exact keys win; otherwise the first matching pattern, either a glob ("EJB*")
+ * or a regex between slashes ("/^EJB(Pcs|Mto)$/")
  */
-function detectModule(pomPath, pom, projectConfig) {
-  const artifactId = pom.project?.artifactId;
-  const packaging = pom.project?.packaging || 'jar';
+function findModuleEntry(moduleMap, names) {
+  for (const name of names) {
+    if (Object.hasOwn(moduleMap, name)) {
+      return moduleMap[name];
+    }
+  }
 
-  if (!artifactId) {
-    throw new Error('artifactId not found in pom.xml');
+  for (const [key, entry] of Object.entries(moduleMap)) {
+    const pattern = toModulePattern(key);
+    if (pattern && names.some(name => pattern.test(name))) {
+      return entry;
+    }
   }
+  return undefined;
+}
 
-  // Check if this is a global module
+function toModulePattern(key) {
+  if (key.length > 2 && key.startsWith('/') && key.endsWith('/')) {
+    return new RegExp(key.slice(1, -1));
+  }
+  if (/[*?]/.test(key)) {
+    const source = key.replace(/[.+^${}()|[\]\\]/g, '\\$&').replace(/\*/g, '.*').replace(/\?/g, '.');
+    return new RegExp(`^${source}$`);
+  }
+  return null;
+}
+
+/**
+ * Split a modules map entry: either the deployment path, or an object with
+ * path plus per-module settings (skip_tests, build_goal, restart_severity, health_check)
+ */
+function parseModuleEntry(entry) {
+  if (entry && typeof entry === 'object') {
+    const { path: deploymentPath, ...settings } = entry;
+    return { deploymentPath: deploymentPath || '', settings };
+  }
+  return { deploymentPath: entry || '', settings: {} };
+}
+
+/**
+ * Module name -> deployment path map of a project
+ * Still reads the deprecated global_modules, which `jmw config migrate` rewrites into modules
+ */
+function getModuleMap(projectConfig) {
+  if (projectConfig.global_modules && !warnedDeprecated) {
+    warnedDeprecated = true;
+    console.log(chalk.yellow('global_modules is deprecated, run `jmw config migrate` to move it to modules'));
+  }
+  return { ...projectConfig.global_modules, ...projectConfig.modules };
+}
+
+/**
+ * The POM followed by its ancestors, walking <parent> through relativePath (default
+ * ../pom.xml) and falling back to the local Maven repository
+ * The paths of the POMs read are added to files
+ */
+function readPomChain(pomPath, pom, files = []) {
+  const chain = [pom];
+  let currentPath = pomPath;
+  files.push(pomPath);
+
+  // The depth limit guards against cycles
+  while (chain.length < 20) {
+    const parent = chain[chain.length - 1].project?.parent;
+    if (!parent?.artifactId) break;
+
+    currentPath = findParentPom(currentPath, parent);
+    if (!currentPath) break;
+    chain.push(parsePom(currentPath));
+    files.push(currentPath);
+  }
+  return chain;
+}
+
+/**
+ * Locate a <parent> POM, or null when it's neither on disk nor in ~/.m2
+ */
+function findParentPom(pomPath, parent) {
+  // An empty <relativePath/> means the parent only comes from a repository
+  const relativePath = parent.relativePath ?? '../pom.xml';
+  if (relativePath) {
+    let candidate = path.resolve(path.dirname(pomPath), relativePath);
+    if (fs.existsSync(candidate) && fs.statSync(candidate).isDirectory()) {
+      candidate = path.join(candidate, 'pom.xml');
+    }
+    if (fs.existsSync(candidate) && parsePom(candidate).project?.artifactId === parent.artifactId) {
+      return candidate;
+    }
+  }
+
+  if (!parent.groupId || !parent.version) {
+    return null;
+  }
+  const repoPath = path.join(os.homedir(), '.m2', 'repository', ...parent.groupId.split('.'),
+    parent.artifactId, parent.version, `${parent.artifactId}-${parent.version}.pom`);
+  return fs.existsSync(repoPath) ? repoPath : null;
+}
+
+/**
+ * Properties available to ${...} references in a POM: <properties> inherited through
+ * the chain (nearest wins) and the project's coordinates
+ */
+function getPomProperties(chain) {
+  const [{ project = {} }] = chain;
+  const inherited = key => chain.map(pom => pom.project?.[key]).find(value => value !== undefined);
+
+  const properties = Object.assign({}, ...chain.map(pom => pom.project?.properties || {}).reverse());
+  properties['project.groupId'] = project.groupId ?? project.parent?.groupId;
+  properties['project.artifactId'] = project.artifactId;
+  properties['project.version'] = project.version ?? project.parent?.version;
+  properties['project.parent.groupId'] = project.parent?.groupId;
+  properties['project.parent.artifactId'] = project.parent?.artifactId;
+  properties['project.parent.version'] = project.parent?.version;
+  properties['project.build.finalName'] = chain.map(pom => pom.project?.build?.finalName).find(Boolean);
+  properties['project.name'] = inherited('name');
+
+  // Deprecated aliases still found in older POMs
+  for (const key of ['version', 'artifactId', 'groupId']) {
+    properties[key] ??= properties[`project.${key}`];
+    properties[`pom.${key}`] ??= properties[`project.${key}`];
+  }
+  return properties;
+}
+
+/**
+ * Resolve ${...} references in a POM value; references that can't be resolved
+ * are left as they are
+ */
+function resolvePomValue(value, properties) {
+  let resolved = String(value);
+  // Properties may refer to other properties; the limit guards against cycles
+  for (let i = 0; i < 10 && resolved.includes('${'); i++) {
+    resolved = resolved.replace(/\$\{([^}]+)\}/g, (ref, name) => properties[name] !== undefined ? String(properties[name]) : ref);
+  }
+  return resolved;
+}
+
+/**
+ * Dependencies declared by a POM with their versions resolved, including versions
+ * only given in <dependencyManagement> of the POM or its parents
+ */
+function getDependencies(pomPath, pom = parsePom(pomPath)) {
+  const chain = readPomChain(pomPath, pom);
+  const properties = getPomProperties(chain);
+  const key = dependency => `${dependency.groupId}:${dependency.artifactId}`;
+
+  const managed = new Map();
+  for (const ancestor of [...chain].reverse()) {
+    asArray(ancestor.project?.dependencyManagement?.dependencies?.dependency)
+      .forEach(dependency => managed.set(key(dependency), dependency.version));
+  }
+
+  return asArray(pom.project?.dependencies?.dependency).map(dependency => ({
+    groupId: resolvePomValue(dependency.groupId, properties),
+    artifactId: resolvePomValue(dependency.artifactId, properties),
+    version: resolvePomValue(dependency.version ?? managed.get(key(dependency)) ?? '', properties),
+    scope: dependency.scope || 'compile'
+  }));
+}
+
+/**
+ * Java version a module compiles for: maven.compiler.release, source or target, or
+ * the maven-compiler-plugin's <release>/<source>/<target>, nearest POM first
+ * Returns the major version ("1.8" is 8) or null when the POMs don't say
+ */
+function getRequiredJavaVersion(pomPath, pom = parsePom(pomPath)) {
+  const chain = readPomChain(pomPath, pom);
+  const properties = getPomProperties(chain);
+  const compiler = chain.flatMap(ancestor => [
+    ...asArray(ancestor.project?.build?.plugins?.plugin),
+    ...asArray(ancestor.project?.build?.pluginManagement?.plugins?.plugin)
+  ]).filter(plugin => plugin.artifactId === 'maven-compiler-plugin' && plugin.configuration);
+
+  const candidates = [
+    properties['maven.compiler.release'],
+    ...compiler.map(plugin => plugin.configuration.release),
+    properties['maven.compiler.source'],
+    properties['maven.compiler.target'],
+    ...compiler.flatMap(plugin => [plugin.configuration.source, plugin.configuration.target])
+  ];
+  for (const candidate of candidates) {
+    const major = parseJavaMajor(resolvePomValue(candidate ?? '', properties));
+    if (major) {
+      return major;
+    }
+  }
+  return null;
+}
+
+/**
+ * Major version of a Java version string: 8 for "1.8" or "1.8.0_292", 21 for "21.0.1"
+ */
+function parseJavaMajor(version) {
+  const match = String(version).match(/^(?:1\.)?(\d+)/);
+  return match ? Number(match[1]) : null;
+}
+
+/**
+ * finalName as Maven computes it (`mvn help:effective-pom`), for POMs using properties
+ * jmw can't resolve itself (profiles, settings.xml, remote parents). Null when Maven fails
+ */
+function readEffectiveFinalName(modulePath) {
+  const output = path.join(os.tmpdir(), `jmw-effective-pom-${process.pid}.xml`);
+  try {
+    const [file, args, spawnOptions] = getSpawnArgs(getMavenCommand(null), ['-q', '-N', 'help:effective-pom', `-Doutput=${output}`]);
+    const result = Bun.spawnSync([file, ...args], {
+      ...spawnOptions,
+      cwd: modulePath,
+      stdout: 'ignore',
+      stderr: 'ignore'
+    });
+    if (result.exitCode !== 0 || !fs.existsSync(output)) {
+      return null;
+    }
+    return parsePom(output).project?.build?.finalName ?? null;
+  } catch (error) {
+    return null;
+  } finally {
+    fs.rmSync(output, { force: true });
+  }
+}
+
+/**
+ * Deployment path and settings of a module from the project's modules map
+ * Tries the artifactId first, then the directory name, then patterns; modules map to
+ * a global module path, unlisted ones ("" too) are normal deployments
+ */
+function getModuleEntry(projectConfig, artifactId, modulePath) {
+  const names = [artifactId, path.basename(modulePath)];
+  return parseModuleEntry(findModuleEntry(getModuleMap(projectConfig), names));
+}
+
+/**
+ * Top of the reactor containing a module: the highest directory up to base_path
+ * whose pom.xml lists the one below it in <modules>
+ */
+function findReactorRoot(pomPath, basePath) {
+  let rootPom = pomPath;
+  let dir = path.dirname(pomPath);
+
+  while (dir !== basePath && path.dirname(dir) !== dir && isWithin(dir, basePath)) {
+    const parentPom = path.join(path.dirname(dir), 'pom.xml');
+    if (!fs.existsSync(parentPom)) break;
+
+    const listed = getModuleDirs(parentPom, parsePom(parentPom));
+    if (!listed.includes(dir)) break;
+    rootPom = parentPom;
+    dir = path.dirname(dir);
+  }
+  return rootPom;
+}
+
+/**
+ * Directories of the <modules> of a POM, including those of its profiles
+ */
+function getModuleDirs(pomPath, pom) {
+  const declared = [
+    ...asArray(pom.project?.modules?.module),
+    ...asArray(pom.project?.profiles?.profile).flatMap(profile => asArray(profile.modules?.module))
+  ];
+  return [...new Set(declared.map(module => {
+    // Entries may name the module's pom.xml instead of its directory
+    const resolved = path.resolve(path.dirname(pomPath), String(module));
+    return resolved.endsWith('.xml') ? path.dirname(resolved) : resolved;
+  }))];
+}
+
+/**
+ * Every module of a reactor, depth first from its root POM
+ * Returns {artifactId, packaging, path, depth, modules} with modules being child directories
+ */
+function getReactorModules(rootPomPath) {
+  const modules = [];
+  const seen = new Set();
+
+  const visit = (pomPath, depth) => {
+    if (seen.has(pomPath) || !fs.existsSync(pomPath)) return;
+    seen.add(pomPath);
+
+    const pom = parsePom(pomPath);
+    const children = getModuleDirs(pomPath, pom);
+    modules.push({
+      artifactId: pom.project?.artifactId,
+      packaging: pom.project?.packaging || 'jar',
+      path: path.dirname(pomPath),
+      depth,
+      modules: children
+    });
+    children.forEach(child => visit(path.join(child, 'pom.xml'), depth + 1));
+  };
+
+  visit(rootPomPath, 0);
+  return modules;
+}
+
+/**
+ * Modules of a module's reactor that depend on it, directly or through other reactor
+ * modules (test dependencies aside), flagged by whether the project's modules map lists them
+ */
+function findDependents(moduleInfo, projectConfig) {
+  const modules = getReactorModules(path.join(moduleInfo.reactorRoot, 'pom.xml'))
+    .map(module => ({ ...readPomInfo(path.join(module.path, 'pom.xml')), path: module.path }));
+  const key = module => `${module.groupId}:${module.artifactId}`;
+
+  // Reverse dependency graph of the reactor
+  const dependentsOf = new Map();
+  for (const module of modules) {
+    for (const dependency of getDependencies(path.join(module.path, 'pom.xml'))) {
+      if (dependency.scope === 'test') continue;
+      dependentsOf.set(key(dependency), [...(dependentsOf.get(key(dependency)) || []), module]);
+    }
+  }
+
+  const found = new Map();
+  const queue = [key(moduleInfo)];
+  while (queue.length > 0) {
+    for (const dependent of dependentsOf.get(queue.shift()) || []) {
+      if (found.has(key(dependent)) || dependent.path === moduleInfo.path) continue;
+      found.set(key(dependent), dependent);
+      queue.push(key(dependent));
+    }
+  }
+
+  const configured = getModuleMap(projectConfig);
+  return [...found.values()].map(module => ({ ...module, configured: findModuleEntry(configured, [module.artifactId, path.basename(module.path)]) !== undefined }));
+}
+
+/**
+ * finalName of a module; one the POMs alone don't resolve is asked from Maven when
+ * first needed rather than during detection, and cached until the POMs change.
+ * Null when not even Maven resolves it
+ */
+function getFinalName(moduleInfo) {
+  if (moduleInfo.finalName || !moduleInfo.pomFiles) {
+    return moduleInfo.finalName;
+  }
+  return cached('effective-pom', moduleInfo.path, () => ({
+    value: readEffectiveFinalName(moduleInfo.path),
+    files: moduleInfo.pomFiles
+  }));
+}
+
+/**
+ * What the POM (with its parents) says about a module: coordinates, packaging,
+ * finalName and <modules>. Cached until one of the POMs involved changes
+ */
+function readPomInfo(pomPath) {
+  return cached('poms', pomPath, () => {
+    const pom = parsePom(pomPath);
+    const artifactId = pom.project?.artifactId;
+
+    if (!artifactId) {
+      throw new Error('artifactId not found in pom.xml');
+    }
+
+    // Coordinates, packaging and finalName may come from parent POMs or properties
+    const files = [];
+    const properties = getPomProperties(readPomChain(pomPath, pom, files));
+    const packaging = resolvePomValue(pom.project.packaging || 'jar', properties);
+    const groupId = resolvePomValue(properties['project.groupId'] ?? '', properties);
+    const version = resolvePomValue(properties['project.version'] ?? '', properties);
+
+    // Artifacts are named <finalName>.<extension>, by default <artifactId>-<version>
+    const finalName = resolvePomValue(properties['project.build.finalName'] || '${project.artifactId}-${project.version}', properties);
+    const unresolved = finalName.includes('${');
+
+    return {
+      value: {
+        artifactId,
+        groupId,
+        version,
+        // null when it depends on properties only Maven can resolve, see getFinalName
+        finalName: unresolved ? null : finalName,
+        pomFiles: unresolved ? files : null,
+        packaging,
+        modules: asArray(pom.project?.modules?.module)
+      },
+      files
+    };
+  });
+}
+
+/**
+ * Detect module information from POM
+ */
+function detectModule(pomPath, projectConfig) {
+  const { artifactId, groupId, version, finalName, pomFiles, packaging, modules } = readPomInfo(pomPath);
   const modulePath = path.dirname(pomPath);
-  const relativePath = path.relative(projectConfig.base_path, modulePath);
 
-  // Determine deployment type - try artifactId first, then directory name
-  // Only global modules are listed in config; everything else is normal deployment
-  const dirName = path.basename(modulePath);
-  const moduleConfig = projectConfig.global_modules?.[artifactId] ?? projectConfig.global_modules?.[dirName];
-  const isGlobalModule = !!moduleConfig;
+  // Check if this is a global module
+  const { deploymentPath, settings } = getModuleEntry(projectConfig, artifactId, modulePath);
+  const isGlobalModule = !!deploymentPath;
 
   // Check if this is a single-repo project (all modules built together)
-  // single_repo: true = one repo, modules built together (e.g., MTO)
-  // single_repo: false = multiple repos, independent builds (e.g., Sinfomar)
-  const isMultiModule = projectConfig.single_repo === true;
-
-  // Extract submodules list if this POM defines any
-  const hasModules = pom.project?.modules?.module;
-  const modules = hasModules ? (Array.isArray(hasModules) ? hasModules : [hasModules]) : [];
+  // single_repo: true = one repo, modules built together from the reactor root, which
+  // may be several aggregator levels up (services/billing/EJBBilling)
+  // single_repo: false = multiple repos, each module built on its own
+  const reactorRoot = projectConfig.single_repo === true
+    ? path.dirname(findReactorRoot(pomPath, projectConfig.base_path))
+    : modulePath;
+  const relativePath = path.relative(reactorRoot, modulePath);
+  const isMultiModule = relativePath !== '';
 
   return {
     artifactId,
+    groupId,
+    version,
+    finalName,
+    pomFiles,
     packaging,
     path: modulePath,
+    reactorRoot,
     relativePath,
     isGlobalModule,
-    deploymentPath: moduleConfig || '',
+    deploymentPath,
+    settings,
     isMultiModule,
-    modules
+    modules,
+    buildSystem: 'maven',
+    outputDir: path.join(modulePath, 'target')
+  };
+}
+
+/**
+ * Detect module information from a Gradle build script
+ * Subprojects are always built from the root of their build, with task paths
+ */
+function detectGradleModule(buildFile, projectConfig) {
+  const modulePath = path.dirname(buildFile);
+  const reactorRoot = findGradleRoot(modulePath, projectConfig.base_path);
+  const { files, ...info } = cached('gradle', buildFile, () => {
+    const gradleInfo = readGradleInfo(buildFile, reactorRoot);
+    return { value: gradleInfo, files: gradleInfo.files };
+  });
+
+  const { deploymentPath, settings } = getModuleEntry(projectConfig, info.artifactId, modulePath);
+  const relativePath = path.relative(reactorRoot, modulePath);
+
+  return {
+    ...info,
+    path: modulePath,
+    reactorRoot,
+    relativePath,
+    isGlobalModule: !!deploymentPath,
+    deploymentPath,
+    settings,
+    isMultiModule: relativePath !== '',
+    buildSystem: 'gradle',
+    outputDir: path.join(modulePath, 'build', 'libs')
   };
 }
 
+/**
+ * Collect <properties> declared by the given POM profiles
+ * Later profiles override earlier ones, mirroring Maven's activation order
+ */
+function getProfileProperties(pom, profileIds) {
+  const declared = asArray(pom.project?.profiles?.profile);
+  const properties = {};
+
+  for (const id of profileIds) {
+    const profile = declared.find(p => String(p.id) === id);
+    if (profile?.properties) {
+      for (const [key, value] of Object.entries(profile.properties)) {
+        properties[key] = String(value);
+      }
+    }
+  }
+
+  return properties;
+}
+
+/**
+ * Normalize a parsed XML node that may be a single element or a list
+ */
+function asArray(value) {
+  if (value === undefined || value === null) return [];
+  return Array.isArray(value) ? value : [value];
+}
+
 export {
   detectProject,
+  selectUnit,
+  requireMaven,
   parsePom,
   findPomXml,
-  detectModule
+  findBuildFile,
+  detectModule,
+  getModuleMap,
+  findModuleEntry,
+  getModuleEntry,
+  findReactorRoot,
+  getReactorModules,
+  findDependents,
+  readPomInfo,
+  getFinalName,
+  isWithin,
+  getProfileProperties,
+  readPomChain,
+  getPomProperties,
+  getDependencies,
+  getRequiredJavaVersion,
+  parseJavaMajor,
+  resolvePomValue,
+  asArray
 };
@@ -0,0 +1,125 @@
+import fs from 'fs';
+import chalk from 'chalk';
+
+import { requireClientConfig, getClientHosts } from './config.js';
+import { getWildflyConfig } from './deployer.js';
+import { forEachController } from './controller.js';
+import { loadServerXml } from './serverxml.js';
+import { findServerArgs } from './ports.js';
+import { getManagementProtocol } from './mgmt.js';
+import { suggestKey } from './schema.js';
+import { symbol } from './output.js';
+
+/**
+ * Compare a parsed server config with jmw's settings for it
+ * Returns [{level: 'ok'|'warning'|'error', message}]
+ */
+function checkServerXml(wildflyConfig, { file, config, hostFile, host }) {
+  const results = [];
+  const ok = message => results.push({ level: 'ok', message });
+  const warn = message => results.push({ level: 'warning', message });
+  const fail = message => results.push({ level: 'error', message });
+
+  // Marker deployments copy to <mode>/deployments, which the scanner must watch
+  if (wildflyConfig.mode === 'standalone') {
+    const watching = config.scanners.find(scanner =>
+      scanner.path === 'deployments' && (scanner.relativeTo ?? 'jboss.server.base.dir') === 'jboss.server.base.dir');
+    if (config.scanners.length === 0) {
+      fail(`${file} has no deployment scanner, marker deployments are never picked up`);
+    } else if (!watching) {
+      fail(`The deployment scanner watches ${config.scanners.map(s => `${s.relativeTo ? `${s.relativeTo}/` : ''}${s.path}`).join(', ')}, jmw copies to standalone/deployments`);
+    } else {
+      ok(`Deployment scanner ${watching.name} watches standalone/deployments`);
+      if (!watching.enabled) {
+        warn(`Deployment scanner ${watching.name} is disabled (jmw scanner --enable)`);
+      }
+      if (watching.timeout > wildflyConfig.deploymentTimeout) {
+        warn(`jmw waits ${wildflyConfig.deploymentTimeout}s for a deployment, the scanner allows ${watching.timeout}s (deployment_timeout)`);
+      }
+    }
+  }
+
+  // Without a configured port jmw uses the server's own, so only a configured one can be wrong
+  const management = wildflyConfig.management || {};
+  const actual = wildflyConfig.mode === 'domain'
+    ? host?.managementPort
+    : getManagementProtocol(management) === 'https' ? config.ports.managementHttps : config.ports.managementHttp;
+  const source = wildflyConfig.mode === 'domain' ? hostFile : file;
+  if (actual === null || actual === undefined) {
+    warn(`Management port not found in ${source}`);
+  } else if (management.port && actual !== management.port) {
+    fail(`Management port is ${actual} in ${source}, jmw uses ${management.port} (management.port)`);
+  } else {
+    ok(`Management port ${actual}${management.port ? '' : ` (from ${source})`}`);
+  }
+
+  if (wildflyConfig.mode === 'domain' && wildflyConfig.serverGroup) {
+    const names = config.serverGroups.map(group => group.name);
+    if (!names.includes(wildflyConfig.serverGroup)) {
+      const suggestion = suggestKey(wildflyConfig.serverGroup, names);
+      fail(`Server group ${wildflyConfig.serverGroup}${suggestion ? ` (did you mean ${suggestion}?)` : ''} is not in ${file}, which has ${names.join(', ') || 'none'}`);
+    } else if (host && !host.servers.some(server => server.group === wildflyConfig.serverGroup)) {
+      warn(`Server group ${wildflyConfig.serverGroup} exists, but ${hostFile} defines no server in it`);
+    } else {
+      ok(`Server group ${wildflyConfig.serverGroup} exists`);
+    }
+  }
+  return results;
+}
+
+/**
+ * Server config as the running server sees it: its -c config file and -D properties
+ * (port offsets) when it runs, the configured file otherwise
+ */
+async function loadRunningServerXml(wildflyConfig, hostConfig = null) {
+  const args = await findServerArgs(wildflyConfig, hostConfig).catch(() => null);
+  return loadServerXml({ ...wildflyConfig, serverConfig: args?.serverConfig ?? wildflyConfig.serverConfig }, hostConfig, args?.properties);
+}
+
+/**
+ * Print check results, returning false when any is an error
+ */
+function showResults(results) {
+  const marks = {
+    ok: chalk.green(symbol('check')),
+    warning: chalk.yellow('!'),
+    error: chalk.red(symbol('cross'))
+  };
+  results.forEach(({ level, message }) => console.log(`  ${marks[level]} ${message}`));
+  return !results.some(result => result.level === 'error');
+}
+
+/**
+ * Check the local WildFly (or a client's hosts) against the project's settings,
+ * reading the server's own XML config
+ */
+async function runDoctor(detection, options = {}) {
+  const { project, projectConfig } = detection;
+  console.log(`  ${chalk.green(symbol('check'))} Config is valid, project ${project}`);
+
+  if (!options.client) {
+    const wildflyConfig = getWildflyConfig(projectConfig, null);
+    if (!wildflyConfig.root) {
+      console.log(`  ${chalk.yellow('!')} No wildfly_root, nothing to check locally`);
+      return true;
+    }
+    if (!fs.existsSync(wildflyConfig.root)) {
+      console.log(`  ${chalk.red(symbol('cross'))} wildfly_root ${wildflyConfig.root} does not exist`);
+      return false;
+    }
+    console.log(chalk.blue(`=== Server Config (local, ${wildflyConfig.root}) ===`));
+    return showResults(checkServerXml(wildflyConfig, await loadRunningServerXml(wildflyConfig)));
+  }
+
+  const clientConfig = requireClientConfig(projectConfig, options.client, options.env);
+  const wildflyConfig = getWildflyConfig(projectConfig, clientConfig);
+  return forEachController(wildflyConfig, getClientHosts(clientConfig), 'Server Config', async (controller, hostConfig) => {
+    const hostWildflyConfig = { ...wildflyConfig, management: hostConfig.management ?? wildflyConfig.management };
+    return showResults(checkServerXml(hostWildflyConfig, await loadRunningServerXml(hostWildflyConfig, hostConfig)));
+  }, { connect: false });
+}
+
+export {
+  checkServerXml,
+  runDoctor
+};
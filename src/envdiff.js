@@ -0,0 +1,143 @@
+import { $ } from 'bun';
+import chalk from 'chalk';
+
+import { requireClientConfig, getClientHosts, findKey } from './config.js';
+import { parsePom, getProfileProperties } from './detector.js';
+import { getProfiles, getBuildProperties } from './builder.js';
+import { runRemote } from './remote.js';
+import { getCliPath } from './jbosscli.js';
+import { symbol } from './output.js';
+
+const SYSTEM_PROPERTIES_CMD = ':read-children-resources(child-type=system-property)';
+
+/**
+ * Resolve an environment spec "<name>[@client]" against project config
+ * The name is matched against the client's environments (default client if omitted),
+ * falling back to a plain Maven profile built and queried locally. The Maven profile
+ * is the environment's profile setting, or the name matched against maven_profiles
+ */
+function resolveEnvironment(spec, projectConfig) {
+  const [name, explicitClient] = spec.split('@');
+  const clientName = explicitClient || projectConfig.default_client;
+  const client = clientName ? projectConfig.clients?.[clientName] : null;
+
+  // System properties are read from the first node; clustered hosts share configuration
+  let clientConfig = null;
+  if (client && findKey(client.environments, name)) {
+    clientConfig = getClientHosts(requireClientConfig(projectConfig, clientName, name))[0];
+  } else if (explicitClient) {
+    clientConfig = getClientHosts(requireClientConfig(projectConfig, clientName))[0];
+  }
+
+  const profileName = clientConfig?.profile ?? projectConfig.profile_aliases?.[name] ?? name;
+  const profile = findKey(projectConfig.maven_profiles, profileName) ?? profileName;
+
+  return { spec, profile, clientName: clientConfig ? clientName : null, clientConfig };
+}
+
+/**
+ * Collect everything jmw knows about deploying to an environment
+ */
+async function collectEnvironment(env, detection) {
+  const { projectConfig, pomPath } = detection;
+  const pom = parsePom(pomPath);
+  const activeProfiles = getProfiles(env.profile, projectConfig).filter(p => !p.startsWith('!'));
+
+  const healthCheck = env.clientConfig?.health_check ?? projectConfig.health_check;
+  const warmup = env.clientConfig?.warmup ?? projectConfig.warmup;
+
+  return {
+    'Maven profiles': { profiles: activeProfiles.join(',') },
+    'Maven profile properties': getProfileProperties(pom, activeProfiles),
+    'Build properties': getBuildProperties(env.profile, projectConfig),
+    'WildFly system properties': await querySystemProperties(env, projectConfig),
+    'Endpoints': {
+      health_check: healthCheck?.url,
+      ...Object.fromEntries((warmup?.urls || []).map((url, i) => [`warmup[${i}]`, url]))
+    }
+  };
+}
+
+/**
+ * Query WildFly system properties through jboss-cli (remote over SSH, or local WildFly)
+ */
+async function querySystemProperties(env, projectConfig) {
+  try {
+    let output;
+    if (env.clientConfig) {
+      const cli = `${env.clientConfig.wildfly_path}/bin/jboss-cli.sh`;
+      output = await runRemote(env.clientConfig, `${cli} -c --output-json --command="${SYSTEM_PROPERTIES_CMD}"`);
+    } else {
+      const cli = getCliPath(projectConfig.wildfly_root);
+      output = await $`${cli} -c --output-json --command=${SYSTEM_PROPERTIES_CMD}`.quiet().text();
+    }
+
+    const response = JSON.parse(output);
+    return Object.fromEntries(
+      Object.entries(response.result || {}).map(([name, prop]) => [name, prop.value])
+    );
+  } catch (error) {
+    return null;
+  }
+}
+
+/**
+ * Compare two environments and print differing values per category
+ */
+async function diffEnvironments(specA, specB, detection, options = {}) {
+  const envA = resolveEnvironment(specA, detection.projectConfig);
+  const envB = resolveEnvironment(specB, detection.projectConfig);
+
+  const [a, b] = await Promise.all([
+    collectEnvironment(envA, detection),
+    collectEnvironment(envB, detection)
+  ]);
+
+  let differences = 0;
+
+  for (const category of Object.keys(a)) {
+    console.log(chalk.blue(`=== ${category} ===`));
+
+    if (a[category] === null || b[category] === null) {
+      const unavailable = a[category] === null ? specA : specB;
+      console.log(chalk.yellow(`  Unavailable for ${unavailable} (server not reachable)`));
+      console.log('');
+      continue;
+    }
+
+    const keys = [...new Set([...Object.keys(a[category]), ...Object.keys(b[category])])].sort();
+    let shown = 0;
+
+    for (const key of keys) {
+      const valueA = a[category][key];
+      const valueB = b[category][key];
+      const same = valueA === valueB;
+
+      if (same && !options.all) continue;
+      if (!same) differences++;
+      shown++;
+
+      const marker = same ? ' ' : chalk.yellow(symbol('differs'));
+      console.log(`${marker} ${chalk.white.bold(key)}`);
+      console.log(`    ${specA}: ${formatValue(valueA)}`);
+      console.log(`    ${specB}: ${formatValue(valueB)}`);
+    }
+
+    if (shown === 0) {
+      console.log(chalk.green('  No differences'));
+    }
+    console.log('');
+  }
+
+  return differences;
+}
+
+function formatValue(value) {
+  return value === undefined ? chalk.gray('(not set)') : value;
+}
+
+export {
+  resolveEnvironment,
+  collectEnvironment,
+  diffEnvironments
+};
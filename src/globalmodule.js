@@ -0,0 +1,168 @@
+import fs from 'fs';
+import path from 'path';
+import chalk from 'chalk';
+
+import { requireClientConfig, getClientHosts } from './config.js';
+import { sha256File } from './history.js';
+import { runRemote, shellQuote, getSudoPrefix, getRestartCommand } from './remote.js';
+import { executeOperations } from './outbox.js';
+import { confirmAction } from './confirm.js';
+import { planPreflightOperation } from './preflight.js';
+
+/**
+ * Checksums of all files in a local directory, keyed by relative path
+ */
+async function hashLocalDirectory(dir) {
+  const hashes = new Map();
+
+  const walk = async (current) => {
+    for (const entry of fs.readdirSync(current, { withFileTypes: true })) {
+      const full = path.join(current, entry.name);
+      if (entry.isDirectory()) {
+        await walk(full);
+      } else if (entry.isFile()) {
+        hashes.set(path.relative(dir, full).split(path.sep).join('/'), await sha256File(full));
+      }
+    }
+  };
+
+  await walk(dir);
+  return hashes;
+}
+
+/**
+ * Checksums of all files in a remote directory, keyed by relative path
+ */
+async function hashRemoteDirectory(clientConfig, dir) {
+  const output = await runRemote(clientConfig,
+    `if [ -d ${shellQuote(dir)} ]; then cd ${shellQuote(dir)} && find . -type f ! -name '*.jmw-part' -exec sha256sum {} +; fi`);
+
+  const hashes = new Map();
+  for (const line of output.split('\n')) {
+    const match = line.match(/^([0-9a-f]{64})\s+\.\/(.+)$/);
+    if (match) {
+      hashes.set(match[2], match[1]);
+    }
+  }
+  return hashes;
+}
+
+/**
+ * Compare local and remote directory contents
+ */
+function diffDirectories(local, remote) {
+  const changes = { added: [], modified: [], removed: [], unchanged: [] };
+
+  for (const [file, hash] of local) {
+    if (!remote.has(file)) {
+      changes.added.push(file);
+    } else if (remote.get(file) !== hash) {
+      changes.modified.push(file);
+    } else {
+      changes.unchanged.push(file);
+    }
+  }
+  for (const file of remote.keys()) {
+    if (!local.has(file)) {
+      changes.removed.push(file);
+    }
+  }
+
+  return changes;
+}
+
+/**
+ * Sync the local WildFly global module directory to a client, transferring only changed files
+ */
+async function syncGlobalModule(detection, clientName, options = {}) {
+  const { project, projectConfig, module: moduleInfo } = detection;
+
+  if (!moduleInfo.isGlobalModule) {
+    throw new Error(`${moduleInfo.artifactId} is not a global module`);
+  }
+
+  const clientConfig = requireClientConfig(projectConfig, clientName, options.env);
+  const localDir = options.source || path.join(projectConfig.wildfly_root, moduleInfo.deploymentPath);
+  const remoteDir = clientConfig.wildfly_path + '/' + moduleInfo.deploymentPath;
+
+  if (!fs.existsSync(localDir)) {
+    throw new Error(`Local module directory not found: ${localDir} (deploy locally first or pass --source)`);
+  }
+
+  console.log(chalk.blue('=== Global Module Sync ==='));
+  console.log(`Source: ${localDir}`);
+  console.log(`Target: ${clientName}${clientConfig.environment ? ` (${clientConfig.environment})` : ''}:${remoteDir}`);
+
+  const local = await hashLocalDirectory(localDir);
+  const plans = [];
+
+  for (const hostConfig of getClientHosts(clientConfig)) {
+    const changes = diffDirectories(local, await hashRemoteDirectory(hostConfig, remoteDir));
+
+    console.log('');
+    console.log(chalk.blue(`--- ${hostConfig.host} ---`));
+    changes.added.forEach(file => console.log(chalk.green(`  + ${file}`)));
+    changes.modified.forEach(file => console.log(chalk.yellow(`  ~ ${file}`)));
+    changes.removed.forEach(file => console.log(options.delete ? chalk.red(`  - ${file}`) : chalk.gray(`  ? ${file} (remote only, kept)`)));
+    console.log(`${changes.unchanged.length} file(s) unchanged`);
+
+    const transfers = [...changes.added, ...changes.modified];
+    const deletions = options.delete ? changes.removed : [];
+    if (transfers.length > 0 || deletions.length > 0) {
+      plans.push({ hostConfig, transfers, deletions });
+    }
+  }
+  console.log('');
+
+  if (plans.length === 0) {
+    console.log(chalk.green('Remote module directory is up to date'));
+    return true;
+  }
+
+  if (options.dryRun) {
+    console.log(chalk.yellow('Dry run, nothing transferred'));
+    return true;
+  }
+
+  const confirmed = await confirmAction(detection.confirmations, 'deploy', {
+    message: `Sync module to ${plans.length} host(s)?`,
+    environment: [clientConfig.environment, clientName],
+    expected: clientName
+  });
+  if (!confirmed) {
+    console.log(chalk.red('Sync cancelled'));
+    return false;
+  }
+
+  let completed = true;
+  for (const { hostConfig, transfers, deletions } of plans) {
+    const sudo = getSudoPrefix(hostConfig);
+    const dirs = [...new Set(transfers.map(file => path.posix.dirname(`${remoteDir}/${file}`)))];
+
+    const bytes = transfers.reduce((total, file) => total + fs.statSync(path.join(localDir, file)).size, 0);
+    const operations = [
+      ...(transfers.length > 0 ? [planPreflightOperation(remoteDir, bytes)] : []),
+      ...(dirs.length > 0 ? [{ type: 'exec', command: `${sudo}mkdir -p ${dirs.map(shellQuote).join(' ')}`, description: 'Create module directories' }] : []),
+      ...transfers.map(file => ({ type: 'upload', source: path.join(localDir, file), dest: `${remoteDir}/${file}` })),
+      ...deletions.map(file => ({ type: 'exec', command: `${sudo}rm -f ${shellQuote(`${remoteDir}/${file}`)}`, description: `Delete ${file}` }))
+    ].map(op => ({ ...op, project, unit: projectConfig.unit, client: clientName, env: clientConfig.environment, host: hostConfig.host }));
+
+    console.log(chalk.blue(`--- ${hostConfig.host} ---`));
+    if (await executeOperations(operations, hostConfig)) {
+      console.log(chalk.green(`Synced ${transfers.length} file(s)`));
+      console.log(chalk.yellow('Restart required for global module changes:'));
+      console.log(`  ssh ${hostConfig.user}@${hostConfig.host} "${getRestartCommand(hostConfig, { interactive: true })}"`);
+    } else {
+      completed = false;
+    }
+  }
+
+  return completed;
+}
+
+export {
+  hashLocalDirectory,
+  hashRemoteDirectory,
+  diffDirectories,
+  syncGlobalModule
+};
@@ -0,0 +1,119 @@
+import fs from 'fs';
+import path from 'path';
+
+const BUILD_FILES = ['build.gradle', 'build.gradle.kts'];
+const SETTINGS_FILES = ['settings.gradle', 'settings.gradle.kts'];
+
+function findFile(dir, names) {
+  return names.map(name => path.join(dir, name)).find(file => fs.existsSync(file)) ?? null;
+}
+
+/**
+ * Gradle build script of a directory, or null
+ */
+function findGradleBuild(dir) {
+  return findFile(dir, BUILD_FILES);
+}
+
+/**
+ * Root of the Gradle build a module belongs to: the nearest directory up to base_path
+ * with a settings script, or the module itself
+ */
+function findGradleRoot(moduleDir, basePath) {
+  let dir = moduleDir;
+  while (dir.startsWith(basePath) && path.dirname(dir) !== dir) {
+    if (findFile(dir, SETTINGS_FILES)) {
+      return dir;
+    }
+    if (dir === basePath) break;
+    dir = path.dirname(dir);
+  }
+  return moduleDir;
+}
+
+/**
+ * First string assigned to a property in a build script: `version = '1.0'`,
+ * `version '1.0'` (Groovy) or `archiveBaseName.set("app")` (Kotlin)
+ */
+function readScriptValue(script, name) {
+  const match = script.match(new RegExp(`(?:^|[\\s{;])${name.replace('.', '\\.')}\\s*(?:=|\\.set\\()?\\s*['"]([^'"]+)['"]`, 'm'));
+  return match ? match[1] : null;
+}
+
+function readGradleProperty(dir, name) {
+  const file = path.join(dir, 'gradle.properties');
+  if (!fs.existsSync(file)) return null;
+  const match = fs.readFileSync(file, 'utf8').match(new RegExp(`^\\s*${name}\\s*[=:]\\s*(.+?)\\s*$`, 'm'));
+  return match ? match[1] : null;
+}
+
+/**
+ * Projects included by a settings script, as directories relative to the root
+ * (include 'services:billing' lives in services/billing)
+ */
+function readIncludedProjects(settingsScript) {
+  const projects = [];
+  for (const line of settingsScript.split('\n')) {
+    if (!/^\s*include\b/.test(line)) continue;
+    for (const [, name] of line.matchAll(/['"]:?([^'"]+)['"]/g)) {
+      projects.push(name.split(':').join('/'));
+    }
+  }
+  return projects;
+}
+
+/**
+ * Module information from a Gradle build script, in the shape the detector gives
+ * Maven modules: name (the artifactId), group, version, packaging from the war/ear
+ * plugins, and the archive name Gradle produces
+ */
+function readGradleInfo(buildFile, rootDir) {
+  const moduleDir = path.dirname(buildFile);
+  const script = fs.readFileSync(buildFile, 'utf8');
+  const settingsFile = findFile(rootDir, SETTINGS_FILES);
+  const settings = settingsFile ? fs.readFileSync(settingsFile, 'utf8') : '';
+
+  const name = (moduleDir === rootDir && readScriptValue(settings, 'rootProject.name')) || path.basename(moduleDir);
+  const version = readScriptValue(script, 'version') ?? readGradleProperty(moduleDir, 'version') ?? readGradleProperty(rootDir, 'version') ?? '';
+  const groupId = readScriptValue(script, 'group') ?? readGradleProperty(moduleDir, 'group') ?? readGradleProperty(rootDir, 'group') ?? '';
+
+  const plugin = type => new RegExp(`(id\\s*\\(?\\s*['"]${type}['"]|apply\\s+plugin:\\s*['"]${type}['"]|^\\s*\`?${type}\`?\\s*$)`, 'm').test(script);
+  const packaging = plugin('ear') ? 'ear' : plugin('war') ? 'war' : 'jar';
+
+  // Archives are <archiveBaseName>-<version>, without the version when it's unspecified
+  const baseName = readScriptValue(script, 'archiveBaseName') ?? name;
+  const finalName = readScriptValue(script, 'archiveFileName')?.replace(/\.[^.]+$/, '') ?? (version ? `${baseName}-${version}` : baseName);
+
+  return {
+    artifactId: name,
+    groupId,
+    version,
+    finalName: finalName.includes('$') ? null : finalName,
+    packaging,
+    modules: moduleDir === rootDir ? readIncludedProjects(settings) : [],
+    files: [...new Set([buildFile, settingsFile, path.join(moduleDir, 'gradle.properties'), path.join(rootDir, 'gradle.properties')])].filter(Boolean)
+  };
+}
+
+/**
+ * Gradle command for a build: the root's wrapper when it has one, else gradle
+ */
+function getGradleCommand(rootDir) {
+  const wrapper = path.join(rootDir, process.platform === 'win32' ? 'gradlew.bat' : 'gradlew');
+  return fs.existsSync(wrapper) ? wrapper : 'gradle';
+}
+
+/**
+ * Task path prefix of a module within its build (":services:billing:"), empty for the root
+ */
+function getTaskPrefix(moduleInfo) {
+  return moduleInfo.relativePath ? `:${moduleInfo.relativePath.split(path.sep).join(':')}:` : '';
+}
+
+export {
+  findGradleBuild,
+  findGradleRoot,
+  readGradleInfo,
+  getGradleCommand,
+  getTaskPrefix
+};
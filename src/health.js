@@ -0,0 +1,148 @@
+import chalk from 'chalk';
+
+import { emitProgress } from './progress.js';
+
+/**
+ * Poll health check URL until it responds with 2xx or timeout expires
+ */
+async function waitForHealthy(healthConfig) {
+  const timeout = (healthConfig.timeout || 120) * 1000;
+  const interval = (healthConfig.interval || 2) * 1000;
+  const deadline = Date.now() + timeout;
+
+  console.log(chalk.blue('=== Health Check ==='));
+  console.log(`URL: ${healthConfig.url}`);
+  emitProgress('health', null, `Waiting for ${healthConfig.url}`);
+
+  while (Date.now() < deadline) {
+    try {
+      // Bounded by the deadline, as a server still deploying may accept and never answer
+      const response = await fetch(healthConfig.url, { signal: AbortSignal.timeout(Math.max(deadline - Date.now(), 1)) });
+      if (response.ok) {
+        console.log(chalk.green(`Healthy (HTTP ${response.status})`));
+        emitProgress('health', 100, 'Healthy');
+        return true;
+      }
+    } catch (error) {
+      // Server not accepting connections yet, or not answering in time
+    }
+    await sleep(interval);
+  }
+
+  console.log(chalk.red(`Health check did not pass within ${timeout / 1000}s`));
+  emitProgress('health', null, 'Health check timed out');
+  return false;
+}
+
+/**
+ * Send warm-up requests so the first real user doesn't pay for JSF/JIT initialization
+ */
+async function runWarmup(warmupConfig) {
+  const urls = warmupConfig.urls || [];
+  const concurrency = warmupConfig.concurrency || 1;
+  const iterations = warmupConfig.iterations || 1;
+
+  if (urls.length === 0) {
+    return [];
+  }
+
+  console.log(chalk.blue('=== Warm-up ==='));
+  console.log(`URLs: ${urls.length}, iterations: ${iterations}, concurrency: ${concurrency}`);
+
+  // Queue every (url, iteration) pair and drain it with a fixed number of workers
+  const queue = [];
+  for (let i = 0; i < iterations; i++) {
+    urls.forEach(url => queue.push(url));
+  }
+
+  const timings = new Map(urls.map(url => [url, { times: [], errors: 0 }]));
+  const total = queue.length;
+
+  const worker = async () => {
+    while (queue.length > 0) {
+      const url = queue.shift();
+      const stats = timings.get(url);
+      const start = performance.now();
+      try {
+        const response = await fetch(url);
+        await response.arrayBuffer();
+        if (!response.ok) {
+          stats.errors++;
+        }
+        stats.times.push(performance.now() - start);
+      } catch (error) {
+        stats.errors++;
+      }
+      emitProgress('warmup', ((total - queue.length) / total) * 100, url);
+    }
+  };
+
+  await Promise.all(Array.from({ length: concurrency }, worker));
+
+  const results = urls.map(url => summarizeTimings(url, timings.get(url)));
+  showWarmupStats(results);
+  return results;
+}
+
+/**
+ * Compute timing statistics for a warm-up URL
+ */
+function summarizeTimings(url, stats) {
+  const sorted = [...stats.times].sort((a, b) => a - b);
+  const total = sorted.reduce((sum, t) => sum + t, 0);
+
+  return {
+    url,
+    requests: sorted.length,
+    errors: stats.errors,
+    first: stats.times[0] ?? null,
+    min: sorted[0] ?? null,
+    max: sorted[sorted.length - 1] ?? null,
+    avg: sorted.length > 0 ? total / sorted.length : null
+  };
+}
+
+/**
+ * Display warm-up timing statistics
+ */
+function showWarmupStats(results) {
+  const ms = value => value === null ? '-' : `${Math.round(value)}ms`;
+
+  for (const result of results) {
+    console.log(`  ${result.url}`);
+    console.log(`    first: ${ms(result.first)}  min: ${ms(result.min)}  avg: ${ms(result.avg)}  max: ${ms(result.max)}`);
+    if (result.errors > 0) {
+      console.log(chalk.yellow(`    errors: ${result.errors}`));
+    }
+  }
+}
+
+/**
+ * Run health check (if configured) followed by warm-up (if configured)
+ */
+async function verifyAndWarmup(config) {
+  if (config.health_check?.url) {
+    const healthy = await waitForHealthy(config.health_check);
+    if (!healthy) {
+      return false;
+    }
+  }
+
+  if (config.warmup?.urls?.length > 0) {
+    console.log('');
+    await runWarmup(config.warmup);
+  }
+
+  return true;
+}
+
+function sleep(ms) {
+  return new Promise(resolve => setTimeout(resolve, ms));
+}
+
+export {
+  waitForHealthy,
+  runWarmup,
+  verifyAndWarmup,
+  sleep
+};
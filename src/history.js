@@ -0,0 +1,104 @@
+import fs from 'fs';
+import path from 'path';
+import crypto from 'crypto';
+import { $ } from 'bun';
+
+import { getConfigDir } from './config.js';
+
+/**
+ * Path of the build history file (one JSON record per line)
+ */
+function getHistoryPath() {
+  return path.join(getConfigDir(), 'history.jsonl');
+}
+
+/**
+ * Compute SHA-256 of a file
+ */
+function sha256File(filePath) {
+  return new Promise((resolve, reject) => {
+    const hash = crypto.createHash('sha256');
+    fs.createReadStream(filePath)
+      .on('data', chunk => hash.update(chunk))
+      .on('end', () => resolve(hash.digest('hex')))
+      .on('error', reject);
+  });
+}
+
+/**
+ * Describe artifacts with size and checksum
+ */
+async function checksumArtifacts(artifactPaths) {
+  return Promise.all(artifactPaths.map(async artifactPath => ({
+    path: artifactPath,
+    name: path.basename(artifactPath),
+    size: fs.statSync(artifactPath).size,
+    sha256: await sha256File(artifactPath)
+  })));
+}
+
+/**
+ * Get current git commit of a directory, or null outside a repository
+ */
+async function getGitSha(dir) {
+  try {
+    const sha = await $`cd ${dir} && git rev-parse HEAD`.quiet().text();
+    return sha.trim();
+  } catch (error) {
+    return null;
+  }
+}
+
+/**
+ * Check whether a directory has uncommitted changes
+ */
+async function isGitDirty(dir) {
+  try {
+    const status = await $`cd ${dir} && git status --porcelain`.quiet().text();
+    return status.trim() !== '';
+  } catch (error) {
+    return true;
+  }
+}
+
+/**
+ * Append a build record to history
+ */
+function recordBuild(entry) {
+  const historyPath = getHistoryPath();
+  fs.mkdirSync(path.dirname(historyPath), { recursive: true });
+  fs.appendFileSync(historyPath, JSON.stringify({ type: 'build', timestamp: new Date().toISOString(), ...entry }) + '\n');
+}
+
+/**
+ * Read history records, newest first, optionally filtered
+ */
+function readHistory(filter = {}) {
+  const historyPath = getHistoryPath();
+  if (!fs.existsSync(historyPath)) {
+    return [];
+  }
+
+  return fs.readFileSync(historyPath, 'utf8')
+    .split('\n')
+    .filter(line => line.trim())
+    .map(line => {
+      try {
+        return JSON.parse(line);
+      } catch (error) {
+        return null;
+      }
+    })
+    .filter(entry => entry && Object.entries(filter).every(([key, value]) => value === undefined || entry[key] === value))
+    .reverse();
+}
+
+export {
+  getHistoryPath,
+  sha256File,
+  checksumArtifacts,
+  getGitSha,
+  isGitDirty,
+  recordBuild,
+  readHistory
+};
@@ -0,0 +1,162 @@
+import path from 'path';
+import { $ } from 'bun';
+import chalk from 'chalk';
+
+import { getMavenEnv, getMavenSettingsArgs } from './builder.js';
+import { runCommand, onCancel } from './process.js';
+import { getMavenCommand } from './maven.js';
+import { sleep } from './health.js';
+
+const DEFAULT_STARTUP_TIMEOUT = 120;
+
+/**
+ * Find integration test settings for a module (by artifactId, then directory name)
+ */
+function getIntegrationTestConfig(projectConfig, moduleInfo) {
+  const tests = projectConfig.integration_tests || {};
+  return tests[moduleInfo.artifactId] ?? tests[path.basename(moduleInfo.path)] ?? null;
+}
+
+/**
+ * Start containers, run failsafe integration tests against them, and tear down
+ */
+async function runIntegrationTests(detection, options = {}) {
+  const { projectConfig, module: moduleInfo } = detection;
+  const itestConfig = getIntegrationTestConfig(projectConfig, moduleInfo);
+
+  if (!itestConfig) {
+    throw new Error(`No integration_tests configured for module ${moduleInfo.artifactId}`);
+  }
+
+  const started = [];
+  const unregister = onCancel(() => stopContainers(started));
+
+  try {
+    console.log(chalk.blue('=== Containers ==='));
+    for (const [name, containerConfig] of Object.entries(itestConfig.containers || {})) {
+      const container = await startContainer(name, containerConfig);
+      started.push(container);
+      await waitForContainer(container, containerConfig);
+      console.log(chalk.green(`  ${name}: ${container.image} ready`));
+    }
+    console.log('');
+
+    // Connection properties reference containers as ${name.host} and ${name.port.<containerPort>}
+    const properties = interpolateProperties(itestConfig.properties || {}, started);
+    const args = [
+      ...(itestConfig.goals || ['verify']),
+      ...getMavenSettingsArgs(projectConfig),
+      ...(moduleInfo.isMultiModule ? ['-pl', moduleInfo.relativePath, '-am'] : []),
+      ...Object.entries(properties).map(([key, value]) => `-D${key}=${value}`),
+      ...(options.test ? [`-Dit.test=${options.test}`] : [])
+    ];
+
+    console.log(chalk.blue('=== Integration Tests ==='));
+    const command = getMavenCommand(moduleInfo.reactorRoot);
+    console.log(chalk.yellow('Command:'), command, args.join(' '));
+    console.log('');
+
+    const cwd = moduleInfo.isMultiModule ? moduleInfo.reactorRoot : moduleInfo.path;
+    await runCommand(command, args, { cwd, env: getMavenEnv(projectConfig) });
+
+  } finally {
+    unregister();
+    if (!options.keep) {
+      await stopContainers(started);
+    } else if (started.length > 0) {
+      console.log(chalk.yellow(`Containers kept running: ${started.map(c => c.id.slice(0, 12)).join(', ')}`));
+    }
+  }
+}
+
+/**
+ * Start a container with its ports published on random host ports
+ */
+async function startContainer(name, containerConfig) {
+  const args = ['run', '-d', '--rm', '--label', 'jmw.itest=true'];
+
+  for (const port of containerConfig.ports || []) {
+    args.push('-p', `127.0.0.1::${port}`);
+  }
+  for (const [key, value] of Object.entries(containerConfig.env || {})) {
+    args.push('-e', `${key}=${value}`);
+  }
+  args.push(containerConfig.image, ...(containerConfig.command || []));
+
+  const id = (await $`docker ${args}`.quiet().text()).trim();
+
+  const ports = {};
+  for (const port of containerConfig.ports || []) {
+    const mapping = (await $`docker port ${id} ${port}`.quiet().text()).trim().split('\n')[0];
+    ports[port] = mapping.split(':').pop();
+  }
+
+  return { name, id, image: containerConfig.image, host: '127.0.0.1', ports };
+}
+
+/**
+ * Wait until the container logs its readiness message, or its first port accepts connections
+ */
+async function waitForContainer(container, containerConfig) {
+  const timeout = (containerConfig.startup_timeout || DEFAULT_STARTUP_TIMEOUT) * 1000;
+  const deadline = Date.now() + timeout;
+
+  while (Date.now() < deadline) {
+    if (containerConfig.wait_for_log) {
+      const logs = await $`docker logs ${container.id}`.quiet().nothrow();
+      if ((logs.stdout.toString() + logs.stderr.toString()).includes(containerConfig.wait_for_log)) {
+        return;
+      }
+    } else {
+      const firstPort = Object.values(container.ports)[0];
+      if (!firstPort || await isPortOpen(container.host, firstPort)) {
+        return;
+      }
+    }
+    await sleep(1000);
+  }
+
+  throw new Error(`Container ${container.name} not ready within ${timeout / 1000}s`);
+}
+
+async function isPortOpen(host, port) {
+  try {
+    const socket = await Bun.connect({ hostname: host, port: Number(port), socket: { data() {} } });
+    socket.end();
+    return true;
+  } catch (error) {
+    return false;
+  }
+}
+
+/**
+ * Replace ${name.host} and ${name.port.N} placeholders with container details
+ */
+function interpolateProperties(properties, containers) {
+  const values = {};
+  for (const container of containers) {
+    values[`${container.name}.host`] = container.host;
+    for (const [containerPort, hostPort] of Object.entries(container.ports)) {
+      values[`${container.name}.port.${containerPort}`] = hostPort;
+    }
+  }
+
+  return Object.fromEntries(Object.entries(properties).map(([key, value]) => [
+    key,
+    String(value).replace(/\$\{([^}]+)\}/g, (match, name) => values[name] ?? match)
+  ]));
+}
+
+async function stopContainers(containers) {
+  for (const container of containers) {
+    await $`docker rm -f ${container.id}`.quiet().nothrow();
+  }
+  if (containers.length > 0) {
+    console.log(chalk.gray(`Removed ${containers.length} container(s)`));
+  }
+}
+
+export {
+  getIntegrationTestConfig,
+  runIntegrationTests
+};
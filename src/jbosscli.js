@@ -0,0 +1,267 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import chalk from 'chalk';
+import { $ } from 'bun';
+import { pathToFileURL } from 'url';
+
+import { requireClientConfig, getClientHosts } from './config.js';
+import { runCommand } from './process.js';
+import { streamRemote, shellQuote, getSudoPrefix } from './remote.js';
+import { resolveManagementConfig } from './secrets.js';
+import { getWildflyConfig } from './deployer.js';
+import { resolveManagementPort } from './ports.js';
+import { getManagementProtocol } from './mgmt.js';
+import { findArtifacts } from './builder.js';
+import { getFinalName } from './detector.js';
+import { forEachController } from './controller.js';
+
+/**
+ * Path of jboss-cli under a local WildFly installation
+ */
+function getCliPath(wildflyRoot) {
+  return getScriptPath(wildflyRoot, 'jboss-cli');
+}
+
+/**
+ * Path of a script in a WildFly bin directory (.bat on Windows, .sh elsewhere)
+ */
+function getScriptPath(wildflyRoot, name) {
+  return path.join(wildflyRoot, 'bin', `${name}${process.platform === 'win32' ? '.bat' : '.sh'}`);
+}
+
+/**
+ * jboss-cli connection arguments from a management config ({host, port, protocol})
+ * Without one, jboss-cli connects to localhost:9990 using local authentication;
+ * credentials are never arguments, see withControllerArgs and getRemoteCliCommand
+ */
+function getControllerArgs(mgmtConfig, defaultHost = 'localhost') {
+  const args = ['-c'];
+  if (!mgmtConfig) {
+    return args;
+  }
+
+  const host = mgmtConfig.host || defaultHost;
+  const port = mgmtConfig.port || 9990;
+  const protocol = getManagementProtocol(mgmtConfig) === 'https' ? 'remote+https' : 'remote+http';
+  args.push(`--controller=${protocol}://${host}:${port}`);
+  return args;
+}
+
+/**
+ * Elytron client config (wildfly-config.xml) with the management user and password,
+ * on one line; null without a user
+ */
+function getAuthConfig(mgmtConfig) {
+  if (!mgmtConfig?.user) {
+    return null;
+  }
+  const escape = value => String(value).replace(/[<>&"']/g, char => `&#${char.charCodeAt(0)};`);
+  return [
+    '<configuration><authentication-client xmlns="urn:elytron:client:1.2">',
+    '<authentication-rules><rule use-configuration="jmw"/></authentication-rules>',
+    '<authentication-configurations><configuration name="jmw">',
+    `<set-user-name name="${escape(mgmtConfig.user)}"/>`,
+    `<credentials><clear-password password="${escape(mgmtConfig.password || '')}"/></credentials>`,
+    '</configuration></authentication-configurations>',
+    '</authentication-client></configuration>'
+  ].join('');
+}
+
+/**
+ * Run with the local jboss-cli arguments of a management config; credentials go in a
+ * wildfly-config.xml only the user can read, removed afterwards, so they show up in no
+ * process list
+ */
+async function withControllerArgs(mgmtConfig, run) {
+  const args = getControllerArgs(mgmtConfig);
+  const auth = getAuthConfig(mgmtConfig);
+  if (!auth) {
+    return run(args);
+  }
+
+  const dir = fs.mkdtempSync(path.join(os.tmpdir(), 'jmw-cli-'));
+  const file = path.join(dir, 'wildfly-config.xml');
+  fs.writeFileSync(file, auth, { mode: 0o600 });
+  try {
+    return await run([...args, `-Dwildfly.config.url=${pathToFileURL(file).href}`]);
+  } finally {
+    fs.rmSync(dir, { recursive: true, force: true });
+  }
+}
+
+/**
+ * Remote shell lines running jboss-cli on a host, as sudo_user, with a management
+ * config's connection and the given (quoted) arguments. Credentials are sent as
+ * input on stdin and written to a wildfly-config.xml only sudo_user can read, so they
+ * show up in no process list. The lines end with jboss-cli's exit status
+ * Returns {command, input}
+ */
+function getRemoteCliCommand(hostConfig, mgmtConfig, args) {
+  const sudo = getSudoPrefix(hostConfig);
+  const cli = [shellQuote(`${hostConfig.wildfly_path}/bin/jboss-cli.sh`), ...getControllerArgs(mgmtConfig).map(shellQuote), args].join(' ');
+  const auth = getAuthConfig(mgmtConfig);
+  if (!auth) {
+    return { command: `${sudo}${cli}`, input: null };
+  }
+
+  return {
+    command: [
+      'IFS= read -r JMW_CLI_AUTH',
+      `jmw_auth=$(${sudo}mktemp) || exit 1`,
+      `printf '%s\\n' "$JMW_CLI_AUTH" | ${sudo}tee "$jmw_auth" > /dev/null`,
+      `${sudo}${cli} "-Dwildfly.config.url=file://$jmw_auth"`,
+      'jmw_rc=$?',
+      `${sudo}rm -f "$jmw_auth"`,
+      '(exit $jmw_rc)'
+    ].join('\n'),
+    input: `${auth}\n`
+  };
+}
+
+/**
+ * Run a jboss-cli command against the local server and return its output
+ */
+async function runLocalCli(wildflyRoot, mgmtConfig, command) {
+  const result = await withControllerArgs(await resolveManagementConfig(mgmtConfig), controllerArgs =>
+    $`${getCliPath(wildflyRoot)} ${controllerArgs} --command=${command}`.quiet().nothrow());
+  if (result.exitCode !== 0) {
+    const output = result.stdout.toString().trim() || result.stderr.toString().trim();
+    throw new Error(`jboss-cli '${command}' failed: ${output || `exit code ${result.exitCode}`}`);
+  }
+  return result.stdout.toString();
+}
+
+/**
+ * Run a jboss-cli command with output streamed, on the local server or on each of a client's hosts
+ * Remote runs connect from the host itself, as sudo_user so local authentication works
+ */
+async function runCli(detection, command, options = {}) {
+  const { projectConfig } = detection;
+
+  if (!options.client) {
+    const wildflyConfig = getWildflyConfig(projectConfig, null);
+    const cliPath = getCliPath(projectConfig.wildfly_root);
+    const management = await resolveManagementPort(projectConfig.management, wildflyConfig);
+    console.log(chalk.gray(`${cliPath} --command=${command}`));
+    await withControllerArgs(await resolveManagementConfig(management), controllerArgs =>
+      runCommand(cliPath, [...controllerArgs, `--command=${command}`]));
+    return true;
+  }
+
+  const clientConfig = requireClientConfig(projectConfig, options.client, options.env);
+  const wildflyConfig = getWildflyConfig(projectConfig, clientConfig);
+  const hostConfigs = getClientHosts(clientConfig);
+  let succeeded = true;
+
+  for (const hostConfig of hostConfigs) {
+    if (hostConfigs.length > 1) {
+      console.log(chalk.blue(`--- ${hostConfig.host} ---`));
+    }
+    const management = await resolveManagementPort(hostConfig.management, wildflyConfig, hostConfig);
+    const remote = getRemoteCliCommand(hostConfig, await resolveManagementConfig(management), shellQuote(`--command=${command}`));
+    const exitCode = await streamRemote(hostConfig, remote.command, remote.input);
+    if (exitCode !== 0) {
+      console.log(chalk.red(`jboss-cli exited with code ${exitCode} on ${hostConfig.host}`));
+      succeeded = false;
+    }
+  }
+
+  return succeeded;
+}
+
+/**
+ * Variables a CLI script may use as ${name}; --var name=value adds or overrides them
+ */
+function getScriptVariables(detection, wildflyConfig, clientName, clientConfig, overrides = []) {
+  const { project, module: moduleInfo } = detection;
+  const finalName = getFinalName(moduleInfo);
+  const artifactPath = findArtifacts(moduleInfo.outputDir, moduleInfo.packaging, finalName)[0] ?? null;
+  const variables = {
+    project,
+    module: moduleInfo.artifactId,
+    artifact: artifactPath ? path.basename(artifactPath) : `${finalName || moduleInfo.artifactId}.${moduleInfo.packaging}`,
+    artifact_path: artifactPath ?? '',
+    server_group: wildflyConfig.serverGroup ?? '',
+    client: clientName ?? '',
+    env: clientConfig?.environment ?? '',
+    wildfly_root: (clientConfig ? clientConfig.wildfly_path : wildflyConfig.root) ?? ''
+  };
+  for (const override of overrides) {
+    const separator = override.indexOf('=');
+    if (separator < 1) {
+      throw new Error(`--var needs name=value, got '${override}'`);
+    }
+    variables[override.slice(0, separator)] = override.slice(separator + 1);
+  }
+  return variables;
+}
+
+/**
+ * Replace the script's ${name} variables that jmw knows; the rest (system properties,
+ * ${env.X}) are left for jboss-cli to resolve
+ */
+function substituteVariables(script, variables) {
+  return script.replace(/\$\{([\w.-]+)\}/g, (placeholder, name) =>
+    Object.hasOwn(variables, name) ? String(variables[name]) : placeholder);
+}
+
+/**
+ * Run a jboss-cli script file with jmw's variables substituted, on the local server
+ * or on a client's hosts
+ */
+async function runCliScript(detection, file, options = {}) {
+  const { projectConfig } = detection;
+  if (!fs.existsSync(file)) {
+    throw new Error(`CLI script not found: ${file}`);
+  }
+  const text = fs.readFileSync(file, 'utf8');
+
+  if (!options.client) {
+    const wildflyConfig = getWildflyConfig(projectConfig, null);
+    const script = substituteVariables(text, getScriptVariables(detection, wildflyConfig, null, null, options.var));
+    const management = await resolveManagementPort(projectConfig.management, wildflyConfig);
+    const scriptPath = path.join(fs.mkdtempSync(path.join(os.tmpdir(), 'jmw-cli-')), path.basename(file));
+    fs.writeFileSync(scriptPath, script);
+    console.log(chalk.gray(`${getCliPath(projectConfig.wildfly_root)} --file=${file}`));
+    try {
+      await withControllerArgs(await resolveManagementConfig(management), controllerArgs =>
+        runCommand(getCliPath(projectConfig.wildfly_root), [...controllerArgs, `--file=${scriptPath}`]));
+    } finally {
+      fs.rmSync(path.dirname(scriptPath), { recursive: true, force: true });
+    }
+    return true;
+  }
+
+  const clientConfig = requireClientConfig(projectConfig, options.client, options.env);
+  const wildflyConfig = getWildflyConfig(projectConfig, clientConfig);
+  const script = substituteVariables(text, getScriptVariables(detection, wildflyConfig, options.client, clientConfig, options.var));
+  return forEachController(wildflyConfig, getClientHosts(clientConfig), 'CLI Script', async (controller, hostConfig) => {
+    const management = await resolveManagementPort(hostConfig.management, wildflyConfig, hostConfig);
+    const remote = getRemoteCliCommand(hostConfig, await resolveManagementConfig(management), '--file="$f"');
+    // Readable by sudo_user, removed whatever jboss-cli returns
+    const command = [
+      'f=$(mktemp /tmp/jmw-XXXXXX.cli) || exit 1',
+      `printf '%s\\n' ${shellQuote(script)} > "$f" && chmod 644 "$f"`,
+      remote.command,
+      'rc=$?',
+      'rm -f "$f"',
+      'exit $rc'
+    ].join('\n');
+    const exitCode = await streamRemote(hostConfig, command, remote.input);
+    if (exitCode !== 0) {
+      console.log(chalk.red(`jboss-cli exited with code ${exitCode} on ${hostConfig.host}`));
+    }
+    return exitCode === 0;
+  }, { connect: false });
+}
+
+export {
+  getCliPath,
+  getScriptPath,
+  withControllerArgs,
+  getRemoteCliCommand,
+  runLocalCli,
+  runCli,
+  runCliScript
+};
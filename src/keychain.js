@@ -0,0 +1,67 @@
+import { $ } from 'bun';
+
+// Service name secrets are stored under in the OS keychain
+const SERVICE = 'jmw';
+
+/**
+ * Look up a secret in the OS keychain (macOS Keychain, or the Secret Service on Linux)
+ * Returns null when the secret is not stored
+ */
+async function readKeychain(account) {
+  let result;
+  switch (process.platform) {
+    case 'darwin':
+      result = await $`security find-generic-password -s ${SERVICE} -a ${account} -w`.quiet().nothrow();
+      break;
+    case 'linux':
+      result = await $`secret-tool lookup service ${SERVICE} account ${account}`.quiet().nothrow();
+      break;
+    default:
+      throw new Error(`OS keychain is not supported on ${process.platform}`);
+  }
+
+  const secret = result.stdout.toString().replace(/\n$/, '');
+  return result.exitCode === 0 && secret ? secret : null;
+}
+
+/**
+ * Store (or replace) a secret in the OS keychain
+ */
+async function writeKeychain(account, secret) {
+  let result;
+  switch (process.platform) {
+    case 'darwin':
+      // -w last prompts for the secret (and again to confirm), fed from stdin so it stays out of argv
+      result = await $`security add-generic-password -U -s ${SERVICE} -a ${account} -w < ${Buffer.from(`${secret}\n${secret}\n`)}`.quiet().nothrow();
+      break;
+    case 'linux':
+      result = await $`secret-tool store --label=${`${SERVICE} ${account}`} service ${SERVICE} account ${account} < ${Buffer.from(secret)}`.quiet().nothrow();
+      break;
+    default:
+      throw new Error(`OS keychain is not supported on ${process.platform}`);
+  }
+
+  if (result.exitCode !== 0) {
+    throw new Error(`Could not store secret in keychain: ${result.stderr.toString().trim()}`);
+  }
+}
+
+/**
+ * Remove a secret from the OS keychain, ignoring missing entries
+ */
+async function deleteKeychain(account) {
+  switch (process.platform) {
+    case 'darwin':
+      await $`security delete-generic-password -s ${SERVICE} -a ${account}`.quiet().nothrow();
+      break;
+    case 'linux':
+      await $`secret-tool clear service ${SERVICE} account ${account}`.quiet().nothrow();
+      break;
+  }
+}
+
+export {
+  readKeychain,
+  writeKeychain,
+  deleteKeychain
+};
@@ -0,0 +1,111 @@
+import chalk from 'chalk';
+
+import { requireClientConfig, getClientHosts } from './config.js';
+import { getWildflyConfig } from './deployer.js';
+import { createController, forEachController } from './controller.js';
+
+const LEVELS = ['ALL', 'FINEST', 'FINER', 'TRACE', 'DEBUG', 'FINE', 'CONFIG', 'INFO', 'WARN', 'WARNING', 'ERROR', 'SEVERE', 'FATAL', 'OFF'];
+
+/**
+ * Addresses of the logging subsystems a change applies to: the server's in standalone
+ * mode, the profile of the server group (or of every group) in domain mode
+ */
+async function getLoggingAddresses(controller, wildflyConfig) {
+  if (wildflyConfig.mode !== 'domain') {
+    return [[{ subsystem: 'logging' }]];
+  }
+
+  const groups = wildflyConfig.serverGroup
+    ? [wildflyConfig.serverGroup]
+    : await controller.execute([], 'read-children-names', { 'child-type': 'server-group' });
+  const profiles = new Set();
+  for (const group of groups) {
+    profiles.add(await controller.execute([{ 'server-group': group }], 'read-attribute', { name: 'profile' }));
+  }
+  return [...profiles].map(profile => [{ profile }, { subsystem: 'logging' }]);
+}
+
+/**
+ * Address of a logger below a logging subsystem; "root" names the root logger
+ */
+function getLoggerAddress(subsystem, category) {
+  return category.toLowerCase() === 'root'
+    ? [...subsystem, { 'root-logger': 'ROOT' }]
+    : [...subsystem, { logger: category }];
+}
+
+/**
+ * Show or set logger levels through one controller
+ * Without a category the configured loggers are listed; a logger that doesn't exist yet is added
+ */
+async function setLogLevelOn(controller, wildflyConfig, category, level) {
+  for (const subsystem of await getLoggingAddresses(controller, wildflyConfig)) {
+    const profile = subsystem.length > 1 ? subsystem[0].profile : null;
+    if (profile) {
+      console.log(chalk.white.bold(`  ${profile}`));
+    }
+    const indent = profile ? '    ' : '  ';
+
+    if (!category) {
+      const root = await controller.execute(getLoggerAddress(subsystem, 'root'), 'read-attribute', { name: 'level' });
+      console.log(`${indent}${'ROOT'.padEnd(40)} ${root}`);
+      for (const { address, result } of await controller.execute([...subsystem, { logger: '*' }], 'read-resource') || []) {
+        console.log(`${indent}${address[address.length - 1].logger.padEnd(40)} ${result.level ?? chalk.gray('(inherited)')}`);
+      }
+      continue;
+    }
+
+    const address = getLoggerAddress(subsystem, category);
+    let current = null;
+    let exists = true;
+    try {
+      current = await controller.execute(address, 'read-attribute', { name: 'level' }) ?? null;
+    } catch (error) {
+      if (!/not found|WFLYCTL0216/i.test(error.message)) throw error;
+      exists = false;
+    }
+
+    if (!level) {
+      console.log(`${indent}${category}: ${!exists ? chalk.gray('not configured (inherits its parent\'s level)') : current ?? chalk.gray('(inherited)')}`);
+    } else if (!exists) {
+      await controller.execute(address, 'add', { level });
+      console.log(chalk.green(`${indent}${category}: added at ${level}`));
+    } else if (current === level) {
+      console.log(`${indent}${category}: already ${level}`);
+    } else {
+      await controller.execute(address, 'write-attribute', { name: 'level', value: level });
+      console.log(chalk.green(`${indent}${category}: ${current ?? '(inherited)'} -> ${level}`));
+    }
+  }
+  return true;
+}
+
+/**
+ * Show or change a logger level on the local WildFly or a client's hosts, at runtime
+ * and without a restart
+ */
+async function setLogLevel(detection, category, level, options = {}) {
+  const { projectConfig } = detection;
+  if (level) {
+    level = level.toUpperCase();
+    if (!LEVELS.includes(level)) {
+      throw new Error(`Unknown level '${level}'. Levels: ${LEVELS.join(', ')}`);
+    }
+  }
+
+  if (!options.client) {
+    const wildflyConfig = getWildflyConfig(projectConfig, null);
+    const controller = await createController(wildflyConfig);
+    console.log(chalk.blue(`=== Log Levels (local, ${controller.target}) ===`));
+    return setLogLevelOn(controller, wildflyConfig, category, level);
+  }
+
+  const clientConfig = requireClientConfig(projectConfig, options.client, options.env);
+  const wildflyConfig = getWildflyConfig(projectConfig, clientConfig);
+  return forEachController(wildflyConfig, getClientHosts(clientConfig), 'Log Levels', controller =>
+    setLogLevelOn(controller, wildflyConfig, category, level));
+}
+
+export {
+  setLogLevel
+};
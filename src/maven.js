@@ -0,0 +1,20 @@
+import fs from 'fs';
+import path from 'path';
+
+const WINDOWS = process.platform === 'win32';
+
+/**
+ * Maven command for a build: the root's wrapper when it has one, else mvn
+ * (mvnw.cmd and mvn.cmd on Windows, which has no extensionless scripts)
+ */
+function getMavenCommand(rootDir) {
+  const wrapper = rootDir ? path.join(rootDir, WINDOWS ? 'mvnw.cmd' : 'mvnw') : null;
+  if (wrapper && fs.existsSync(wrapper)) {
+    return wrapper;
+  }
+  return WINDOWS ? 'mvn.cmd' : 'mvn';
+}
+
+export {
+  getMavenCommand
+};
@@ -0,0 +1,311 @@
+import fs from 'fs';
+import path from 'path';
+import crypto from 'crypto';
+
+import { resolveSecret } from './secrets.js';
+import { NO_RETRY, withRetry } from './retry.js';
+
+const DEFAULT_MANAGEMENT_PORT = 9990;
+
+// Failures where the request never reached the server, safe to retry for any operation
+const NOT_CONNECTED = /ConnectionRefused|ECONNREFUSED|EHOSTUNREACH|ENETUNREACH|FailedToOpenSocket|Unable to connect/i;
+
+// Failures that may have cut a request short, only retried for operations that change nothing
+const INTERRUPTED = /ECONNRESET|ConnectionClosed|socket|closed|timed out|TimeoutError/i;
+
+/**
+ * Create a client for the WildFly HTTP management API (DMR over HTTP, digest auth)
+ * Besides raw operations (execute) and uploads, it offers the operations commands
+ * share: reading resources, deploying, undeploying, reloading, shutting down and
+ * querying server groups. https may trust an internal CA (tls.ca_file) or skip
+ * verification (tls.insecure_skip_verify)
+ * Connection failures are retried with the retry policy; reads and uploads are also
+ * retried when cut short, and given up after the policy's timeout
+ */
+function createManagementClient(mgmtConfig, defaultHost = 'localhost', retry = NO_RETRY) {
+  const protocol = getManagementProtocol(mgmtConfig);
+  const host = mgmtConfig.host || defaultHost;
+  const port = mgmtConfig.port || DEFAULT_MANAGEMENT_PORT;
+  const baseUrl = `${protocol}://${host}:${port}`;
+  const tls = getTlsOptions(mgmtConfig);
+
+  // Digest challenge is reused across requests with an incrementing nonce count
+  let challenge = null;
+  let nonceCount = 0;
+  // Resolved on the first challenge, so secret references are only looked up when needed
+  let password = null;
+
+  const authorize = (method, uri) => {
+    if (!challenge || !mgmtConfig.user) return {};
+    nonceCount++;
+    return { Authorization: buildDigestHeader(challenge, method, uri, mgmtConfig.user, password, nonceCount) };
+  };
+
+  /**
+   * POST once; a busy or unavailable server (503, and 502/504 from a proxy for
+   * requests that change nothing) throws so it can be retried
+   */
+  const send = async (uri, makeBody, headers, idempotent) => {
+    const response = await fetch(baseUrl + uri, {
+      method: 'POST',
+      headers: { ...headers, ...authorize('POST', uri) },
+      body: makeBody(),
+      ...(tls ? { tls } : {}),
+      ...(idempotent && retry.timeout ? { signal: AbortSignal.timeout(retry.timeout * 1000) } : {})
+    });
+    if (response.status === 503 || (idempotent && [502, 504].includes(response.status))) {
+      throw Object.assign(new Error(`HTTP ${response.status} from ${baseUrl}`), { status: response.status });
+    }
+    return response;
+  };
+
+  const isTransient = idempotent => error =>
+    error.status !== undefined || NOT_CONNECTED.test(`${error.code} ${error.message}`) ||
+    (idempotent && (error.name === 'TimeoutError' || INTERRUPTED.test(`${error.code} ${error.message}`)));
+
+  /**
+   * Send a request, answering a digest challenge once
+   * The body factory is called per attempt since multipart bodies can't be replayed
+   */
+  const request = async (uri, makeBody, headers = {}, idempotent = false) => {
+    for (let attempt = 0; attempt < 2; attempt++) {
+      const response = await withRetry(() => send(uri, makeBody, headers, idempotent), retry, isTransient(idempotent), `Management API ${baseUrl}`);
+
+      if (response.status === 401 && attempt === 0) {
+        const header = response.headers.get('www-authenticate') || '';
+        if (!header.startsWith('Digest')) {
+          throw new Error(`Management API requires unsupported authentication: ${header}`);
+        }
+        if (!mgmtConfig.user) {
+          throw new Error(`Management API at ${baseUrl} requires credentials (management.user/password)`);
+        }
+        password ??= await resolveSecret(mgmtConfig.password || '', 'management.password');
+        challenge = parseDigestChallenge(header);
+        nonceCount = 0;
+        continue;
+      }
+
+      return response;
+    }
+    throw new Error(`Management API authentication failed for ${mgmtConfig.user}@${baseUrl}`);
+  };
+
+  /**
+   * Execute a DMR operation and return its result
+   */
+  const execute = async (operation) => {
+    const response = await request('/management', () => JSON.stringify(operation), { 'Content-Type': 'application/json' },
+      operation.operation.startsWith('read-'));
+    return parseResponse(response, operation.operation);
+  };
+
+  /**
+   * Upload a file to the content repository, returning its content hash
+   * Content is stored by hash, so an interrupted upload can be sent again
+   */
+  const upload = async (filePath) => {
+    const response = await request('/management/add-content', () => {
+      const form = new FormData();
+      form.append('file', Bun.file(filePath), path.basename(filePath));
+      return form;
+    }, {}, true);
+    return parseResponse(response, 'add-content');
+  };
+
+  const readResource = (address = [], options = {}) => execute({
+    operation: 'read-resource',
+    address,
+    recursive: !!options.recursive,
+    'include-runtime': !!options.includeRuntime
+  });
+
+  const readAttribute = (address, name) => execute({ operation: 'read-attribute', address, name });
+
+  const readChildrenNames = (address, childType) => execute({ operation: 'read-children-names', address, 'child-type': childType });
+
+  /**
+   * Server groups of a domain controller
+   */
+  const listServerGroups = () => readChildrenNames([], 'server-group');
+
+  /**
+   * Deployments of a server group, or of the server itself without one
+   */
+  const listDeployments = (serverGroup) => readChildrenNames(serverGroup ? [{ 'server-group': serverGroup }] : [], 'deployment');
+
+  /**
+   * Upload an artifact and add or replace its deployment; in domain mode it is
+   * also assigned, enabled, to the server group
+   */
+  const deploy = async (artifactPath, { mode, serverGroup }) => {
+    const name = path.basename(artifactPath);
+    const hash = await upload(artifactPath);
+    const content = [{ hash }];
+
+    if ((await listDeployments()).includes(name)) {
+      // Replaces content everywhere the deployment is assigned, keeping assignments
+      await execute({ operation: 'full-replace-deployment', address: [], name, content, enabled: true });
+    } else if (mode === 'domain') {
+      await execute({ operation: 'add', address: [{ deployment: name }], content });
+    } else {
+      await execute({ operation: 'add', address: [{ deployment: name }], content, enabled: true });
+    }
+
+    if (mode === 'domain' && !(await listDeployments(serverGroup)).includes(name)) {
+      await execute({ operation: 'add', address: [{ 'server-group': serverGroup }, { deployment: name }], enabled: true });
+    }
+
+    return { name, hash: hash.BYTES_VALUE };
+  };
+
+  /**
+   * Remove a deployment, first from its server group in domain mode
+   */
+  const undeploy = async (name, { mode, serverGroup }) => {
+    if (mode === 'domain' && (await listDeployments(serverGroup)).includes(name)) {
+      await execute({ operation: 'remove', address: [{ 'server-group': serverGroup }, { deployment: name }] });
+    }
+    if ((await listDeployments()).includes(name)) {
+      await execute({ operation: 'remove', address: [{ deployment: name }] });
+    }
+  };
+
+  /**
+   * Reload the server, or the servers of a group in domain mode
+   */
+  const reload = ({ mode, serverGroup } = {}) => mode === 'domain'
+    ? execute({ operation: 'reload-servers', address: [{ 'server-group': serverGroup }], blocking: true })
+    : execute({ operation: 'reload', address: [] });
+
+  /**
+   * Shut the server down (restart: have it start again); in domain mode the
+   * servers of the group are stopped or restarted instead, and without a group
+   * the host controllers are shut down
+   * The connection may drop before the server answers, which counts as success
+   */
+  const shutdown = async ({ mode, serverGroup, restart = false } = {}) => {
+    const run = async (operation) => {
+      try {
+        return await execute(operation);
+      } catch (error) {
+        if (error.name === 'TypeError' || /ECONNRESET|socket|closed/i.test(error.message)) {
+          return null;
+        }
+        throw error;
+      }
+    };
+
+    if (mode !== 'domain') {
+      return run({ operation: 'shutdown', address: [], restart });
+    }
+    if (serverGroup) {
+      return run({ operation: restart ? 'restart-servers' : 'stop-servers', address: [{ 'server-group': serverGroup }], blocking: true });
+    }
+    for (const host of await readChildrenNames([], 'host')) {
+      await run({ operation: 'shutdown', address: [{ host }], restart });
+    }
+    return null;
+  };
+
+  return {
+    baseUrl,
+    execute,
+    upload,
+    readResource,
+    readAttribute,
+    readChildrenNames,
+    listServerGroups,
+    listDeployments,
+    deploy,
+    undeploy,
+    reload,
+    shutdown
+  };
+}
+
+/**
+ * Protocol of a management config: http unless https is set explicitly
+ */
+function getManagementProtocol(mgmtConfig) {
+  return mgmtConfig?.protocol || 'http';
+}
+
+/**
+ * fetch TLS options for https management endpoints, or null for the defaults
+ * tls: {ca_file, insecure_skip_verify}
+ */
+function getTlsOptions(mgmtConfig) {
+  const caFile = mgmtConfig.tls?.ca_file;
+  const insecure = mgmtConfig.tls?.insecure_skip_verify;
+  if (mgmtConfig.tls && getManagementProtocol(mgmtConfig) !== 'https') {
+    throw new Error('management.tls is set but management.protocol is not https');
+  }
+  if (!caFile && !insecure) {
+    return null;
+  }
+  if (caFile && !fs.existsSync(caFile)) {
+    throw new Error(`Management CA file not found: ${caFile} (management.tls.ca_file)`);
+  }
+  return {
+    ...(caFile ? { ca: fs.readFileSync(caFile, 'utf8') } : {}),
+    ...(insecure ? { rejectUnauthorized: false } : {})
+  };
+}
+
+async function parseResponse(response, operationName) {
+  const text = await response.text();
+  let body;
+  try {
+    body = JSON.parse(text);
+  } catch (error) {
+    throw new Error(`Management API returned HTTP ${response.status} for ${operationName}: ${text.slice(0, 200)}`);
+  }
+
+  if (body.outcome !== 'success') {
+    const failure = typeof body['failure-description'] === 'string'
+      ? body['failure-description']
+      : JSON.stringify(body['failure-description']);
+    throw new Error(`${operationName} failed: ${failure}`);
+  }
+  return body.result;
+}
+
+function parseDigestChallenge(header) {
+  const challenge = {};
+  for (const match of header.slice('Digest'.length).matchAll(/(\w+)=(?:"([^"]*)"|([^,\s]*))/g)) {
+    challenge[match[1]] = match[2] ?? match[3];
+  }
+  return challenge;
+}
+
+function buildDigestHeader(challenge, method, uri, user, password, nonceCount) {
+  const md5 = value => crypto.createHash('md5').update(value).digest('hex');
+  const nc = nonceCount.toString(16).padStart(8, '0');
+  const cnonce = crypto.randomBytes(8).toString('hex');
+  const ha1 = md5(`${user}:${challenge.realm}:${password}`);
+  const ha2 = md5(`${method}:${uri}`);
+  const qop = challenge.qop?.split(',').map(q => q.trim()).includes('auth') ? 'auth' : null;
+
+  const response = qop
+    ? md5(`${ha1}:${challenge.nonce}:${nc}:${cnonce}:${qop}:${ha2}`)
+    : md5(`${ha1}:${challenge.nonce}:${ha2}`);
+
+  const parts = [
+    `username="${user}"`,
+    `realm="${challenge.realm}"`,
+    `nonce="${challenge.nonce}"`,
+    `uri="${uri}"`,
+    `response="${response}"`,
+    `algorithm=${challenge.algorithm || 'MD5'}`
+  ];
+  if (challenge.opaque) parts.push(`opaque="${challenge.opaque}"`);
+  if (qop) parts.push(`qop=${qop}`, `nc=${nc}`, `cnonce="${cnonce}"`);
+
+  return `Digest ${parts.join(', ')}`;
+}
+
+export {
+  DEFAULT_MANAGEMENT_PORT,
+  createManagementClient,
+  getManagementProtocol
+};
@@ -0,0 +1,165 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import crypto from 'crypto';
+import chalk from 'chalk';
+
+import { requireClientConfig, getClientHosts, getConfigDir } from './config.js';
+import { getWildflyConfig } from './deployer.js';
+import { forEachController } from './controller.js';
+import { runRemote, shellQuote, getSudoPrefix } from './remote.js';
+import { writeKeychain } from './keychain.js';
+import { askSecret } from './confirm.js';
+import { addConfigEntry } from './wizard.js';
+
+const DEFAULT_USER = 'jmw';
+
+// Realm add-user hashes passwords for, unless the users file names its own
+const DEFAULT_REALM = 'ManagementRealm';
+
+// Users files add-user writes, below the WildFly root
+const USERS_FILES = ['standalone/configuration/mgmt-users.properties', 'domain/configuration/mgmt-users.properties'];
+
+/**
+ * Random management password; the letter-digit-symbol tail keeps add-user's
+ * password restrictions quiet
+ */
+function generatePassword() {
+  return `${crypto.randomBytes(15).toString('base64url')}a7!`;
+}
+
+/**
+ * Password typed twice, or generated when nothing is typed
+ */
+async function choosePassword(user) {
+  const password = await askSecret(`Password for ${user} (empty to generate one): `);
+  if (!password) {
+    return generatePassword();
+  }
+  if (await askSecret('Repeat the password: ') !== password) {
+    throw new Error('Passwords do not match');
+  }
+  return password;
+}
+
+/**
+ * Keep the password as a secret and return the reference to put in the config:
+ * the OS keychain, or a file only the user can read where there is no keychain
+ */
+async function storePassword(account, password, store) {
+  const target = store || (['darwin', 'linux'].includes(process.platform) ? 'keychain' : 'file');
+  if (target === 'keychain') {
+    await writeKeychain(account, password);
+    return `\${keychain:${account}}`;
+  }
+  if (target !== 'file') {
+    throw new Error(`Unknown secret store '${target}' (keychain or file)`);
+  }
+
+  const filePath = path.join(getConfigDir(), 'secrets', account.replace(/[^\w.-]+/g, '_'));
+  fs.mkdirSync(path.dirname(filePath), { recursive: true, mode: 0o700 });
+  fs.writeFileSync(filePath, `${password}\n`, { mode: 0o600 });
+  const home = os.homedir();
+  return `\${file:${filePath.startsWith(home + path.sep) ? '~' + filePath.slice(home.length).split(path.sep).join('/') : filePath}}`;
+}
+
+/**
+ * Users file with the user's entry set to its hash, replacing an existing or disabled one
+ */
+function setUserEntry(text, user, hash) {
+  const lines = text.split('\n').filter(line => !line.startsWith(`${user}=`) && !line.startsWith(`#${user}=`));
+  while (lines.length > 0 && lines[lines.length - 1] === '') lines.pop();
+  return `${[...lines, `${user}=${hash}`].join('\n')}\n`;
+}
+
+/**
+ * Add the user to the local WildFly's users files the way add-user does: an MD5 of
+ * user:realm:password. add-user only takes the password as an argument, which would
+ * show it in the process list
+ */
+function addLocalUser(root, user, password) {
+  const files = USERS_FILES.map(file => path.join(root, file)).filter(file => fs.existsSync(file));
+  if (files.length === 0) {
+    throw new Error(`No mgmt-users.properties under ${root}`);
+  }
+  for (const file of files) {
+    const text = fs.readFileSync(file, 'utf8');
+    const realm = text.match(/^#\$REALM_NAME=([^$\n]+)\$/m)?.[1] ?? DEFAULT_REALM;
+    const hash = crypto.createHash('md5').update(`${user}:${realm}:${password}`).digest('hex');
+    fs.writeFileSync(file, setUserEntry(text, user, hash));
+  }
+}
+
+/**
+ * Add the user to a client host's users files, as the user owning WildFly; the
+ * password goes over stdin and is hashed on the host, out of any process list
+ */
+async function addRemoteUser(hostConfig, user, password) {
+  const sudo = getSudoPrefix(hostConfig);
+  const files = USERS_FILES.map(file => shellQuote(`${hostConfig.wildfly_path}/${file}`)).join(' ');
+  const command = [
+    'IFS= read -r JMW_MGMT_PASSWORD',
+    `u=${shellQuote(user)}`,
+    'updated=0',
+    `for f in ${files}; do`,
+    `  current=$(${sudo}cat "$f" 2>/dev/null) || continue`,
+    `  realm=$(printf '%s\\n' "$current" | sed -n 's/^#\\$REALM_NAME=\\([^$]*\\)\\$.*/\\1/p' | head -n 1)`,
+    `  hash=$(printf '%s' "$u:\${realm:-${DEFAULT_REALM}}:$JMW_MGMT_PASSWORD" | md5sum | cut -d ' ' -f 1)`,
+    `  { printf '%s\\n' "$current" | awk -F= -v u="$u" '$1 != u && $1 != "#" u'; printf '%s=%s\\n' "$u" "$hash"; } | ${sudo}tee "$f" > /dev/null || exit 1`,
+    '  updated=1',
+    'done',
+    `[ "$updated" = 1 ] || { echo ${shellQuote(`No mgmt-users.properties under ${hostConfig.wildfly_path}`)} >&2; exit 1; }`
+  ].join('\n');
+  await runRemote(hostConfig, command, `${password}\n`);
+}
+
+/**
+ * Create a management user as add-user does (locally, or on a client's hosts), keep
+ * its password as a secret and point the project's or client's management settings at it
+ */
+async function addManagementUser(detection, user = DEFAULT_USER, options = {}) {
+  const { project, projectConfig } = detection;
+  const clientConfig = options.client ? requireClientConfig(projectConfig, options.client, options.env) : null;
+  const wildflyConfig = getWildflyConfig(projectConfig, clientConfig);
+  if (!clientConfig && !wildflyConfig.root) {
+    throw new Error(`Project ${project} has no wildfly_root`);
+  }
+
+  const password = options.generate ? generatePassword() : await choosePassword(user);
+
+  if (clientConfig) {
+    const added = await forEachController(wildflyConfig, getClientHosts(clientConfig), 'Add Management User', async (controller, hostConfig) => {
+      await addRemoteUser(hostConfig, user, password);
+      console.log(chalk.green(`Management user ${user} added on ${hostConfig.host}`));
+    }, { connect: false });
+    // Running again sets the user on every host, with a new password
+    if (!added) {
+      return false;
+    }
+  } else {
+    addLocalUser(wildflyConfig.root, user, password);
+    console.log(chalk.green(`Management user ${user} added to ${wildflyConfig.root}`));
+  }
+
+  const scope = clientConfig
+    ? [options.client, clientConfig.environment].filter(Boolean).join('.')
+    : project;
+  const reference = await storePassword(`mgmt:${scope}:${user}`, password, options.store);
+  console.log(chalk.green(`Password stored as ${reference}`));
+
+  const keyPath = ['projects', project, ...(clientConfig ? ['clients', options.client] : [])];
+  const environmentPath = clientConfig?.environment ? [...keyPath, 'environments', clientConfig.environment] : null;
+  const value = `{user: ${user}, password: "${reference}"}`;
+  const file = (environmentPath && addConfigEntry(environmentPath, 'management', value)) || addConfigEntry(keyPath, 'management', value);
+  if (file) {
+    console.log(chalk.green(`Added management to ${keyPath.slice(1).join('.')} in ${file}`));
+  } else {
+    console.log(chalk.yellow(`Set the management credentials of ${keyPath.slice(1).join('.')} yourself:`));
+    console.log(`  management: ${value}`);
+  }
+  return true;
+}
+
+export {
+  addManagementUser
+};
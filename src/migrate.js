@@ -0,0 +1,140 @@
+import yaml from 'js-yaml';
+import fs from 'fs';
+import path from 'path';
+import chalk from 'chalk';
+
+import { parseConfigFile } from './config.js';
+import { locateKey } from './schema.js';
+
+// Deprecated fields that have no equivalent; jmw builds and deploys every module
+const UNTRANSLATABLE = {
+  ignored_modules: 'jmw has no ignored modules; remove it (unlisted modules deploy normally)'
+};
+
+/**
+ * Plan the migration of one config file's parsed contents
+ * Returns the projects whose global_modules move to modules (merged when the
+ * project already has modules), and warnings for fields left as they are
+ */
+function planMigration(config) {
+  const renames = [];
+  const warnings = [];
+
+  for (const [name, project] of Object.entries(config?.projects || {})) {
+    if (project.global_modules) {
+      renames.push({ project: name, merge: !!project.modules });
+    }
+    for (const [field, reason] of Object.entries(UNTRANSLATABLE)) {
+      if (project[field] !== undefined) {
+        warnings.push(`projects.${name}.${field}: ${reason}`);
+      }
+    }
+  }
+
+  return { renames, warnings };
+}
+
+/**
+ * Rename global_modules keys in the text of a YAML or TOML file, leaving comments intact
+ * Returns null when the file can't be rewritten textually (both keys in one project)
+ */
+function rewriteText(configPath, text, renames) {
+  if (renames.some(rename => rename.merge)) {
+    return null;
+  }
+
+  if (path.extname(configPath).toLowerCase() === '.toml') {
+    return text
+      .replace(/^(\s*\[\s*projects\.[^\]]*\.)global_modules(\s*\])/gm, '$1modules$2')
+      .replace(/^(\s*)global_modules(\s*[=.])/gm, '$1modules$2');
+  }
+
+  const lines = text.split('\n');
+  for (const { project } of renames) {
+    const location = locateKey(text, ['projects', project, 'global_modules']);
+    if (!location || location.depth !== 3) {
+      return null;
+    }
+    const index = location.line - 1;
+    lines[index] = lines[index].replace('global_modules', 'modules');
+  }
+  return lines.join('\n');
+}
+
+/**
+ * Move global_modules into modules, merging when a project already has both
+ */
+function migrateObject(config, renames) {
+  const projects = { ...config.projects };
+  for (const { project } of renames) {
+    const { global_modules: legacy, ...rest } = projects[project];
+    projects[project] = { ...rest, modules: { ...legacy, ...rest.modules } };
+  }
+  return { ...config, projects };
+}
+
+/**
+ * Rewrite deprecated fields of a config file into their current form
+ * The original is kept next to it as <file>.bak. YAML and TOML are edited in place
+ * to keep comments; JSON, or files needing a merge, are re-serialized
+ */
+function migrateConfigFile(configPath, options = {}) {
+  const text = fs.readFileSync(configPath, 'utf8');
+  const config = parseConfigFile(configPath, text);
+  const { renames, warnings } = planMigration(config);
+
+  if (renames.length === 0) {
+    return { changed: false, renames, warnings };
+  }
+
+  const format = path.extname(configPath).toLowerCase();
+  let migrated = format === '.json' ? null : rewriteText(configPath, text, renames);
+  if (migrated === null) {
+    const object = migrateObject(config, renames);
+    if (format === '.json') {
+      migrated = JSON.stringify(object, null, 2) + '\n';
+    } else if (format === '.toml') {
+      warnings.push('Projects with both modules and global_modules must be merged by hand in TOML files');
+      return { changed: false, renames, warnings };
+    } else {
+      // Merging can't be done line by line; comments are lost in this case
+      migrated = yaml.dump(object, { lineWidth: -1 });
+      warnings.push('Comments could not be preserved while merging modules');
+    }
+  }
+
+  if (!options.dryRun) {
+    fs.copyFileSync(configPath, configPath + '.bak');
+    fs.writeFileSync(configPath, migrated);
+  }
+  return { changed: true, renames, warnings };
+}
+
+/**
+ * Display the outcome of migrating one file
+ */
+function showMigration(configPath, result, options = {}) {
+  console.log(chalk.white.bold(configPath));
+  if (result.renames.length === 0 && result.warnings.length === 0) {
+    console.log(chalk.green('  Up to date'));
+    return;
+  }
+
+  for (const { project, merge } of result.renames) {
+    const action = merge ? 'merged into' : 'renamed to';
+    console.log(`  projects.${project}.global_modules ${action} modules`);
+  }
+  result.warnings.forEach(warning => console.log(chalk.yellow(`  ${warning}`)));
+
+  if (result.changed) {
+    console.log(options.dryRun
+      ? chalk.gray('  Dry run, file not changed')
+      : chalk.green(`  Migrated (original saved as ${path.basename(configPath)}.bak)`));
+  }
+}
+
+export {
+  planMigration,
+  migrateConfigFile,
+  showMigration
+};
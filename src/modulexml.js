@@ -0,0 +1,175 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import { $ } from 'bun';
+
+import { shellQuote } from './remote.js';
+
+/**
+ * Module name and slot from a global module path (e.g. modules/com/acme/ejb/main -> com.acme.ejb, main)
+ */
+function getModuleIdentity(deploymentPath) {
+  const segments = deploymentPath.split('/').filter(Boolean);
+  const start = segments[0] === 'modules' ? 1 : 0;
+  const slot = segments[segments.length - 1];
+  return { name: segments.slice(start, -1).join('.'), slot };
+}
+
+/**
+ * module.xml settings for a global module, keyed by module name in project config
+ * Returns null when module.xml is not managed by jmw for this module
+ */
+function getModuleDefinition(projectConfig, deploymentPath) {
+  const identity = getModuleIdentity(deploymentPath);
+  const definition = projectConfig.module_xml?.[identity.name];
+  if (!definition) {
+    return null;
+  }
+  return { ...identity, dependencies: definition.dependencies || [] };
+}
+
+/**
+ * How global modules are installed: copy (files, plus generated module.xml) or cli (jboss-cli module add)
+ */
+function getModuleInstallMode(projectConfig, clientConfig) {
+  const mode = clientConfig?.module_install || projectConfig.module_install || 'copy';
+  if (!['copy', 'cli'].includes(mode)) {
+    throw new Error(`Invalid module_install '${mode}' (expected copy|cli)`);
+  }
+  return mode;
+}
+
+/**
+ * jboss-cli commands re-creating a module from the given resources
+ * module add refuses existing modules, so the module is removed first
+ */
+function getModuleCliCommands(definition, resources, separator) {
+  const moduleArgs = `--name=${definition.name} --slot=${definition.slot}`;
+  const names = definition.dependencies.map(dep => typeof dep === 'string' ? dep : dep.name);
+  const exported = definition.dependencies.filter(dep => typeof dep === 'object' && dep.export).map(dep => dep.name);
+
+  let add = `module add ${moduleArgs} --resources=${resources.join(separator)}`;
+  if (names.length > 0) add += ` --dependencies=${names.join(',')}`;
+  if (exported.length > 0) add += ` --export-dependencies=${exported.join(',')}`;
+
+  return { remove: `module remove ${moduleArgs}`, add };
+}
+
+/**
+ * Re-create a local module through jboss-cli from the jars in its directory
+ */
+async function installLocalModuleViaCli(cliPath, modulePath, definition) {
+  // module remove deletes the module directory, so work from a copy of its jars
+  const staging = fs.mkdtempSync(path.join(os.tmpdir(), 'jmw-module-'));
+  try {
+    const resources = fs.readdirSync(modulePath).filter(file => file.endsWith('.jar')).sort().map(jar => {
+      fs.copyFileSync(path.join(modulePath, jar), path.join(staging, jar));
+      return path.join(staging, jar);
+    });
+
+    const { remove, add } = getModuleCliCommands(definition, resources, path.delimiter);
+    await $`${cliPath} --command=${remove}`.quiet().nothrow();
+    const output = await $`${cliPath} --command=${add}`.quiet().nothrow();
+    if (output.exitCode !== 0) {
+      throw new Error(`jboss-cli module add failed: ${output.stdout.toString().trim() || output.stderr.toString().trim()}`);
+    }
+  } finally {
+    fs.rmSync(staging, { recursive: true, force: true });
+  }
+}
+
+/**
+ * Remote step re-creating the module through jboss-cli from the jars in its directory
+ * With keepExisting, a module that already has a module.xml is left as it is
+ */
+function planModuleCliOperation(cliPath, modulePath, definition, sudo, keepExisting = false) {
+  const { remove, add } = getModuleCliCommands(definition, ['RESOURCES'], ':');
+  const [addBefore, addAfter] = add.split('RESOURCES');
+  const keep = keepExisting ? `if [ -f ${shellQuote(modulePath)}/module.xml ]; then echo 'module.xml kept'; exit 0; fi; ` : '';
+  const script = keep + 'staging=$(mktemp -d) && ' +
+    `cp ${shellQuote(modulePath)}/*.jar "$staging"/ && ` +
+    `{ ${shellQuote(cliPath)} --command=${shellQuote(remove)} >/dev/null 2>&1; ` +
+    `${shellQuote(cliPath)} --command=${shellQuote(addBefore)}"$(ls "$staging"/*.jar | paste -sd:)"${shellQuote(addAfter)}; ` +
+    'rc=$?; rm -rf "$staging"; exit $rc; }';
+
+  return {
+    type: 'exec',
+    command: `${sudo}sh -c ${shellQuote(script)}`,
+    description: `Install module ${definition.name} via jboss-cli`
+  };
+}
+
+function renderDependency(dependency) {
+  const { name, export: exported, optional } = typeof dependency === 'string' ? { name: dependency } : dependency;
+  const attributes = [`name="${name}"`];
+  if (exported) attributes.push('export="true"');
+  if (optional) attributes.push('optional="true"');
+  return `        <module ${attributes.join(' ')}/>\n`;
+}
+
+function renderResourceRoot(resource) {
+  return `        <resource-root path="${resource}"/>\n`;
+}
+
+/**
+ * module.xml split around the resource roots, so they can be filled in where the jars live
+ */
+function renderModuleXmlParts(definition) {
+  const slot = definition.slot === 'main' ? '' : ` slot="${definition.slot}"`;
+  const head = '<?xml version="1.0" encoding="UTF-8"?>\n' +
+    `<module xmlns="urn:jboss:module:1.9" name="${definition.name}"${slot}>\n` +
+    '    <resources>\n';
+  const dependencies = definition.dependencies.length > 0
+    ? '    <dependencies>\n' + definition.dependencies.map(renderDependency).join('') + '    </dependencies>\n'
+    : '';
+  const tail = '    </resources>\n' + dependencies + '</module>\n';
+  return { head, tail };
+}
+
+/**
+ * Render module.xml with the given resource roots
+ */
+function renderModuleXml(definition, resources) {
+  const { head, tail } = renderModuleXmlParts(definition);
+  return head + resources.map(renderResourceRoot).join('') + tail;
+}
+
+/**
+ * Write module.xml listing every jar in a local module directory
+ */
+function writeLocalModuleXml(modulePath, definition) {
+  const jars = fs.readdirSync(modulePath).filter(file => file.endsWith('.jar')).sort();
+  const moduleXmlPath = path.join(modulePath, 'module.xml');
+  fs.writeFileSync(moduleXmlPath, renderModuleXml(definition, jars));
+  return moduleXmlPath;
+}
+
+/**
+ * Remote step writing module.xml listing every jar in the module directory
+ * The jars are listed when the step runs, so other artifacts sharing the module are kept
+ */
+function planModuleXmlOperation(modulePath, definition, sudo) {
+  const { head, tail } = renderModuleXmlParts(definition);
+  const resourceRoot = renderResourceRoot('%s').replace('\n', '\\n');
+  const script = `cd ${shellQuote(modulePath)} && { printf '%s' ${shellQuote(head)}; ` +
+    `for jar in *.jar; do [ -f "$jar" ] && printf ${shellQuote(resourceRoot)} "$jar"; done; ` +
+    `printf '%s' ${shellQuote(tail)}; } > module.xml.jmw-part && mv -f module.xml.jmw-part module.xml`;
+
+  return {
+    type: 'exec',
+    command: `${sudo}sh -c ${shellQuote(script)}`,
+    description: `Generate module.xml for ${definition.name}`
+  };
+}
+
+export {
+  getModuleIdentity,
+  getModuleDefinition,
+  getModuleInstallMode,
+  getModuleCliCommands,
+  installLocalModuleViaCli,
+  planModuleCliOperation,
+  renderModuleXml,
+  writeLocalModuleXml,
+  planModuleXmlOperation
+};
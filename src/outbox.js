@@ -0,0 +1,210 @@
+import fs from 'fs';
+import path from 'path';
+import crypto from 'crypto';
+import chalk from 'chalk';
+
+import { loadConfig, requireClientConfig, getConfigDir } from './config.js';
+import { runRemote, uploadFile, isReachable } from './remote.js';
+import { emitProgress } from './progress.js';
+import { waitForRemoteDeployment, showDeploymentOutcome } from './scanner.js';
+import { waitForLogResult, readRemoteLog, showLogResult } from './serverlog.js';
+import { checkRemoteTarget } from './preflight.js';
+import { selectUnit } from './detector.js';
+
+/**
+ * Path of the persisted outbox of deferred remote operations
+ */
+function getOutboxPath() {
+  return path.join(getConfigDir(), 'outbox.json');
+}
+
+function readOutbox() {
+  const outboxPath = getOutboxPath();
+  if (!fs.existsSync(outboxPath)) {
+    return [];
+  }
+  return JSON.parse(fs.readFileSync(outboxPath, 'utf8'));
+}
+
+function writeOutbox(operations) {
+  const outboxPath = getOutboxPath();
+  fs.mkdirSync(path.dirname(outboxPath), { recursive: true });
+  fs.writeFileSync(outboxPath, JSON.stringify(operations, null, 2));
+}
+
+/**
+ * Queue operations for a later retry
+ */
+function enqueue(operations) {
+  const queued = operations.map(op => ({
+    id: op.id || crypto.randomUUID(),
+    queuedAt: op.queuedAt || new Date().toISOString(),
+    ...op
+  }));
+  writeOutbox([...readOutbox(), ...queued]);
+  return queued;
+}
+
+/**
+ * Describe an operation for display
+ */
+function describeOperation(op) {
+  switch (op.type) {
+    case 'upload':
+      return `Upload ${path.basename(op.source)} to ${op.client}:${op.dest}`;
+    case 'exec':
+      return op.description || `Run on ${op.client}: ${op.command}`;
+    case 'await_deployment':
+      return `Wait for ${op.artifactName} to be deployed`;
+    case 'preflight':
+      return `Check space and permissions for ${op.dir}`;
+    default:
+      return `Unknown operation ${op.type}`;
+  }
+}
+
+/**
+ * Execute a single remote operation
+ * Operations carry project/unit/client names and are resolved against current config
+ */
+async function runOperation(op, clientConfig) {
+  switch (op.type) {
+    case 'upload':
+      if (!fs.existsSync(op.source)) {
+        throw new Error(`Local artifact no longer exists: ${op.source}`);
+      }
+      await uploadFile(clientConfig, op.source, op.dest);
+      break;
+    case 'exec':
+      await runRemote(clientConfig, op.command);
+      break;
+    case 'preflight':
+      await checkRemoteTarget(clientConfig, op.dir, op.bytes);
+      break;
+    case 'await_deployment': {
+      const outcome = await waitForRemoteDeployment(clientConfig, op.deploymentsPath, op.artifactName, op.timeout);
+      showDeploymentOutcome(op.artifactName, outcome, op.timeout);
+      if (outcome.status !== 'timeout' && op.logPath) {
+        const logResult = await waitForLogResult(() => readRemoteLog(clientConfig, op.logPath, op.artifactName), op.artifactName);
+        showLogResult(op.artifactName, logResult, op.logPath);
+        if (logResult.status === 'failed') {
+          throw new Error(`${op.artifactName} failed according to server.log`);
+        }
+      }
+      if (outcome.status !== 'deployed') {
+        throw new Error(`${op.artifactName} ${outcome.status === 'timeout' ? 'timed out' : outcome.status}`);
+      }
+      break;
+    }
+    default:
+      throw new Error(`Unknown operation type: ${op.type}`);
+  }
+}
+
+/**
+ * Execute operations in order
+ * If the remote becomes unreachable, the failed and remaining operations go to the outbox
+ * Returns true when everything ran, false when operations were deferred
+ */
+async function executeOperations(operations, clientConfig) {
+  for (let i = 0; i < operations.length; i++) {
+    const op = operations[i];
+    console.log(`  ${describeOperation(op)}`);
+    emitProgress('remote', (i / operations.length) * 100, describeOperation(op));
+
+    try {
+      await runOperation(op, clientConfig);
+    } catch (error) {
+      if (await isReachable(clientConfig)) {
+        throw error;
+      }
+
+      const queued = enqueue(operations.slice(i));
+      console.log('');
+      console.log(chalk.yellow(`Remote ${clientConfig.host} is unreachable, ${queued.length} operation(s) queued`));
+      console.log(chalk.yellow('Run `jmw outbox retry` once connectivity returns'));
+      emitProgress('remote', null, 'Remote unreachable, operations queued', { queued: queued.length });
+      return false;
+    }
+  }
+
+  emitProgress('remote', 100, 'Remote operations completed');
+  return true;
+}
+
+/**
+ * Retry queued operations, keeping per-client order
+ * Operations for a client stop at its first failure; others continue
+ */
+async function retryOutbox() {
+  const operations = readOutbox();
+  if (operations.length === 0) {
+    console.log(chalk.green('Outbox is empty'));
+    return true;
+  }
+
+  const config = loadConfig();
+  const remaining = [];
+  const blockedClients = new Set();
+
+  for (const op of operations) {
+    const key = `${op.project}/${op.unit || ''}/${op.client}/${op.env || ''}/${op.host || ''}`;
+    if (blockedClients.has(key)) {
+      remaining.push(op);
+      continue;
+    }
+
+    console.log(`  ${describeOperation(op)}`);
+    try {
+      const projectConfig = config.projects[op.project];
+      if (!projectConfig) {
+        throw new Error(`Project '${op.project}' no longer configured`);
+      }
+      // A unit's clients replace the project's, as when deploying from inside it
+      const clientConfig = requireClientConfig(op.unit ? selectUnit(projectConfig, op.unit) : projectConfig, op.client, op.env);
+      await runOperation(op, op.host ? { ...clientConfig, host: op.host } : clientConfig);
+      console.log(chalk.green('    done'));
+    } catch (error) {
+      console.log(chalk.red(`    failed: ${error.message}`));
+      blockedClients.add(key);
+      remaining.push(op);
+    }
+  }
+
+  writeOutbox(remaining);
+
+  if (remaining.length > 0) {
+    console.log('');
+    console.log(chalk.yellow(`${remaining.length} operation(s) still queued`));
+    return false;
+  }
+  return true;
+}
+
+/**
+ * Display queued operations
+ */
+function showOutbox() {
+  const operations = readOutbox();
+  if (operations.length === 0) {
+    console.log(chalk.green('Outbox is empty'));
+    return;
+  }
+
+  for (const op of operations) {
+    console.log(`  ${chalk.gray(new Date(op.queuedAt).toLocaleString())}  ${chalk.white.bold(`${op.project}${op.unit ? `:${op.unit}` : ''}/${op.client}${op.host ? `@${op.host}` : ''}`)}  ${describeOperation(op)}`);
+  }
+}
+
+function clearOutbox() {
+  writeOutbox([]);
+}
+
+export {
+  enqueue,
+  executeOperations,
+  retryOutbox,
+  showOutbox,
+  clearOutbox,
+  describeOperation
+};
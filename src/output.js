@@ -0,0 +1,109 @@
+import chalk from 'chalk';
+
+// Unicode symbols and their plain ASCII fallbacks
+const SYMBOLS = {
+  arrow: ['→', '->'],
+  differs: ['≠', '!='],
+  check: ['✓', 'OK'],
+  cross: ['✗', 'X']
+};
+
+let plain = false;
+let quiet = false;
+
+// Progress line being drawn: null when none, else the last label shown
+let progress = null;
+
+/**
+ * Configure color and symbol output
+ * Plain mode is used with --plain, NO_COLOR, or when stdout is not a terminal
+ */
+function configureOutput(options = {}) {
+  plain = !!options.plain
+    || (process.env.NO_COLOR !== undefined && process.env.NO_COLOR !== '')
+    || !process.stdout.isTTY;
+
+  if (plain) {
+    chalk.level = 0;
+  }
+}
+
+/**
+ * Get a display symbol, falling back to ASCII in plain mode
+ */
+function symbol(name) {
+  const [fancy, ascii] = SYMBOLS[name];
+  return plain ? ascii : fancy;
+}
+
+function isPlain() {
+  return plain;
+}
+
+/**
+ * Quiet mode (--quiet) keeps Maven to warnings and errors
+ */
+function setQuiet(value) {
+  quiet = !!value;
+}
+
+function isQuiet() {
+  return quiet;
+}
+
+/**
+ * Format file size in human-readable format
+ */
+function formatSize(bytes) {
+  if (bytes < 1024) return `${bytes} B`;
+  if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`;
+  return `${(bytes / (1024 * 1024)).toFixed(1)} MB`;
+}
+
+/**
+ * Render a single-line progress bar, redrawn in place. In plain mode (logs, pipes)
+ * only the first update is printed, and the last one by endProgress
+ */
+function showProgress(current, total, label) {
+  const width = 30;
+  const ratio = total > 0 ? Math.min(current / total, 1) : 1;
+  const filled = Math.round(ratio * width);
+  const bar = '#'.repeat(filled) + '-'.repeat(width - filled);
+  const line = `[${bar}] ${label}`;
+
+  if (plain) {
+    if (progress === null) {
+      console.log(line);
+    }
+    progress = line;
+    return;
+  }
+  progress = line;
+  process.stdout.write(`\r${line}`.padEnd(100).slice(0, 100));
+}
+
+/**
+ * Finish the progress line: move past it, or in plain mode print its last state
+ */
+function endProgress() {
+  if (progress === null) {
+    return;
+  }
+  if (plain) {
+    console.log(progress);
+  } else {
+    process.stdout.write('\n');
+  }
+  progress = null;
+}
+
+export {
+  configureOutput,
+  symbol,
+  isPlain,
+  setQuiet,
+  isQuiet,
+  formatSize,
+  showProgress,
+  endProgress
+};
@@ -0,0 +1,139 @@
+import { loadServerXml } from './serverxml.js';
+import { runRemote } from './remote.js';
+import { DEFAULT_MANAGEMENT_PORT, getManagementProtocol } from './mgmt.js';
+
+const DEFAULT_HTTP_PORT = 8080;
+
+// Detected ports per install, a detection costs a ps and a config read (over SSH for clients)
+const detected = new Map();
+
+/**
+ * -D system properties and server config file of the running WildFly with this home,
+ * from the command lines of the running processes: {properties, serverConfig}, or null
+ */
+function parseServerArgs(commandLines, root) {
+  const home = root.replace(/\/+$/, '');
+  // jboss-cli runs from the same home
+  const line = commandLines.find(candidate => !candidate.includes('org.jboss.as.cli') &&
+    (candidate.includes(`-Djboss.home.dir=${home} `) || candidate.endsWith(`-Djboss.home.dir=${home}`)));
+  if (!line) {
+    return null;
+  }
+
+  const properties = Object.fromEntries([...line.matchAll(/(?:^|\s)-D([^=\s]+)=(\S*)/g)].map(([, name, value]) => [name, value]));
+  const serverConfig = line.match(/(?:^|\s)(?:-c|--server-config)[= ](\S+)/)?.[1] ?? null;
+  return { properties, serverConfig };
+}
+
+/**
+ * Command lines of the processes running locally or on a client host
+ */
+async function readCommandLines(hostConfig) {
+  if (hostConfig) {
+    return (await runRemote(hostConfig, 'ps -eo args')).split('\n');
+  }
+  if (process.platform === 'win32') {
+    return [];
+  }
+  const result = Bun.spawnSync(['ps', '-eo', 'args'], { stdout: 'pipe', stderr: 'ignore' });
+  return result.exitCode === 0 ? result.stdout.toString().split('\n') : [];
+}
+
+/**
+ * -D system properties and server config of the running WildFly of the local install
+ * or a client host's, or null when it isn't running
+ */
+async function findServerArgs(wildflyConfig, hostConfig = null) {
+  const root = hostConfig ? hostConfig.wildfly_path : wildflyConfig.root;
+  return root ? parseServerArgs(await readCommandLines(hostConfig), root) : null;
+}
+
+/**
+ * Management and HTTP ports of the local install or a client host's, port offsets
+ * included: read from the server config with the system properties of the running
+ * server, if any. Returns {management, http, https}, or null when the config can't be read
+ */
+async function detectPorts(wildflyConfig, hostConfig = null) {
+  const root = hostConfig ? hostConfig.wildfly_path : wildflyConfig.root;
+  if (!root) {
+    return null;
+  }
+  const key = hostConfig ? `${hostConfig.host}:${root}` : root;
+  if (detected.has(key)) {
+    return detected.get(key);
+  }
+
+  let ports = null;
+  try {
+    const args = await findServerArgs(wildflyConfig, hostConfig);
+    const serverConfig = args?.serverConfig ?? wildflyConfig.serverConfig;
+    const { config, host } = await loadServerXml({ ...wildflyConfig, serverConfig }, hostConfig, args?.properties);
+
+    if (wildflyConfig.mode === 'domain') {
+      // HTTP ports of the first server of the group: its group's bindings plus its own offset
+      const server = host?.servers.find(candidate => !wildflyConfig.serverGroup || candidate.group === wildflyConfig.serverGroup);
+      const group = config.serverGroups.find(candidate => candidate.name === server?.group);
+      const bindings = config.socketBindingGroups[group?.socketBindingGroup] || {};
+      const offset = server?.offset ?? 0;
+      ports = {
+        management: host?.managementPort ?? null,
+        http: bindings.http === null || bindings.http === undefined ? null : bindings.http + offset,
+        https: bindings.https === null || bindings.https === undefined ? null : bindings.https + offset
+      };
+    } else {
+      const secure = getManagementProtocol(wildflyConfig.management) === 'https';
+      ports = {
+        management: secure ? config.ports.managementHttps : config.ports.managementHttp,
+        http: config.ports.http,
+        https: config.ports.https
+      };
+    }
+  } catch (error) {
+    ports = null;
+  }
+  detected.set(key, ports);
+  return ports;
+}
+
+/**
+ * Management config with the detected port filled in when none is configured and
+ * the server doesn't listen on the default one. Client hosts reached over HTTP
+ * alone may have no SSH access, so only those used through jboss-cli are looked at
+ */
+async function resolveManagementPort(management, wildflyConfig, hostConfig = null) {
+  if (management?.port || (hostConfig && management?.user)) {
+    return management;
+  }
+  const ports = await detectPorts(wildflyConfig, hostConfig);
+  if (!ports?.management || ports.management === DEFAULT_MANAGEMENT_PORT) {
+    return management;
+  }
+  return { ...(management || {}), port: ports.management };
+}
+
+/**
+ * Health check and warm-up settings with path-only URLs (/app/health) made absolute
+ * against the server's detected HTTP port
+ */
+async function resolveVerifyConfig(config, wildflyConfig, hostConfig = null) {
+  const urls = [config.health_check?.url, ...(config.warmup?.urls || [])];
+  if (!urls.some(url => url?.startsWith('/'))) {
+    return config;
+  }
+
+  const ports = await detectPorts(wildflyConfig, hostConfig);
+  const base = `http://${hostConfig?.host || 'localhost'}:${ports?.http ?? DEFAULT_HTTP_PORT}`;
+  const absolute = url => url?.startsWith('/') ? base + url : url;
+  return {
+    ...config,
+    ...(config.health_check ? { health_check: { ...config.health_check, url: absolute(config.health_check.url) } } : {}),
+    ...(config.warmup ? { warmup: { ...config.warmup, urls: (config.warmup.urls || []).map(absolute) } } : {})
+  };
+}
+
+export {
+  findServerArgs,
+  detectPorts,
+  resolveManagementPort,
+  resolveVerifyConfig
+};
@@ -0,0 +1,47 @@
+import { runRemote, shellQuote, getSudoPrefix } from './remote.js';
+import { formatSize } from './output.js';
+
+// Room for the temporary .jmw-part file next to the backup of the replaced artifact
+const SPACE_FACTOR = 2;
+
+/**
+ * Check that a remote directory (or its nearest existing parent) is writable
+ * and its filesystem has room for the upload; throws with a clear message otherwise
+ */
+async function checkRemoteTarget(clientConfig, dir, bytes) {
+  const sudo = getSudoPrefix(clientConfig);
+  const script = [
+    `d=${shellQuote(dir)}`,
+    'while [ ! -d "$d" ]; do d=$(dirname "$d"); done',
+    'echo "DIR:$d"',
+    'echo "AVAIL:$(df -Pk "$d" | awk \'NR==2 {print $4}\')"',
+    `if ${sudo}test -w "$d"; then echo WRITABLE:yes; else echo WRITABLE:no; fi`
+  ].join('\n');
+
+  const output = await runRemote(clientConfig, script);
+  const value = key => output.match(new RegExp(`^${key}:(.*)$`, 'm'))?.[1];
+
+  const existing = value('DIR');
+  if (value('WRITABLE') !== 'yes') {
+    const as = clientConfig.sudo_user || (sudo ? 'root' : clientConfig.user);
+    throw new Error(`${existing} is not writable by ${as} on ${clientConfig.host}`);
+  }
+
+  const available = Number(value('AVAIL')) * 1024;
+  const required = bytes * SPACE_FACTOR;
+  if (Number.isFinite(available) && available < required) {
+    throw new Error(`Not enough space on ${clientConfig.host} for ${dir}: ${formatSize(available)} free, ${formatSize(required)} needed`);
+  }
+}
+
+/**
+ * Remote step running the checks before any upload
+ */
+function planPreflightOperation(dir, bytes) {
+  return { type: 'preflight', dir, bytes };
+}
+
+export {
+  checkRemoteTarget,
+  planPreflightOperation
+};
@@ -0,0 +1,165 @@
+import { spawn, spawnSync } from 'child_process';
+import readline from 'readline';
+import chalk from 'chalk';
+
+// Exit codes follow the shell convention of 128 + signal number
+const EXIT_CANCELLED = 130;
+const EXIT_TERMINATED = 143;
+
+// Grace period before escalating from SIGTERM to SIGKILL
+const KILL_GRACE_MS = 5000;
+
+const WINDOWS = process.platform === 'win32';
+
+// Characters cmd.exe treats specially, escaped with ^
+const CMD_META = /([()\][%!^"`<>&|;, *?])/g;
+
+const controller = new AbortController();
+const cleanups = new Set();
+
+/**
+ * Install SIGINT/SIGTERM handlers that cancel running work
+ * A second signal while cleaning up exits immediately
+ */
+function installSignalHandlers() {
+  process.on('SIGINT', () => cancel('SIGINT'));
+  process.on('SIGTERM', () => cancel('SIGTERM'));
+}
+
+/**
+ * Abort the shared cancellation signal, run cleanups (newest first) and exit
+ */
+async function cancel(signalName) {
+  const exitCode = signalName === 'SIGTERM' ? EXIT_TERMINATED : EXIT_CANCELLED;
+
+  if (controller.signal.aborted) {
+    process.exit(exitCode);
+  }
+
+  console.error(chalk.yellow(`\nReceived ${signalName}, cancelling...`));
+  controller.abort();
+
+  for (const cleanup of [...cleanups].reverse()) {
+    try {
+      await cleanup();
+    } catch (error) {
+      console.error(chalk.yellow(`Cleanup failed: ${error.message}`));
+    }
+  }
+
+  process.exit(exitCode);
+}
+
+/**
+ * Register a cleanup to run on cancellation, returns a function that unregisters it
+ */
+function onCancel(cleanup) {
+  cleanups.add(cleanup);
+  return () => cleanups.delete(cleanup);
+}
+
+/**
+ * Shared cancellation signal for long-running operations
+ */
+function getCancelSignal() {
+  return controller.signal;
+}
+
+/**
+ * Quote an argument for a .cmd/.bat script run through cmd.exe: quoted for the
+ * script's own parsing, then its metacharacters escaped twice, once for cmd /c and
+ * once more because cmd expands them again when running the batch file
+ */
+function quoteBatchArg(arg) {
+  const quoted = `"${String(arg).replace(/(\\*)"/g, '$1$1\\"').replace(/(\\*)$/, '$1$1')}"`;
+  return quoted.replace(CMD_META, '^$1').replace(CMD_META, '^$1');
+}
+
+/**
+ * [command, args, options] for spawn; .cmd/.bat scripts such as mvn.cmd only run
+ * through cmd.exe, which gets one command line with every argument quoted, so
+ * paths with spaces and values such as -Dx=a&b arrive as they were given
+ */
+function getSpawnArgs(command, args) {
+  if (!WINDOWS || !/\.(cmd|bat)$/i.test(command)) {
+    return [command, args, {}];
+  }
+  const line = [command.replace(CMD_META, '^$1'), ...args.map(quoteBatchArg)].join(' ');
+  return [process.env.comspec || 'cmd.exe', ['/d', '/s', '/c', `"${line}"`], { windowsVerbatimArguments: true }];
+}
+
+/**
+ * Run a command in its own process group, streaming output to the terminal
+ * On cancellation the whole group is killed, so forked JVMs don't linger
+ * With options.onLine, stdout is captured and passed line by line instead
+ */
+function runCommand(command, args, options = {}) {
+  return new Promise((resolve, reject) => {
+    // Windows has no process groups (detached opens a console instead)
+    const [file, fileArgs, spawnOptions] = getSpawnArgs(command, args);
+    const child = spawn(file, fileArgs, {
+      ...spawnOptions,
+      cwd: options.cwd,
+      env: options.env || process.env,
+      stdio: options.onLine ? ['inherit', 'pipe', 'inherit'] : 'inherit',
+      detached: !WINDOWS
+    });
+
+    if (options.onLine) {
+      readline.createInterface({ input: child.stdout }).on('line', options.onLine);
+    }
+
+    const exited = new Promise(done => child.once('close', done));
+
+    const unregister = onCancel(async () => {
+      killGroup(child.pid, 'SIGTERM');
+      const timer = setTimeout(() => killGroup(child.pid, 'SIGKILL'), KILL_GRACE_MS);
+      await exited;
+      clearTimeout(timer);
+    });
+
+    child.once('error', error => {
+      unregister();
+      reject(error);
+    });
+
+    child.once('close', (code, signal) => {
+      unregister();
+      if (controller.signal.aborted) {
+        // Leave the promise pending; cancel() owns the exit code
+        return;
+      }
+      if (code === 0) {
+        resolve();
+      } else {
+        reject(new Error(`${command} exited with ${signal ? `signal ${signal}` : `code ${code}`}`));
+      }
+    });
+  });
+}
+
+/**
+ * Send a signal to every process in a process group
+ */
+function killGroup(pid, signalName) {
+  try {
+    if (WINDOWS) {
+      // taskkill /T takes the process tree down, the closest thing to a group
+      spawnSync('taskkill', ['/pid', String(pid), '/T', ...(signalName === 'SIGKILL' ? ['/F'] : [])], { stdio: 'ignore' });
+      return;
+    }
+    process.kill(-pid, signalName);
+  } catch (error) {
+    // Group already gone
+  }
+}
+
+export {
+  EXIT_CANCELLED,
+  EXIT_TERMINATED,
+  installSignalHandlers,
+  onCancel,
+  getCancelSignal,
+  getSpawnArgs,
+  runCommand
+};
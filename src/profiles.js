@@ -0,0 +1,251 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import { $ } from 'bun';
+import chalk from 'chalk';
+
+import { asArray, parsePom, readPomChain, getReactorModules } from './detector.js';
+import { getMavenSettings } from './builder.js';
+
+/**
+ * List profiles declared in a POM with their activation rules
+ */
+function getDeclaredProfiles(pom) {
+  return asArray(pom.project?.profiles?.profile).map(profile => ({
+    id: String(profile.id),
+    activation: profile.activation || null
+  }));
+}
+
+/**
+ * Describe the local machine the way Maven's profile activators see it
+ */
+async function getMachineContext(modulePath, properties = {}) {
+  return {
+    jdkVersion: await getJdkVersion(),
+    os: {
+      name: { linux: 'linux', darwin: 'mac os x', win32: 'windows' }[process.platform] || process.platform,
+      family: process.platform === 'win32' ? 'windows' : (process.platform === 'darwin' ? 'mac' : 'unix'),
+      arch: { x64: 'amd64', arm64: 'aarch64', ia32: 'x86' }[os.arch()] || os.arch()
+    },
+    properties,
+    basedir: modulePath
+  };
+}
+
+/**
+ * Get the active JDK version from `java -version`, using JAVA_HOME like Maven does
+ */
+async function getJdkVersion() {
+  try {
+    const java = process.env.JAVA_HOME ? path.join(process.env.JAVA_HOME, 'bin', 'java') : 'java';
+    const result = await $`${java} -version`.quiet().nothrow();
+    const match = result.stderr.toString().match(/version "([^"]+)"/);
+    return match ? match[1] : null;
+  } catch (error) {
+    return null;
+  }
+}
+
+/**
+ * Evaluate a profile's <activation> block against the machine context
+ * Returns null when the profile has no automatic activation
+ */
+function evaluateActivation(activation, context) {
+  if (!activation) return null;
+
+  if (activation.activeByDefault === true || activation.activeByDefault === 'true') {
+    return { active: true, reason: 'activeByDefault', byDefault: true };
+  }
+
+  // All specified conditions must match (Maven 3.2.2+ semantics)
+  const checks = [];
+
+  if (activation.jdk !== undefined) {
+    const matched = matchJdk(String(activation.jdk), context.jdkVersion);
+    checks.push({ matched, reason: `jdk ${activation.jdk} (have ${context.jdkVersion || 'unknown'})` });
+  }
+
+  if (activation.os) {
+    const matched = ['name', 'family', 'arch'].every(key =>
+      activation.os[key] === undefined || matchNegatable(String(activation.os[key]).toLowerCase(), context.os[key]));
+    checks.push({ matched, reason: `os ${JSON.stringify(activation.os)}` });
+  }
+
+  if (activation.property) {
+    checks.push({ matched: matchProperty(activation.property, context.properties), reason: `property ${formatProperty(activation.property)}` });
+  }
+
+  if (activation.file) {
+    checks.push(matchFile(activation.file, context.basedir));
+  }
+
+  if (checks.length === 0) return null;
+
+  return {
+    active: checks.every(c => c.matched),
+    reason: checks.map(c => c.reason).join(', ')
+  };
+}
+
+/**
+ * Match JDK version prefix ("1.8", "!11") or range ("[1.8,11)")
+ */
+function matchJdk(spec, version) {
+  if (!version) return false;
+
+  if (spec.startsWith('[') || spec.startsWith('(')) {
+    const [low, high] = spec.slice(1, -1).split(',').map(v => v.trim());
+    const cmpLow = low ? compareVersions(version, low) : 1;
+    const cmpHigh = high ? compareVersions(version, high) : -1;
+    const lowOk = spec.startsWith('[') ? cmpLow >= 0 : cmpLow > 0;
+    const highOk = spec.endsWith(']') ? cmpHigh <= 0 : cmpHigh < 0;
+    return lowOk && highOk;
+  }
+
+  return matchNegatable(spec, version, (expected, actual) => actual.startsWith(expected));
+}
+
+function matchNegatable(spec, actual, compare = (expected, value) => expected === value) {
+  if (spec.startsWith('!')) {
+    return !compare(spec.slice(1), actual);
+  }
+  return compare(spec, actual);
+}
+
+function matchProperty(property, properties) {
+  const name = String(property.name);
+  const negatedName = name.startsWith('!');
+  const value = properties[negatedName ? name.slice(1) : name];
+
+  if (negatedName) return value === undefined;
+  if (property.value === undefined) return value !== undefined;
+  return matchNegatable(String(property.value), value);
+}
+
+function matchFile(file, basedir) {
+  const resolve = p => path.resolve(basedir, String(p).replace(/\$\{(project\.)?basedir\}/g, basedir));
+
+  if (file.exists !== undefined) {
+    return { matched: fs.existsSync(resolve(file.exists)), reason: `file exists ${file.exists}` };
+  }
+  return { matched: !fs.existsSync(resolve(file.missing)), reason: `file missing ${file.missing}` };
+}
+
+function formatProperty(property) {
+  return property.value === undefined ? String(property.name) : `${property.name}=${property.value}`;
+}
+
+function compareVersions(a, b) {
+  const pa = a.split(/[._-]/).map(n => parseInt(n, 10) || 0);
+  const pb = b.split(/[._-]/).map(n => parseInt(n, 10) || 0);
+  for (let i = 0; i < Math.max(pa.length, pb.length); i++) {
+    const diff = (pa[i] || 0) - (pb[i] || 0);
+    if (diff !== 0) return diff;
+  }
+  return 0;
+}
+
+/**
+ * Work out which profiles Maven will actually activate for a requested -P list
+ * Requested entries may be negated ("!PROD") to force deactivation
+ */
+function resolveActiveProfiles(pom, requested, context) {
+  const declared = getDeclaredProfiles(pom);
+  const explicitOn = requested.filter(p => !p.startsWith('!'));
+  const explicitOff = requested.filter(p => p.startsWith('!')).map(p => p.slice(1));
+
+  // activeByDefault profiles are switched off when any profile of this POM is requested explicitly
+  const anyExplicit = declared.some(p => explicitOn.includes(p.id));
+
+  const profiles = declared.map(profile => {
+    const evaluation = evaluateActivation(profile.activation, context);
+
+    if (explicitOff.includes(profile.id)) {
+      return { id: profile.id, active: false, source: 'deactivated', reason: 'explicitly disabled' };
+    }
+    if (explicitOn.includes(profile.id)) {
+      return { id: profile.id, active: true, source: 'requested', reason: 'requested with -P' };
+    }
+    if (evaluation?.byDefault) {
+      return anyExplicit
+        ? { id: profile.id, active: false, source: 'default', reason: 'activeByDefault overridden by -P' }
+        : { id: profile.id, active: true, source: 'default', reason: 'activeByDefault' };
+    }
+    if (evaluation) {
+      return { id: profile.id, active: evaluation.active, source: 'auto', reason: evaluation.reason };
+    }
+    return { id: profile.id, active: false, source: 'manual', reason: 'not requested' };
+  });
+
+  const warnings = [];
+  for (const profile of profiles) {
+    if (profile.source === 'auto' && profile.active) {
+      warnings.push(`Profile ${profile.id} is auto-activated on this machine (${profile.reason})`);
+    }
+  }
+  for (const id of explicitOn) {
+    if (!declared.some(p => p.id === id)) {
+      warnings.push(`Requested profile ${id} is not declared in this POM`);
+    }
+  }
+
+  return { profiles, warnings };
+}
+
+/**
+ * Requested profiles that are defined nowhere Maven looks: the module's POM and its
+ * parents, the other modules of the reactor (-P applies to all of them) and
+ * the settings.xml files (maven_settings or ~/.m2/settings.xml, and global_settings).
+ * Returns them with the ids that do exist
+ */
+function findUndefinedProfiles(detection, requested) {
+  const { pomPath, projectConfig, module: moduleInfo } = detection;
+  const poms = readPomChain(pomPath, parsePom(pomPath));
+  if (moduleInfo.isMultiModule) {
+    for (const module of getReactorModules(path.join(moduleInfo.reactorRoot, 'pom.xml'))) {
+      poms.push(parsePom(path.join(module.path, 'pom.xml')));
+    }
+  }
+
+  const known = new Set(poms.flatMap(pom => getDeclaredProfiles(pom).map(profile => profile.id)));
+  const { settings, globalSettings } = getMavenSettings(projectConfig);
+  for (const settingsPath of [settings ?? path.join(os.homedir(), '.m2', 'settings.xml'), globalSettings]) {
+    if (settingsPath && fs.existsSync(settingsPath)) {
+      asArray(parsePom(settingsPath).settings?.profiles?.profile).forEach(profile => known.add(String(profile.id)));
+    }
+  }
+
+  const missing = requested
+    .map(id => id.replace(/^[!-]/, ''))
+    .filter(id => !known.has(id));
+  return { missing, known: [...known] };
+}
+
+/**
+ * Display resolved profiles and warnings
+ */
+function showProfiles(resolution) {
+  if (resolution.profiles.length === 0) {
+    console.log('No profiles declared in pom.xml');
+  }
+
+  for (const profile of resolution.profiles) {
+    const state = profile.active ? chalk.green('active  ') : chalk.gray('inactive');
+    console.log(`  ${state} ${chalk.white.bold(profile.id)} ${chalk.gray(`(${profile.reason})`)}`);
+  }
+
+  for (const warning of resolution.warnings) {
+    console.log(chalk.yellow(`  Warning: ${warning}`));
+  }
+}
+
+export {
+  getDeclaredProfiles,
+  getMachineContext,
+  getJdkVersion,
+  evaluateActivation,
+  resolveActiveProfiles,
+  findUndefinedProfiles,
+  showProfiles
+};
@@ -0,0 +1,41 @@
+import fs from 'fs';
+import net from 'net';
+
+let sink = null;
+
+/**
+ * Open the progress side channel from --progress-fd or --progress-socket
+ */
+function configureProgress(options = {}) {
+  if (options.progressFd !== undefined) {
+    sink = fs.createWriteStream(null, { fd: Number(options.progressFd), autoClose: false });
+  } else if (options.progressSocket) {
+    sink = net.createConnection(options.progressSocket);
+    // A GUI going away must not abort the build
+    sink.on('error', () => {
+      sink = null;
+    });
+  }
+}
+
+/**
+ * Emit a progress event as one JSON line
+ * percent is 0-100, or null when the stage has no measurable progress
+ */
+function emitProgress(stage, percent, message, extra = {}) {
+  if (!sink) return;
+
+  const event = {
+    time: new Date().toISOString(),
+    stage,
+    percent: percent === null ? null : Math.round(Math.max(0, Math.min(100, percent))),
+    message,
+    ...extra
+  };
+  sink.write(JSON.stringify(event) + '\n');
+}
+
+export {
+  configureProgress,
+  emitProgress
+};
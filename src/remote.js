@@ -0,0 +1,291 @@
+import fs from 'fs';
+import path from 'path';
+import zlib from 'zlib';
+import { spawn } from 'child_process';
+
+import { getDestination, sshExec, sshTry, sshSpawn, ensureSession, isAuthFailure } from './ssh.js';
+import { onCancel } from './process.js';
+import { sha256File } from './history.js';
+import { formatSize, showProgress, endProgress } from './output.js';
+import { emitProgress } from './progress.js';
+import { askSecret } from './confirm.js';
+import { resolveSecret } from './secrets.js';
+
+// ssh exits with 255 when the connection itself fails
+const SSH_CONNECTION_ERROR = 255;
+
+// Stream compression for uploads: local compressor and the remote command undoing it
+const COMPRESSIONS = {
+  gzip: { decompress: 'gzip -dc', compressor: () => zlib.createGzip() },
+  zstd: { decompress: 'zstd -dcq', compressor: () => spawnCompressor('zstd', ['-c', '-q', '-T0']) }
+};
+
+// Reads the sudo password from the first stdin line and serves it to `sudo -A`
+// through a temporary askpass helper, keeping it out of the remote argv
+const ASKPASS_SETUP = [
+  'IFS= read -r JMW_SUDO_PASSWORD; export JMW_SUDO_PASSWORD',
+  'SUDO_ASKPASS=$(mktemp); export SUDO_ASKPASS',
+  'trap \'rm -f "$SUDO_ASKPASS"\' EXIT',
+  'printf \'#!/bin/sh\\nprintf "%%s\\\\n" "$JMW_SUDO_PASSWORD"\\n\' > "$SUDO_ASKPASS"; chmod 700 "$SUDO_ASKPASS"'
+].join('\n');
+
+// Prompted sudo passwords, asked once per user@host and run
+const sudoPasswords = new Map();
+
+/**
+ * Whether commands touching WildFly files go through sudo
+ * Defaults to sudo for non-root users; setting sudo_user implies sudo
+ */
+function usesSudo(clientConfig) {
+  return clientConfig.use_sudo ?? (!!clientConfig.sudo_user || clientConfig.user !== 'root');
+}
+
+/**
+ * Prefix for commands on WildFly files, run as sudo_user when configured
+ * Interactive prefixes are meant for commands shown to the user
+ */
+function getSudoPrefix(clientConfig, { interactive = false } = {}) {
+  const root = getRootPrefix(clientConfig, { interactive });
+  return root && clientConfig.sudo_user ? `${root}-u ${clientConfig.sudo_user} ` : root;
+}
+
+/**
+ * Prefix for commands that need root, such as service restarts
+ */
+function getRootPrefix(clientConfig, { interactive = false } = {}) {
+  if (!usesSudo(clientConfig)) {
+    return '';
+  }
+  return clientConfig.sudo_password && !interactive ? 'sudo -A ' : 'sudo ';
+}
+
+/**
+ * The client's restart command, run as root
+ */
+function getRestartCommand(clientConfig, options = {}) {
+  const root = getRootPrefix(clientConfig, options);
+  return root ? `${root}sh -c ${shellQuote(clientConfig.restart_cmd)}` : clientConfig.restart_cmd;
+}
+
+/**
+ * sudo password from config (or a secret reference), or prompted when set to "prompt"
+ */
+async function getSudoPassword(clientConfig) {
+  if (clientConfig.sudo_password !== 'prompt') {
+    return String(await resolveSecret(clientConfig.sudo_password, 'sudo_password'));
+  }
+
+  const key = `${clientConfig.user}@${clientConfig.host}`;
+  if (!sudoPasswords.has(key)) {
+    sudoPasswords.set(key, await askSecret(`sudo password for ${key}: `));
+  }
+  return sudoPasswords.get(key);
+}
+
+/**
+ * Add the askpass setup to a command when sudo needs a password
+ * Returns the command and its stdin: the password line, then the command's own input
+ */
+async function prepareCommand(clientConfig, command, input = null) {
+  if (!clientConfig.sudo_password || !usesSudo(clientConfig)) {
+    return { command, input };
+  }
+  return {
+    command: `${ASKPASS_SETUP}\n${command}`,
+    input: `${await getSudoPassword(clientConfig)}\n${input ?? ''}`
+  };
+}
+
+/**
+ * Run a command on a client host over SSH and return its stdout
+ * Input (secrets the command reads) is sent on stdin, keeping it out of the remote argv
+ */
+async function runRemote(clientConfig, command, input = null) {
+  const prepared = await prepareCommand(clientConfig, command, input);
+  return await sshExec(clientConfig, prepared.command, prepared.input);
+}
+
+/**
+ * Run a command on a client host with its output streamed to the terminal
+ * Resolves with the remote exit code
+ */
+async function streamRemote(clientConfig, command, input = null) {
+  const prepared = await prepareCommand(clientConfig, command, input);
+  await ensureSession(clientConfig);
+
+  return new Promise((resolve, reject) => {
+    const child = sshSpawn(clientConfig, prepared.command, [prepared.input ? 'pipe' : 'ignore', 'inherit', 'inherit']);
+    if (prepared.input) {
+      child.stdin.end(prepared.input);
+    }
+    child.once('error', reject);
+    child.once('close', code => resolve(code));
+  });
+}
+
+/**
+ * Quote a value for the remote POSIX shell
+ */
+function shellQuote(value) {
+  return `'${String(value).replace(/'/g, `'\\''`)}'`;
+}
+
+/**
+ * Upload compression configured for a client (none, gzip or zstd)
+ */
+function getCompression(clientConfig) {
+  const compression = clientConfig.compression || 'none';
+  if (compression !== 'none' && !COMPRESSIONS[compression]) {
+    throw new Error(`Invalid compression '${compression}' (expected none|${Object.keys(COMPRESSIONS).join('|')})`);
+  }
+  return compression;
+}
+
+/**
+ * Run a local compressor process as a stream
+ * Its failures surface on stdout; writes into a stdin that went away are ignored
+ */
+function spawnCompressor(command, args) {
+  if (!Bun.which(command)) {
+    throw new Error(`${command} not available locally; install it or set compression: none`);
+  }
+  const child = spawn(command, args, { stdio: ['pipe', 'pipe', 'ignore'] });
+  child.stdin.on('error', () => {});
+  child.stdout.on('error', () => {});
+  child.once('error', error => {
+    child.stdout.destroy(new Error(`${command} not available locally: ${error.message}`));
+  });
+  return { input: child.stdin, output: child.stdout };
+}
+
+/**
+ * Upload a file by streaming it over SSH with a progress bar
+ * Data lands in a temporary .jmw-part file that is renamed only after the
+ * SHA-256 of the remote copy matches the local file
+ */
+async function uploadFile(clientConfig, source, dest) {
+  const size = fs.statSync(source).size;
+  const partPath = `${dest}.jmw-part`;
+  const localSha = await sha256File(source);
+  const sudo = getSudoPrefix(clientConfig);
+
+  // Remove the partial file if the user cancels mid-transfer
+  const unregister = onCancel(async () => {
+    await runRemote(clientConfig, `${sudo}rm -f ${shellQuote(partPath)}`).catch(() => {});
+  });
+
+  try {
+    // Written as sudo_user (if any) so the file gets the WildFly user's ownership
+    const write = sudo ? `${sudo}sh -c ${shellQuote(`cat > ${shellQuote(partPath)}`)}` : `cat > ${shellQuote(partPath)}`;
+    await streamToRemote(clientConfig, source, write, size, getCompression(clientConfig));
+
+    const output = await runRemote(clientConfig, `${sudo}sha256sum ${shellQuote(partPath)}`);
+    const remoteSha = output.trim().split(/\s+/)[0];
+    if (remoteSha !== localSha) {
+      await runRemote(clientConfig, `${sudo}rm -f ${shellQuote(partPath)}`);
+      throw new Error(`Checksum mismatch after upload (local ${localSha}, remote ${remoteSha})`);
+    }
+
+    await runRemote(clientConfig, `${sudo}mv -f ${shellQuote(partPath)} ${shellQuote(dest)}`);
+    return localSha;
+  } finally {
+    unregister();
+  }
+}
+
+/**
+ * Pipe a local file into a remote command's stdin, showing progress and rate
+ * With compression the stream is compressed locally and decompressed on the remote
+ */
+async function streamToRemote(clientConfig, source, remoteCommand, size, compression = 'none') {
+  const codec = COMPRESSIONS[compression];
+  const prepared = await prepareCommand(clientConfig, codec ? `${codec.decompress} | ${remoteCommand}` : remoteCommand);
+  await ensureSession(clientConfig);
+  // Started before ssh, so a missing compressor fails before anything is sent
+  const compressor = codec ? normalizeCompressor(codec.compressor()) : null;
+
+  return new Promise((resolve, reject) => {
+    const child = sshSpawn(clientConfig, prepared.command, ['pipe', 'ignore', 'pipe']);
+    if (prepared.input) {
+      child.stdin.write(prepared.input);
+    }
+
+    const start = Date.now();
+    let sent = 0;
+    let wire = 0;
+    let stderr = '';
+
+    const input = fs.createReadStream(source);
+    input.on('data', chunk => {
+      sent += chunk.length;
+      const seconds = Math.max((Date.now() - start) / 1000, 0.001);
+      const compressed = codec ? `  (${formatSize(wire)} ${compression})` : '';
+      showProgress(sent, size, `${formatSize(sent)} / ${formatSize(size)}  ${formatSize(sent / seconds)}/s${compressed}`);
+      emitProgress('upload', size > 0 ? (sent / size) * 100 : 100, `Uploading ${path.basename(source)}`, {
+        bytes: sent,
+        total: size,
+        rate: Math.round(sent / seconds),
+        ...(codec ? { compressed: wire } : {})
+      });
+    });
+
+    if (compressor) {
+      const { input: compressIn, output: compressOut } = compressor;
+      compressOut.on('data', chunk => {
+        wire += chunk.length;
+      });
+      compressOut.once('error', error => {
+        child.kill();
+        reject(error);
+      });
+      input.pipe(compressIn);
+      compressOut.pipe(child.stdin);
+    } else {
+      input.pipe(child.stdin);
+    }
+
+    child.stderr.on('data', chunk => {
+      stderr += chunk;
+    });
+
+    child.once('error', reject);
+    child.once('close', code => {
+      endProgress();
+      if (code === 0) {
+        resolve();
+      } else {
+        reject(new Error(`Upload failed: ${stderr.trim() || `ssh exited with code ${code}`}`));
+      }
+    });
+  });
+}
+
+/**
+ * zlib streams are a single duplex; process compressors have separate ends
+ */
+function normalizeCompressor(compressor) {
+  return compressor.input ? compressor : { input: compressor, output: compressor };
+}
+
+/**
+ * Check whether the client host accepts SSH connections
+ */
+async function isReachable(clientConfig) {
+  const result = await sshTry(clientConfig, 'true', ['-o', 'ConnectTimeout=5', '-o', 'BatchMode=yes']);
+  // A rejected login still means the host is up
+  return result.exitCode !== SSH_CONNECTION_ERROR || isAuthFailure(result);
+}
+
+export {
+  getDestination,
+  usesSudo,
+  getSudoPrefix,
+  getRootPrefix,
+  getRestartCommand,
+  runRemote,
+  streamRemote,
+  uploadFile,
+  getCompression,
+  shellQuote,
+  isReachable
+};
@@ -0,0 +1,111 @@
+import chalk from 'chalk';
+
+import { readZipEntries, readZipEntry } from './zip.js';
+import { symbol } from './output.js';
+
+// Manifest attributes that embed the build machine or JDK
+const LEAKY_MANIFEST_ATTRIBUTES = ['Built-By', 'Build-Jdk', 'Build-Jdk-Spec', 'Created-By', 'Build-Time', 'Build-Date'];
+
+/**
+ * Compare two builds of the same artifact entry by entry
+ */
+function compareArchives(pathA, pathB) {
+  const entriesA = new Map(readZipEntries(pathA).map(e => [e.name, e]));
+  const entriesB = new Map(readZipEntries(pathB).map(e => [e.name, e]));
+  const differences = [];
+
+  for (const [name, a] of entriesA) {
+    const b = entriesB.get(name);
+    if (!b) {
+      differences.push({ entry: name, kind: 'removed' });
+    } else if (a.crc32 !== b.crc32 || a.size !== b.size) {
+      differences.push({ entry: name, kind: 'content' });
+    } else if (a.dosTime !== b.dosTime || a.dosDate !== b.dosDate) {
+      differences.push({ entry: name, kind: 'timestamp' });
+    }
+  }
+
+  for (const name of entriesB.keys()) {
+    if (!entriesA.has(name)) {
+      differences.push({ entry: name, kind: 'added' });
+    }
+  }
+
+  return differences;
+}
+
+/**
+ * Find build environment details embedded in an artifact
+ */
+function findEnvironmentLeaks(artifactPath) {
+  const leaks = [];
+
+  const manifest = readZipEntry(artifactPath, 'META-INF/MANIFEST.MF');
+  if (manifest) {
+    for (const line of manifest.toString('utf8').split(/\r?\n/)) {
+      const [attribute] = line.split(':');
+      if (LEAKY_MANIFEST_ATTRIBUTES.includes(attribute)) {
+        leaks.push(`MANIFEST.MF ${line.trim()}`);
+      }
+    }
+  }
+
+  // Older maven-archiver writes a generation date comment into pom.properties
+  for (const entry of readZipEntries(artifactPath)) {
+    if (entry.name.endsWith('/pom.properties')) {
+      const content = readZipEntry(artifactPath, entry.name).toString('utf8');
+      const dateLine = content.split(/\r?\n/).find(line => /^#\w{3} \w{3} \d+/.test(line));
+      if (dateLine) {
+        leaks.push(`${entry.name} ${dateLine}`);
+      }
+    }
+  }
+
+  return leaks;
+}
+
+/**
+ * Report whether two artifacts are byte-identical and why not
+ */
+function reportReproducibility(name, checksumA, checksumB, pathA, pathB) {
+  if (checksumA === checksumB) {
+    console.log(chalk.green(`${symbol('check')} ${name} is byte-identical`));
+    return true;
+  }
+
+  console.log(chalk.red(`${symbol('cross')} ${name} differs`));
+  console.log(`    ${checksumA}`);
+  console.log(`    ${checksumB}`);
+
+  // Without the previous binary only the checksum is known, so skip the entry diff
+  if (pathA) {
+    const differences = compareArchives(pathA, pathB);
+    const byKind = kind => differences.filter(d => d.kind === kind);
+
+    const timestamps = byKind('timestamp');
+    if (timestamps.length > 0) {
+      console.log(chalk.yellow(`  ${timestamps.length} entries differ only in timestamp`));
+      console.log('  Hint: set <project.build.outputTimestamp> in the POM for stable entry dates');
+    }
+
+    for (const kind of ['content', 'added', 'removed']) {
+      for (const difference of byKind(kind)) {
+        console.log(chalk.yellow(`  ${kind}: ${difference.entry}`));
+      }
+    }
+  }
+
+  const leaks = findEnvironmentLeaks(pathB);
+  if (leaks.length > 0) {
+    console.log(chalk.yellow('  Build environment embedded in artifact:'));
+    leaks.forEach(leak => console.log(`    ${leak}`));
+  }
+
+  return false;
+}
+
+export {
+  compareArchives,
+  findEnvironmentLeaks,
+  reportReproducibility
+};
@@ -0,0 +1,41 @@
+import chalk from 'chalk';
+
+// retry: {attempts, delay, timeout} of a project or client; delay (seconds) doubles after each failure
+const DEFAULT_RETRY = { attempts: 3, delay: 1, timeout: null };
+
+// For probes, where a server that doesn't answer is an answer
+const NO_RETRY = { attempts: 1, delay: 0, timeout: null };
+
+/**
+ * Retry policy from a retry setting, defaults filled in
+ */
+function getRetryPolicy(retry) {
+  return { ...DEFAULT_RETRY, ...(retry || {}) };
+}
+
+/**
+ * Run an operation, retrying failures isTransient accepts with exponential backoff
+ * until the policy's attempts are used up; other failures are thrown at once
+ */
+async function withRetry(run, policy, isTransient, what) {
+  const { attempts, delay } = getRetryPolicy(policy);
+  for (let attempt = 1; ; attempt++) {
+    try {
+      return await run();
+    } catch (error) {
+      if (attempt >= attempts || !isTransient(error)) {
+        throw error;
+      }
+      const wait = delay * 2 ** (attempt - 1);
+      const reason = (error.stderr?.toString().trim() || error.message).split('\n').pop();
+      console.log(chalk.yellow(`${what}: ${reason}, retrying in ${wait}s (attempt ${attempt + 1} of ${attempts})`));
+      await Bun.sleep(wait * 1000);
+    }
+  }
+}
+
+export {
+  NO_RETRY,
+  getRetryPolicy,
+  withRetry
+};
@@ -0,0 +1,185 @@
+import fs from 'fs';
+import path from 'path';
+import chalk from 'chalk';
+
+import { runRemote, shellQuote } from './remote.js';
+import { confirmAction } from './confirm.js';
+import { formatSize } from './output.js';
+
+const BACKUP_DIR = 'jmw-backups';
+const DEFAULT_BACKUP_RETENTION = 5;
+
+// Backups are named <artifact>.<yyyymmdd-hhmmss>, so name order is time order
+const BACKUP_NAME = /^(.+)\.(\d{8})-(\d{6})$/;
+
+/**
+ * Directory holding backups of replaced artifacts
+ * Backups live next to (not inside) the deployments directory so the scanner ignores them
+ */
+function getBackupDir(wildflyPath, mode) {
+  return `${wildflyPath}/${mode}/${BACKUP_DIR}`;
+}
+
+function formatBackupStamp(date) {
+  const pad = value => String(value).padStart(2, '0');
+  return `${date.getFullYear()}${pad(date.getMonth() + 1)}${pad(date.getDate())}-` +
+    `${pad(date.getHours())}${pad(date.getMinutes())}${pad(date.getSeconds())}`;
+}
+
+/**
+ * Split a backup file name into artifact name and timestamp
+ */
+function parseBackupName(fileName) {
+  const match = fileName.match(BACKUP_NAME);
+  if (!match) {
+    return null;
+  }
+  const [, artifact, day, time] = match;
+  const timestamp = new Date(
+    Number(day.slice(0, 4)), Number(day.slice(4, 6)) - 1, Number(day.slice(6, 8)),
+    Number(time.slice(0, 2)), Number(time.slice(2, 4)), Number(time.slice(4, 6)));
+  return { artifact, timestamp };
+}
+
+/**
+ * Remote step copying the currently deployed artifact to a timestamped backup,
+ * keeping only the newest `retention` backups of that artifact
+ */
+function planBackupOperation(deployedPath, backupDir, retention, sudo) {
+  const dest = shellQuote(deployedPath);
+  const pattern = `${shellQuote(backupDir)}/${shellQuote(path.posix.basename(deployedPath))}.*`;
+  return {
+    type: 'exec',
+    command: [
+      `if [ -f ${dest} ]; then ${sudo}mkdir -p ${shellQuote(backupDir)} && ${sudo}cp -p ${dest} ${shellQuote(`${backupDir}/${path.posix.basename(deployedPath)}`)}.$(date +%Y%m%d-%H%M%S); fi`,
+      `ls -1d ${pattern} 2>/dev/null | sort -r | tail -n +${retention + 1} | xargs -r ${sudo}rm -f`
+    ].join('; '),
+    description: 'Back up current artifact'
+  };
+}
+
+/**
+ * Remote step putting the newest backup of the artifact back in place
+ */
+function planRestoreOperation(deployedPath, backupDir, sudo) {
+  const pattern = `${shellQuote(backupDir)}/${shellQuote(path.posix.basename(deployedPath))}.*`;
+  return {
+    type: 'exec',
+    command: [
+      `latest=$(ls -1d ${pattern} 2>/dev/null | sort | tail -n 1)`,
+      'if [ -z "$latest" ]; then echo "No previous artifact to roll back to" >&2; exit 1; fi',
+      `${sudo}cp -p "$latest" ${shellQuote(deployedPath)}`
+    ].join('; '),
+    description: 'Restore previous artifact'
+  };
+}
+
+/**
+ * Copy the currently deployed local artifact to a timestamped backup, applying retention
+ */
+function backupLocalArtifact(deployedPath, backupDir, retention = DEFAULT_BACKUP_RETENTION) {
+  const artifactName = path.basename(deployedPath);
+
+  if (fs.existsSync(deployedPath)) {
+    fs.mkdirSync(backupDir, { recursive: true });
+    fs.copyFileSync(deployedPath, path.join(backupDir, `${artifactName}.${formatBackupStamp(new Date())}`));
+  }
+
+  for (const backup of listLocalBackups(backupDir, artifactName).slice(retention)) {
+    fs.rmSync(backup.path, { force: true });
+  }
+}
+
+/**
+ * Put the newest local backup of the artifact back in place
+ */
+function restoreLocalArtifact(deployedPath, backupDir) {
+  const [latest] = listLocalBackups(backupDir, path.basename(deployedPath));
+  if (!latest) {
+    throw new Error('No previous artifact to roll back to');
+  }
+  fs.copyFileSync(latest.path, deployedPath);
+  return latest;
+}
+
+/**
+ * Local backups, newest first, optionally limited to one artifact
+ */
+function listLocalBackups(backupDir, artifactName) {
+  if (!fs.existsSync(backupDir)) {
+    return [];
+  }
+
+  return fs.readdirSync(backupDir)
+    .map(file => ({ file, parsed: parseBackupName(file) }))
+    .filter(({ parsed }) => parsed && (!artifactName || parsed.artifact === artifactName))
+    .map(({ file, parsed }) => ({
+      ...parsed,
+      path: path.join(backupDir, file),
+      size: fs.statSync(path.join(backupDir, file)).size
+    }))
+    .sort((a, b) => b.timestamp - a.timestamp);
+}
+
+/**
+ * Backups on a client host, newest first
+ */
+async function listRemoteBackups(clientConfig, backupDir) {
+  const output = await runRemote(clientConfig,
+    `if [ -d ${shellQuote(backupDir)} ]; then find ${shellQuote(backupDir)} -maxdepth 1 -type f -printf '%s %f\\n'; fi`);
+
+  return output.split('\n')
+    .map(line => line.match(/^(\d+) (.+)$/))
+    .filter(Boolean)
+    .map(([, size, file]) => ({ ...parseBackupName(file), path: `${backupDir}/${file}`, size: Number(size) }))
+    .filter(backup => backup.artifact)
+    .sort((a, b) => b.timestamp - a.timestamp);
+}
+
+/**
+ * Display backups grouped by artifact
+ */
+function showBackups(backups) {
+  if (backups.length === 0) {
+    console.log(chalk.yellow('No backups'));
+    return;
+  }
+
+  const artifacts = [...new Set(backups.map(backup => backup.artifact))].sort();
+  for (const artifact of artifacts) {
+    console.log(chalk.white.bold(artifact));
+    for (const backup of backups.filter(b => b.artifact === artifact)) {
+      console.log(`  ${backup.timestamp.toLocaleString()}  ${formatSize(backup.size).padStart(10)}  ${chalk.gray(backup.path)}`);
+    }
+  }
+}
+
+/**
+ * Decide whether to roll back a failed deployment, asking as the confirmations
+ * policy's rollback rule requires for environment unless --auto-rollback is set
+ */
+async function shouldRollBack(target, options = {}, confirmations = {}, environment = 'local') {
+  if (options.autoRollback) {
+    console.log(chalk.yellow(`Rolling back ${target} to the previous artifact`));
+    return true;
+  }
+  return confirmAction(confirmations, 'rollback', {
+    message: `Roll back ${target} to the previous artifact?`,
+    environment,
+    expected: target
+  });
+}
+
+export {
+  DEFAULT_BACKUP_RETENTION,
+  getBackupDir,
+  parseBackupName,
+  planBackupOperation,
+  planRestoreOperation,
+  backupLocalArtifact,
+  restoreLocalArtifact,
+  listLocalBackups,
+  listRemoteBackups,
+  showBackups,
+  shouldRollBack
+};
@@ -0,0 +1,282 @@
+import fs from 'fs';
+import path from 'path';
+import chalk from 'chalk';
+
+import { runRemote, shellQuote, getSudoPrefix } from './remote.js';
+import { requireClientConfig, getClientHosts } from './config.js';
+import { getWildflyConfig } from './deployer.js';
+import { createController, forEachController } from './controller.js';
+import { emitProgress } from './progress.js';
+import { sleep } from './health.js';
+
+const DEFAULT_DEPLOYMENT_TIMEOUT = 300;
+
+// Markers written by the deployment scanner once it is done with an artifact
+const RESULT_MARKERS = ['deployed', 'failed', 'undeployed'];
+
+// Markers present while the scanner has yet to finish
+const PENDING_MARKERS = ['dodeploy', 'isdeploying', 'pending'];
+
+// Markers that, found before a deployment starts, mean an earlier one never finished or failed
+const STUCK_MARKERS = ['isdeploying', 'pending', 'failed'];
+
+/**
+ * Wait for the scanner to pick up a .dodeploy marker in a local deployments directory
+ * Result markers only count once the trigger and in-progress markers are gone,
+ * so stale markers from an earlier deployment are ignored
+ */
+async function waitForLocalDeployment(deploymentsDir, artifactName, timeout = DEFAULT_DEPLOYMENT_TIMEOUT) {
+  const marker = suffix => path.join(deploymentsDir, `${artifactName}.${suffix}`);
+  const deadline = Date.now() + timeout * 1000;
+
+  emitProgress('scanner', null, `Waiting for ${artifactName} to be deployed`);
+
+  while (Date.now() < deadline) {
+    if (!PENDING_MARKERS.some(suffix => fs.existsSync(marker(suffix)))) {
+      const status = RESULT_MARKERS.find(suffix => fs.existsSync(marker(suffix)));
+      if (status) {
+        const details = status === 'failed' ? fs.readFileSync(marker('failed'), 'utf8') : '';
+        return { status, details };
+      }
+    }
+    await sleep(1000);
+  }
+
+  return { status: 'timeout', details: '' };
+}
+
+/**
+ * Wait for the scanner on a client host, polling in a single remote shell loop
+ */
+async function waitForRemoteDeployment(clientConfig, deploymentsPath, artifactName, timeout = DEFAULT_DEPLOYMENT_TIMEOUT) {
+  const sudo = getSudoPrefix(clientConfig);
+  const name = shellQuote(artifactName);
+  const pending = PENDING_MARKERS.map(suffix => `[ ! -e ${name}.${suffix} ]`).join(' && ');
+  const script = [
+    `cd ${shellQuote(deploymentsPath)} || exit 1`,
+    `end=$(( $(date +%s) + ${Number(timeout)} ))`,
+    'while [ "$(date +%s)" -lt "$end" ]; do',
+    `  if ${pending}; then`,
+    `    for s in ${RESULT_MARKERS.join(' ')}; do`,
+    `      if [ -e ${name}.$s ]; then echo "STATUS:$s"; [ "$s" = failed ] && ${sudo}cat ${name}.failed; exit 0; fi`,
+    '    done',
+    '  fi',
+    '  sleep 1',
+    'done',
+    'echo STATUS:timeout'
+  ].join('\n');
+
+  emitProgress('scanner', null, `Waiting for ${artifactName} on ${clientConfig.host}`);
+  const output = await runRemote(clientConfig, script);
+  const match = output.match(/^STATUS:(\w+)$/m);
+  if (!match) {
+    throw new Error(`Unexpected scanner status output: ${output.trim()}`);
+  }
+
+  return { status: match[1], details: output.slice(match.index + match[0].length).trim() };
+}
+
+/**
+ * Print the scanner outcome, including the .failed content
+ */
+function showDeploymentOutcome(artifactName, outcome, timeout) {
+  switch (outcome.status) {
+    case 'deployed':
+      console.log(chalk.green(`${artifactName} deployed`));
+      emitProgress('scanner', 100, 'Deployed');
+      break;
+    case 'failed':
+      console.log(chalk.red(`${artifactName} failed to deploy:`));
+      console.log(chalk.red(outcome.details.trim().split('\n').map(line => `  ${line}`).join('\n')));
+      emitProgress('scanner', null, 'Deployment failed', { error: outcome.details.trim() });
+      break;
+    case 'undeployed':
+      console.log(chalk.yellow(`${artifactName} was undeployed`));
+      emitProgress('scanner', null, 'Undeployed');
+      break;
+    case 'timeout':
+      console.log(chalk.yellow(`No scanner result for ${artifactName} within ${timeout}s (is WildFly running?)`));
+      emitProgress('scanner', null, 'Timed out waiting for deployment');
+      break;
+  }
+}
+
+/**
+ * Deployments directory of a standalone install, locally or on a client host
+ */
+function getDeploymentsDir(wildflyConfig, hostConfig = null) {
+  return hostConfig
+    ? `${hostConfig.wildfly_path}/standalone/deployments`
+    : path.join(wildflyConfig.root, 'standalone', 'deployments');
+}
+
+/**
+ * Signs of an earlier deployment of an artifact that never finished: leftover
+ * .isdeploying/.pending/.failed markers, and a FAILED runtime status
+ * Returns {markers, failed}; what can't be read counts as nothing found
+ */
+async function findStuckDeployment(wildflyConfig, artifactName, hostConfig = null) {
+  const deploymentsDir = getDeploymentsDir(wildflyConfig, hostConfig);
+  let markers = [];
+  if (hostConfig) {
+    const name = shellQuote(artifactName);
+    const output = await runRemote(hostConfig,
+      `cd ${shellQuote(deploymentsDir)} 2>/dev/null && for s in ${STUCK_MARKERS.join(' ')}; do [ -e ${name}.$s ] && echo $s; done; true`).catch(() => '');
+    markers = output.split('\n').map(line => line.trim()).filter(suffix => STUCK_MARKERS.includes(suffix));
+  } else {
+    markers = STUCK_MARKERS.filter(suffix => fs.existsSync(path.join(deploymentsDir, `${artifactName}.${suffix}`)));
+  }
+
+  let failed = false;
+  try {
+    const controller = await createController(wildflyConfig, hostConfig, { retry: false });
+    failed = await controller.execute([{ deployment: artifactName }], 'read-attribute', { name: 'status' }) === 'FAILED';
+  } catch (error) {
+    // Not deployed, or the server can't be asked
+  }
+  return { markers, failed };
+}
+
+/**
+ * Print what findStuckDeployment found, returning whether anything was
+ */
+function showStuckDeployment(artifactName, { markers, failed }, forceClean) {
+  if (markers.length === 0 && !failed) {
+    return false;
+  }
+  const found = [...markers.map(suffix => `.${suffix} marker`), ...(failed ? ['FAILED status'] : [])];
+  console.log(chalk.yellow('Stuck Deployment:'), chalk.red(`${artifactName} has ${found.join(', ')}`));
+  if (!forceClean) {
+    console.log(chalk.yellow('  The scanner may not pick up the new artifact; jmw deploy --force-clean clears it first'));
+  }
+  return true;
+}
+
+/**
+ * Remove an artifact's deployment from the server and every scanner marker it has,
+ * so the next .dodeploy starts from scratch instead of tripping over a stuck one
+ */
+async function cleanDeployment(wildflyConfig, artifactName, hostConfig = null) {
+  const where = hostConfig ? hostConfig.host : 'local';
+  try {
+    const controller = await createController(wildflyConfig, hostConfig);
+    await controller.execute([{ deployment: artifactName }], 'remove');
+    console.log(chalk.green(`Removed deployment ${artifactName} (${where})`));
+  } catch (error) {
+    if (!/not found|WFLYCTL0216/i.test(error.message)) {
+      console.log(chalk.yellow(`Could not remove deployment ${artifactName} (${where}): ${error.message}`));
+    }
+  }
+
+  const deploymentsDir = getDeploymentsDir(wildflyConfig, hostConfig);
+  const suffixes = [...PENDING_MARKERS, ...RESULT_MARKERS];
+  if (hostConfig) {
+    const files = suffixes.map(suffix => shellQuote(`${deploymentsDir}/${artifactName}.${suffix}`)).join(' ');
+    await runRemote(hostConfig, `${getSudoPrefix(hostConfig)}rm -f ${files}`);
+  } else {
+    suffixes.forEach(suffix => fs.rmSync(path.join(deploymentsDir, `${artifactName}.${suffix}`), { force: true }));
+  }
+  console.log(chalk.green(`Cleared scanner markers of ${artifactName} (${where})`));
+}
+
+/**
+ * Scanner attributes shown and changed by jmw scanner, with their units
+ */
+const SCANNER_ATTRIBUTES = [
+  ['scan-enabled', ''],
+  ['scan-interval', 'ms'],
+  ['deployment-timeout', 's'],
+  ['path', ''],
+  ['relative-to', ''],
+  ['auto-deploy-zipped', ''],
+  ['auto-deploy-exploded', '']
+];
+
+/**
+ * Attribute changes asked for on the command line: {name: value}
+ */
+function getScannerChanges(options) {
+  if (options.enable && options.disable) {
+    throw new Error('Use either --enable or --disable');
+  }
+  const changes = {};
+  if (options.enable || options.disable) {
+    changes['scan-enabled'] = Boolean(options.enable);
+  }
+  for (const [option, attribute] of [['interval', 'scan-interval'], ['timeout', 'deployment-timeout']]) {
+    if (options[option] === undefined) continue;
+    if (!Number.isInteger(options[option]) || options[option] < 0) {
+      throw new Error(`--${option} needs a whole number, got ${options[option]}`);
+    }
+    changes[attribute] = options[option];
+  }
+  return changes;
+}
+
+/**
+ * Apply changes to the deployment scanners a controller reaches and print their settings
+ * The scanner subsystem takes attribute changes without a reload
+ */
+async function configureScannersOn(controller, changes, name) {
+  // A wildcard address answers [{address, result}], a named scanner just its resource
+  const address = [{ subsystem: 'deployment-scanner' }, { scanner: name || '*' }];
+  const resource = await controller.execute(address, 'read-resource');
+  const entries = name ? [{ address, result: resource }] : resource;
+  if (!entries || entries.length === 0) {
+    console.log(chalk.yellow('  No deployment scanners, marker deployments will never be picked up'));
+    return false;
+  }
+
+  for (const { address, result } of entries) {
+    const scanner = address[address.length - 1].scanner;
+    for (const [attribute, value] of Object.entries(changes)) {
+      if (result[attribute] === value) continue;
+      await controller.execute(address, 'write-attribute', { name: attribute, value });
+      result[attribute] = value;
+      console.log(chalk.green(`  ${scanner}: ${attribute} set to ${value}`));
+    }
+
+    console.log(chalk.white.bold(`  ${scanner}`));
+    for (const [attribute, unit] of SCANNER_ATTRIBUTES) {
+      const value = result[attribute];
+      if (value === undefined || value === null) continue;
+      const shown = attribute === 'scan-enabled' && !value ? chalk.red('false (marker deployments are ignored)') : `${value}${unit}`;
+      console.log(`    ${attribute.padEnd(22)} ${shown}`);
+    }
+  }
+  return true;
+}
+
+/**
+ * Show, and with --enable/--disable/--interval/--timeout change, the deployment
+ * scanner settings of the local standalone WildFly or of each client host
+ */
+async function configureScanners(detection, options = {}) {
+  const { projectConfig } = detection;
+  const changes = getScannerChanges(options);
+  const clientConfig = options.client ? requireClientConfig(projectConfig, options.client, options.env) : null;
+  const wildflyConfig = getWildflyConfig(projectConfig, clientConfig);
+  if (wildflyConfig.mode === 'domain') {
+    throw new Error('Domain mode has no deployment scanner, deployments go through the domain controller');
+  }
+
+  if (!clientConfig) {
+    const controller = await createController(wildflyConfig);
+    console.log(chalk.blue(`=== Deployment Scanner (local, ${controller.target}) ===`));
+    return configureScannersOn(controller, changes, options.name);
+  }
+
+  return forEachController(wildflyConfig, getClientHosts(clientConfig), 'Deployment Scanner', controller =>
+    configureScannersOn(controller, changes, options.name));
+}
+
+export {
+  DEFAULT_DEPLOYMENT_TIMEOUT,
+  waitForLocalDeployment,
+  waitForRemoteDeployment,
+  showDeploymentOutcome,
+  findStuckDeployment,
+  showStuckDeployment,
+  cleanDeployment,
+  configureScanners
+};
@@ -0,0 +1,420 @@
+/**
+ * Schema of the config format (a JSON Schema subset: type, enum, properties,
+ * additionalProperties, items, anyOf) and its validation
+ */
+
+import fs from 'fs';
+import path from 'path';
+
+const string = { type: 'string' };
+const number = { type: 'number' };
+const boolean = { type: 'boolean' };
+const strings = { type: 'array', items: string };
+
+function object(properties, additionalProperties = false) {
+  return { type: 'object', properties, additionalProperties };
+}
+
+function mapOf(schema) {
+  return { type: 'object', additionalProperties: schema };
+}
+
+const healthCheck = object({ url: string, timeout: number, interval: number });
+const warmup = object({ urls: strings, concurrency: number, iterations: number });
+const management = object({
+  host: string,
+  port: number,
+  protocol: { enum: ['http', 'https'] },
+  user: string,
+  password: string,
+  tls: object({ ca_file: string, insecure_skip_verify: boolean })
+});
+const retry = object({ attempts: number, delay: number, timeout: number });
+const bastion = object({ host: string, user: string, key: string, port: number });
+const confirmationMode = { enum: ['never', 'always', 'typed'] };
+
+// Settings a client, one of its environments, or client_defaults may set
+const clientSettings = {
+  host: string,
+  hosts: strings,
+  port: number,
+  user: string,
+  identity_file: string,
+  use_agent: boolean,
+  bastion,
+  keychain: boolean,
+  password_auth: boolean,
+  retry,
+  wildfly_path: string,
+  wildfly_mode: { enum: ['standalone', 'domain'] },
+  server_group: string,
+  server_config: string,
+  suspend_timeout: number,
+  restart_cmd: string,
+  management,
+  server_log: string,
+  deployment_timeout: number,
+  backup_retention: number,
+  module_install: { enum: ['copy', 'cli'] },
+  compression: { enum: ['none', 'gzip', 'zstd'] },
+  sudo_user: string,
+  use_sudo: boolean,
+  sudo_password: string,
+  health_check: healthCheck,
+  warmup,
+  profile: string,
+  expected_version: string
+};
+
+const client = object({
+  ...clientSettings,
+  default_environment: string,
+  environments: mapOf(object(clientSettings))
+});
+
+const moduleDependency = {
+  anyOf: [string, object({ name: string, export: boolean, optional: boolean })]
+};
+
+const confirmations = mapOf({ anyOf: [confirmationMode, mapOf(confirmationMode)] });
+
+const audit = object({
+  file: string,
+  remote: object({ host: string, user: string, port: number, identity_file: string, path: string })
+});
+
+const project = object({
+  base_path: string,
+  git_remote: { anyOf: [string, strings] },
+  single_repo: boolean,
+  default_profile: string,
+  maven_profiles: mapOf(strings),
+  profile_aliases: mapOf(string),
+  release_profiles: strings,
+  skip_tests: boolean,
+  maven_opts: string,
+  maven_settings: string,
+  global_settings: string,
+  build_properties: mapOf(mapOf({ type: ['string', 'number', 'boolean'] })),
+  wildfly_root: string,
+  wildfly_mode: { enum: ['standalone', 'domain'] },
+  server_group: string,
+  server_config: string,
+  suspend_timeout: number,
+  management,
+  retry,
+  deployment_timeout: number,
+  server_log: string,
+  backup_retention: number,
+  expected_version: string,
+  health_check: healthCheck,
+  warmup,
+  wildfly_instances: mapOf(object({
+    wildfly_root: string,
+    wildfly_mode: { enum: ['standalone', 'domain'] },
+    server_group: string,
+    server_config: string,
+    suspend_timeout: number,
+    management,
+    server_log: string,
+    deployment_timeout: number,
+    backup_retention: number,
+    expected_version: string,
+    health_check: healthCheck,
+    warmup,
+    shortcuts: mapOf(string)
+  })),
+  default_instance: string,
+  client_defaults: object(clientSettings),
+  clients: mapOf(client),
+  default_client: string,
+  modules: mapOf({
+    anyOf: [string, object({
+      path: string,
+      skip_tests: boolean,
+      build_goal: string,
+      restart_severity: { enum: ['required', 'recommended', 'reload', 'none'] },
+      health_check: healthCheck
+    })]
+  }),
+  global_modules: mapOf(string),
+  module_xml: mapOf(object({ dependencies: { type: 'array', items: moduleDependency } })),
+  module_install: { enum: ['copy', 'cli'] },
+  integration_tests: mapOf({ type: 'object' }),
+  shortcuts: mapOf(string),
+  defaults: object({ yes: boolean, quiet: boolean, dry_run_deploy: boolean }),
+  units: mapOf(object({
+    path: { anyOf: [string, strings] },
+    default_profile: string,
+    skip_tests: boolean,
+    modules: mapOf({ anyOf: [string, { type: 'object' }] }),
+    client_defaults: object(clientSettings),
+    clients: mapOf(client),
+    default_client: string,
+    health_check: healthCheck,
+    warmup
+  })),
+  confirmations,
+  audit
+});
+
+const CONFIG_SCHEMA = object({
+  include: strings,
+  projects: mapOf(project),
+  confirmations,
+  audit,
+  restart_rules: object({
+    global_module: boolean,
+    patterns: {
+      type: 'array',
+      items: object({ match: string, reason: string, severity: { enum: ['required', 'recommended', 'reload'] } })
+    }
+  })
+});
+
+function typeOf(value) {
+  if (value === null) return 'null';
+  if (Array.isArray(value)) return 'array';
+  if (typeof value === 'number') return Number.isInteger(value) ? 'integer' : 'number';
+  return typeof value;
+}
+
+function matchesType(value, type) {
+  const actual = typeOf(value);
+  return [].concat(type).some(expected => expected === actual || (expected === 'number' && actual === 'integer'));
+}
+
+function describe(schema) {
+  if (schema.enum) return schema.enum.join('|');
+  if (schema.anyOf) return schema.anyOf.map(describe).join(' or ');
+  const names = { object: 'map', array: 'list' };
+  return `a ${[].concat(schema.type).map(type => names[type] || type).join(' or ')}`;
+}
+
+/**
+ * Closest known key to a misspelled one, if reasonably close
+ */
+function suggestKey(key, known) {
+  const distance = (a, b) => {
+    const row = Array.from({ length: b.length + 1 }, (_, i) => i);
+    for (let i = 1; i <= a.length; i++) {
+      let previous = row[0];
+      row[0] = i;
+      for (let j = 1; j <= b.length; j++) {
+        const current = row[j];
+        row[j] = Math.min(row[j] + 1, row[j - 1] + 1, previous + (a[i - 1] === b[j - 1] ? 0 : 1));
+        previous = current;
+      }
+    }
+    return row[b.length];
+  };
+
+  const [best] = known
+    .map(candidate => ({ candidate, score: distance(key, candidate) }))
+    .filter(({ score }) => score <= 2)
+    .sort((a, b) => a.score - b.score);
+  return best?.candidate;
+}
+
+function validateNode(value, schema, keyPath, errors) {
+  if (schema.anyOf) {
+    // When only one option has the value's type, report that option's detailed problems
+    const candidates = schema.anyOf.filter(option => option.type && matchesType(value, option.type));
+    if (candidates.length === 1) {
+      return validateNode(value, candidates[0], keyPath, errors);
+    }
+    if (!schema.anyOf.some(option => validateNode(value, option, keyPath, []))) {
+      errors.push({ path: keyPath, message: `must be ${describe(schema)}` });
+      return false;
+    }
+    return true;
+  }
+
+  if (schema.enum) {
+    if (!schema.enum.includes(value)) {
+      errors.push({ path: keyPath, message: `must be ${describe(schema)}` });
+      return false;
+    }
+    return true;
+  }
+
+  if (schema.type && !matchesType(value, schema.type)) {
+    errors.push({ path: keyPath, message: `must be ${describe(schema)}` });
+    return false;
+  }
+
+  const count = errors.length;
+  if (typeOf(value) === 'object' && (schema.properties || schema.additionalProperties !== undefined)) {
+    const properties = schema.properties || {};
+    for (const [key, child] of Object.entries(value)) {
+      if (properties[key]) {
+        validateNode(child, properties[key], [...keyPath, key], errors);
+      } else if (typeof schema.additionalProperties === 'object') {
+        validateNode(child, schema.additionalProperties, [...keyPath, key], errors);
+      } else if (schema.additionalProperties === false) {
+        const suggestion = suggestKey(key, Object.keys(properties));
+        errors.push({ path: [...keyPath, key], message: `is not a known key${suggestion ? ` (did you mean ${suggestion}?)` : ''}` });
+      }
+    }
+  }
+  if (typeOf(value) === 'array' && schema.items) {
+    value.forEach((item, i) => validateNode(item, schema.items, [...keyPath, i], errors));
+  }
+  return errors.length === count;
+}
+
+/**
+ * Line and column (1-based) of a key path in block-style YAML, or of its closest
+ * ancestor that can be found, with the number of keys matched (list items and
+ * flow maps are not descended into). Null when not even the first key is there
+ */
+function locateKey(text, keyPath) {
+  const lines = text.split(/\r?\n/);
+  let location = null;
+  let parentIndent = -1;
+  let start = 0;
+
+  for (const key of keyPath) {
+    if (typeof key === 'number') break;
+
+    const pattern = new RegExp(`^\\s*(["']?)${key.replace(/[.*+?^${}()|[\]\\]/g, '\\$&')}\\1\\s*:(\\s|$)`);
+    let childIndent = null;
+    let found = -1;
+    for (let i = start; i < lines.length; i++) {
+      const indent = lines[i].search(/\S/);
+      if (indent === -1 || lines[i].trimStart().startsWith('#')) continue;
+      if (indent <= parentIndent) break;
+      childIndent ??= indent;
+      if (indent === childIndent && pattern.test(lines[i])) {
+        found = i;
+        break;
+      }
+    }
+    if (found === -1) break;
+
+    parentIndent = childIndent;
+    location = { line: found + 1, column: childIndent + 1, depth: (location?.depth ?? 0) + 1 };
+    start = found + 1;
+  }
+  return location;
+}
+
+/**
+ * Problems the schema can't express: profile aliases must name a mapped profile
+ * (when the project maps its profiles at all) and must not shadow one
+ */
+function checkReferences(config, errors) {
+  for (const [name, project] of Object.entries(config?.projects || {})) {
+    const profiles = project?.maven_profiles;
+    for (const [alias, target] of Object.entries(project?.profile_aliases || {})) {
+      const keyPath = ['projects', name, 'profile_aliases', alias];
+      if (typeOf(target) !== 'string' || typeOf(profiles) !== 'object') continue;
+      if (!Object.hasOwn(profiles, target)) {
+        errors.push({ path: keyPath, message: `refers to ${target}, which is not in maven_profiles` });
+      } else if (Object.hasOwn(profiles, alias)) {
+        errors.push({ path: keyPath, message: 'shadows the maven_profiles entry of the same name' });
+      }
+    }
+  }
+}
+
+/**
+ * Local paths the config refers to that don't exist on this machine, typically
+ * left behind when a WildFly upgrade or a moved checkout changed directories
+ */
+function checkFilesystem(config, errors, projectNames = null) {
+  const cli = process.platform === 'win32' ? 'jboss-cli.bat' : 'jboss-cli.sh';
+  const expect = (keyPath, file, what) => {
+    if (!fs.existsSync(file)) {
+      errors.push({ path: keyPath, message: `points to a missing ${what} (${file})` });
+    }
+  };
+
+  for (const [name, project] of Object.entries(config?.projects || {})) {
+    if (typeOf(project) !== 'object' || (projectNames && !projectNames.includes(name))) continue;
+    if (typeof project.base_path === 'string') {
+      expect(['projects', name, 'base_path'], project.base_path, 'directory');
+    }
+    for (const key of ['maven_settings', 'global_settings']) {
+      if (typeof project[key] === 'string') {
+        expect(['projects', name, key], path.resolve(project.base_path || '.', project[key]), 'settings file');
+      }
+    }
+
+    // The project's own install and each named instance, with the settings they end up using
+    const installs = [{ keyPath: ['projects', name], ...project }];
+    for (const [instance, settings] of Object.entries(project.wildfly_instances || {})) {
+      installs.push({ ...project, ...settings, keyPath: ['projects', name, 'wildfly_instances', instance] });
+    }
+
+    for (const { keyPath, wildfly_root: root, wildfly_mode: mode } of installs) {
+      if (typeof root !== 'string') continue;
+      const rootPath = [...keyPath, 'wildfly_root'];
+      if (!fs.existsSync(root)) {
+        expect(rootPath, root, 'directory');
+        continue;
+      }
+      expect(rootPath, path.join(root, 'bin', cli), 'jboss-cli');
+      if ((mode || 'standalone') === 'standalone') {
+        expect(rootPath, path.join(root, 'standalone', 'deployments'), 'deployments directory');
+      }
+
+      // Global modules are copied into existing module directories of the install
+      for (const key of ['modules', 'global_modules']) {
+        for (const [module, entry] of Object.entries(project[key] || {})) {
+          const modulePath = typeof entry === 'string' ? entry : entry?.path;
+          if (typeof modulePath === 'string' && modulePath) {
+            expect(['projects', name, key, module], path.join(root, modulePath), 'module directory');
+          }
+        }
+      }
+    }
+  }
+}
+
+/**
+ * Validate a parsed config against the schema, and with deep also against the filesystem
+ * (only the projects named in options.projects when given)
+ * Returns a list of {path, message}; the file, line and column each problem comes from
+ * are looked up in the sources ({file, text}), preferring later ones on ties
+ */
+function validateConfig(config, sources = [], options = {}) {
+  const errors = [];
+  validateNode(config, CONFIG_SCHEMA, [], errors);
+  checkReferences(config, errors);
+  if (options.deep && errors.length === 0) {
+    checkFilesystem(config, errors, options.projects);
+  }
+
+  return errors.map(error => {
+    let best = null;
+    for (const source of sources) {
+      const location = locateKey(source.text, error.path);
+      if (location && location.depth >= (best?.depth ?? 0)) {
+        best = { file: source.file, ...location };
+      }
+    }
+    if (!best) {
+      return error;
+    }
+    const { depth, ...location } = best;
+    return { ...error, ...location };
+  });
+}
+
+/**
+ * One-line description of a validation problem
+ */
+function formatValidationError(error) {
+  const keyPath = error.path.map(key => typeof key === 'number' ? `[${key}]` : key).join('.').replace(/\.\[/g, '[');
+  const where = error.line ? ` at ${error.file}:${error.line}:${error.column}` : '';
+  return `${keyPath || 'config'} ${error.message}${where}`;
+}
+
+export {
+  CONFIG_SCHEMA,
+  validateConfig,
+  formatValidationError,
+  suggestKey,
+  locateKey
+};
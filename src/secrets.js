@@ -0,0 +1,58 @@
+import fs from 'fs';
+import os from 'os';
+
+import { readKeychain } from './keychain.js';
+
+// ${env:NAME}, ${keychain:account} or ${file:path} as a whole config value
+const SECRET_REF = /^\$\{(env|keychain|file):([^}]+)\}$/;
+
+/**
+ * Resolve a config value that may reference a secret instead of containing it
+ * Plain values are returned as they are; missing secrets throw, naming the key
+ */
+async function resolveSecret(value, key = 'secret') {
+  const match = typeof value === 'string' ? value.match(SECRET_REF) : null;
+  if (!match) {
+    return value;
+  }
+
+  const [, source, name] = match;
+  switch (source) {
+    case 'env':
+      if (process.env[name] === undefined) {
+        throw new Error(`${key}: environment variable ${name} is not set`);
+      }
+      return process.env[name];
+
+    case 'keychain': {
+      const secret = await readKeychain(name);
+      if (secret === null) {
+        throw new Error(`${key}: no '${name}' secret in the OS keychain`);
+      }
+      return secret;
+    }
+
+    case 'file': {
+      const filePath = name.startsWith('~') ? name.replace('~', os.homedir()) : name;
+      if (!fs.existsSync(filePath)) {
+        throw new Error(`${key}: secret file ${filePath} not found`);
+      }
+      return fs.readFileSync(filePath, 'utf8').replace(/\r?\n$/, '');
+    }
+  }
+}
+
+/**
+ * Management config with its password reference resolved
+ */
+async function resolveManagementConfig(mgmtConfig) {
+  if (!mgmtConfig?.password) {
+    return mgmtConfig;
+  }
+  return { ...mgmtConfig, password: await resolveSecret(mgmtConfig.password, 'management.password') };
+}
+
+export {
+  resolveSecret,
+  resolveManagementConfig
+};
@@ -0,0 +1,383 @@
+import fs from 'fs';
+import path from 'path';
+import { spawn } from 'child_process';
+import chalk from 'chalk';
+
+import { requireClientConfig, getClientHosts } from './config.js';
+import { getWildflyConfig } from './deployer.js';
+import { createController, forEachController } from './controller.js';
+import { createManagementClient, getManagementProtocol, DEFAULT_MANAGEMENT_PORT } from './mgmt.js';
+import { resolveManagementConfig } from './secrets.js';
+import { getScriptPath, runLocalCli } from './jbosscli.js';
+import { sleep } from './health.js';
+import { emitProgress } from './progress.js';
+import { resolveManagementPort } from './ports.js';
+import { getSpawnArgs } from './process.js';
+
+const DEFAULT_START_TIMEOUT = 120;
+const DEFAULT_STOP_TIMEOUT = 60;
+const DEFAULT_RESTART_TIMEOUT = 120;
+const POLL_INTERVAL_MS = 1000;
+
+// How long, polling quickly, a reloaded or restarted standalone server has to be seen
+// going down before it is taken to have been back too fast to notice
+const GOING_DOWN_GRACE_MS = 15000;
+const GOING_DOWN_POLL_MS = 200;
+
+/**
+ * Local WildFly settings of the current project, with the management config resolved
+ * and its port detected from the server config when not configured
+ */
+async function getLocalServer(detection) {
+  const wildflyConfig = getWildflyConfig(detection.projectConfig, null);
+  if (!wildflyConfig.root) {
+    throw new Error(`Project ${detection.project} has no wildfly_root`);
+  }
+  const management = await resolveManagementPort(wildflyConfig.management, wildflyConfig);
+  return { ...wildflyConfig, management: await resolveManagementConfig(management) };
+}
+
+/**
+ * Whether the management interface accepts connections (any HTTP answer counts,
+ * including the 401 asking for credentials)
+ */
+async function isListening(mgmtConfig = {}) {
+  const protocol = getManagementProtocol(mgmtConfig);
+  const url = `${protocol}://${mgmtConfig.host || 'localhost'}:${mgmtConfig.port || DEFAULT_MANAGEMENT_PORT}/management`;
+  try {
+    await fetch(url, protocol === 'https' ? { tls: { rejectUnauthorized: false } } : {});
+    return true;
+  } catch (error) {
+    return false;
+  }
+}
+
+/**
+ * Server state ("running", "starting", "reload-required"...) through the management
+ * API when credentials are configured, else through jboss-cli's local authentication
+ * In domain mode this is the state of the host controllers. Null when it can't be read
+ */
+async function readServerState(wildflyConfig) {
+  const { root, mode, management } = wildflyConfig;
+  try {
+    if (management?.user) {
+      const client = createManagementClient(management);
+      if (mode !== 'domain') {
+        return await client.readAttribute([], 'server-state');
+      }
+      const hosts = await client.readChildrenNames([], 'host');
+      const states = await Promise.all(hosts.map(host => client.readAttribute([{ host }], 'host-state')));
+      return states.find(state => state !== 'running') ?? 'running';
+    }
+
+    if (mode !== 'domain') {
+      return parseCliResult(await runLocalCli(root, management, ':read-attribute(name=server-state)'));
+    }
+    const hosts = await readCliHosts(root, management);
+    const states = [];
+    for (const host of hosts) {
+      states.push(parseCliResult(await runLocalCli(root, management, `/host=${host}:read-attribute(name=host-state)`)));
+    }
+    return states.find(state => state !== 'running') ?? 'running';
+  } catch (error) {
+    return null;
+  }
+}
+
+/**
+ * Host controllers of a local domain, through jboss-cli
+ */
+async function readCliHosts(root, management) {
+  const output = await runLocalCli(root, management, ':read-children-names(child-type=host)');
+  const list = output.match(/"result" => \[([^\]]*)\]/)?.[1] ?? '';
+  return [...list.matchAll(/"([^"]+)"/g)].map(match => match[1]);
+}
+
+/**
+ * Value of a simple "result" => "value" line of jboss-cli output
+ */
+function parseCliResult(output) {
+  return output.match(/"result" => "([^"]*)"/)?.[1] ?? null;
+}
+
+/**
+ * Start the local WildFly in the background (standalone.sh or domain.sh), its console
+ * output going to <mode>/log/console.log, and wait until it reports running
+ */
+async function startServer(detection, options = {}) {
+  const wildflyConfig = await getLocalServer(detection);
+  const { root, mode, management } = wildflyConfig;
+  const timeout = (options.timeout || DEFAULT_START_TIMEOUT) * 1000;
+
+  console.log(chalk.blue('=== Start WildFly ==='));
+  console.log(chalk.yellow('WildFly Root:'), root);
+  console.log(chalk.yellow('Mode:'), mode);
+
+  if (await isListening(management)) {
+    console.log(chalk.yellow('WildFly is already running'));
+    return true;
+  }
+
+  const script = getScriptPath(root, mode);
+  const logPath = path.join(root, mode, 'log', 'console.log');
+  fs.mkdirSync(path.dirname(logPath), { recursive: true });
+  const output = fs.openSync(logPath, 'a');
+
+  // Detached so the server outlives jmw
+  const [file, args, spawnOptions] = getSpawnArgs(script, []);
+  const child = spawn(file, args, {
+    ...spawnOptions,
+    cwd: root,
+    detached: true,
+    stdio: ['ignore', output, output]
+  });
+  fs.closeSync(output);
+  let exitCode = null;
+  child.once('exit', code => { exitCode = code; });
+  child.unref();
+
+  console.log(chalk.yellow('Script:'), script);
+  console.log(chalk.yellow('Console log:'), logPath);
+  console.log(chalk.yellow('PID:'), child.pid);
+  emitProgress('server', null, 'Starting WildFly');
+
+  const deadline = Date.now() + timeout;
+  let state = null;
+  while (Date.now() < deadline) {
+    if (exitCode !== null) {
+      console.log(chalk.red(`${path.basename(script)} exited with code ${exitCode}, see ${logPath}`));
+      return false;
+    }
+    if (await isListening(management)) {
+      state = await readServerState(wildflyConfig);
+      // Without a readable state, an answering management port is as good as it gets
+      if (state === null || state === 'running') {
+        console.log(chalk.green(`WildFly is running${state ? '' : ' (management port open)'}`));
+        emitProgress('server', 100, 'WildFly running');
+        return true;
+      }
+    }
+    await sleep(POLL_INTERVAL_MS);
+  }
+
+  console.log(chalk.red(`WildFly did not report running within ${timeout / 1000}s${state ? ` (state: ${state})` : ''}, see ${logPath}`));
+  emitProgress('server', null, 'WildFly start timed out');
+  return false;
+}
+
+/**
+ * Shut the local WildFly down, through the management API when credentials are
+ * configured, else through jboss-cli, and wait until the management port closes
+ */
+async function stopServer(detection, options = {}) {
+  const wildflyConfig = await getLocalServer(detection);
+  const { root, mode, management } = wildflyConfig;
+  const timeout = (options.timeout || DEFAULT_STOP_TIMEOUT) * 1000;
+
+  console.log(chalk.blue('=== Stop WildFly ==='));
+  console.log(chalk.yellow('WildFly Root:'), root);
+  console.log(chalk.yellow('Mode:'), mode);
+
+  if (!(await isListening(management))) {
+    console.log(chalk.yellow('WildFly is not running'));
+    return true;
+  }
+
+  if (management?.user) {
+    await createManagementClient(management).shutdown({ mode });
+  } else if (mode === 'domain') {
+    for (const host of await readCliHosts(root, management)) {
+      await runLocalCli(root, management, `shutdown --host=${host}`);
+    }
+  } else {
+    await runLocalCli(root, management, 'shutdown');
+  }
+  emitProgress('server', null, 'Stopping WildFly');
+
+  const deadline = Date.now() + timeout;
+  while (Date.now() < deadline) {
+    if (!(await isListening(management))) {
+      console.log(chalk.green('WildFly stopped'));
+      emitProgress('server', 100, 'WildFly stopped');
+      return true;
+    }
+    await sleep(POLL_INTERVAL_MS);
+  }
+
+  console.log(chalk.red(`WildFly still answers after ${timeout / 1000}s`));
+  return false;
+}
+
+/**
+ * server-state of the running servers a controller reaches: the server itself in
+ * standalone mode, the started servers of the group (or of the domain) in domain mode
+ * Returns [{label, address, state}]
+ */
+async function readServerStates(controller, wildflyConfig) {
+  if (wildflyConfig.mode !== 'domain') {
+    return [{ label: null, address: [], state: await controller.execute([], 'read-attribute', { name: 'server-state' }) }];
+  }
+
+  const states = [];
+  for (const host of await controller.execute([], 'read-children-names', { 'child-type': 'host' })) {
+    const configs = await controller.execute([{ host }, { 'server-config': '*' }], 'read-resource', { 'include-runtime': true });
+    for (const { address, result } of configs || []) {
+      const server = address[address.length - 1]['server-config'];
+      if (wildflyConfig.serverGroup && result.group !== wildflyConfig.serverGroup) continue;
+      if (result.status && result.status !== 'STARTED') continue;
+      const state = await controller.execute([{ host }, { server }], 'read-attribute', { name: 'server-state' });
+      states.push({ label: `${host}/${server}`, address: [{ host }, { server }], state });
+    }
+  }
+  return states;
+}
+
+/**
+ * Reload (cheap, services restart in the same JVM) or fully restart the servers a
+ * controller reaches. Without --reload or --full the servers decide: a full restart
+ * when any is restart-required, a reload otherwise
+ */
+async function restartOn(controller, wildflyConfig, options) {
+  const states = await readServerStates(controller, wildflyConfig);
+  if (states.length === 0) {
+    console.log(chalk.yellow('  No running servers'));
+    return false;
+  }
+  states.forEach(({ label, state }) => {
+    const color = state === 'running' ? chalk.green : chalk.yellow;
+    console.log(`  ${label ? `${label}: ` : ''}${color(state)}`);
+  });
+
+  let full = Boolean(options.full);
+  if (!options.full && !options.reload) {
+    full = states.some(({ state }) => state === 'restart-required');
+    if (!full && states.every(({ state }) => state === 'running')) {
+      console.log(chalk.gray('  Nothing pending, reloading'));
+    }
+  }
+  console.log(chalk.yellow('Operation:'), full ? 'full restart' : 'reload');
+
+  const { mode, serverGroup } = wildflyConfig;
+  if (mode === 'domain') {
+    const groups = serverGroup
+      ? [serverGroup]
+      : await controller.execute([], 'read-children-names', { 'child-type': 'server-group' });
+    for (const group of groups) {
+      await controller.execute([{ 'server-group': group }], full ? 'restart-servers' : 'reload-servers', { blocking: true });
+    }
+  } else if (full) {
+    // The server drops the connection while it goes down
+    await controller.execute([], 'shutdown', { restart: true }).catch(() => {});
+  } else {
+    await controller.execute([], 'reload');
+  }
+  emitProgress('server', null, full ? 'Restarting WildFly' : 'Reloading WildFly');
+
+  const timeout = (options.timeout || DEFAULT_RESTART_TIMEOUT) * 1000;
+  const deadline = Date.now() + timeout;
+
+  // A standalone server answers the operation before going down, so polling for running
+  // right away would find the old one; the blocking domain operations return once done
+  if (mode !== 'domain' && !(await waitGoingDown(controller, wildflyConfig))) {
+    console.log(chalk.gray('  Not seen going down, it may have been back already'));
+  }
+
+  let pending = states;
+  while (Date.now() < deadline) {
+    await sleep(POLL_INTERVAL_MS);
+    try {
+      pending = (await readServerStates(controller, wildflyConfig)).filter(({ state }) => state !== 'running');
+    } catch (error) {
+      // Not answering yet
+      continue;
+    }
+    if (pending.length === 0) {
+      console.log(chalk.green(`WildFly is running (${full ? 'restarted' : 'reloaded'})`));
+      emitProgress('server', 100, 'WildFly running');
+      return true;
+    }
+  }
+
+  const left = pending.map(({ label, state }) => `${label ? `${label}: ` : ''}${state}`).join(', ');
+  console.log(chalk.red(`WildFly did not report running within ${timeout / 1000}s${left ? ` (${left})` : ''}`));
+  return false;
+}
+
+/**
+ * Wait until a server stops answering or leaves running, false when it did neither
+ * within the grace period
+ */
+async function waitGoingDown(controller, wildflyConfig) {
+  const deadline = Date.now() + GOING_DOWN_GRACE_MS;
+  while (Date.now() < deadline) {
+    try {
+      const states = await readServerStates(controller, wildflyConfig);
+      if (states.some(({ state }) => state !== 'running')) {
+        return true;
+      }
+    } catch (error) {
+      // The connection dropped
+      return true;
+    }
+    await sleep(GOING_DOWN_POLL_MS);
+  }
+  return false;
+}
+
+/**
+ * Suspend the servers a controller reaches: new requests are rejected and running ones
+ * get up to timeout seconds to finish. In domain mode the servers of the group are suspended
+ */
+async function suspendServers(controller, wildflyConfig, timeout) {
+  if (wildflyConfig.mode === 'domain') {
+    await controller.execute([{ 'server-group': wildflyConfig.serverGroup }], 'suspend-servers', { 'suspend-timeout': timeout });
+  } else {
+    await controller.execute([], 'suspend', { 'suspend-timeout': timeout });
+  }
+}
+
+/**
+ * Resume servers suspended by suspendServers
+ */
+async function resumeServers(controller, wildflyConfig) {
+  if (wildflyConfig.mode === 'domain') {
+    await controller.execute([{ 'server-group': wildflyConfig.serverGroup }], 'resume-servers');
+  } else {
+    await controller.execute([], 'resume');
+  }
+}
+
+/**
+ * Reload or restart the local WildFly, or a client's
+ */
+async function restartServer(detection, options = {}) {
+  const { projectConfig } = detection;
+  if (options.reload && options.full) {
+    throw new Error('Use either --reload or --full');
+  }
+
+  if (!options.client) {
+    const wildflyConfig = await getLocalServer(detection);
+    // The server stops answering while it restarts, which the polling waits out itself
+    const controller = await createController(wildflyConfig, null, { retry: false });
+    console.log(chalk.blue(`=== Restart WildFly (local, ${controller.target}) ===`));
+    return restartOn(controller, wildflyConfig, options);
+  }
+
+  const clientConfig = requireClientConfig(projectConfig, options.client, options.env);
+  const wildflyConfig = getWildflyConfig(projectConfig, clientConfig);
+  return forEachController(wildflyConfig, getClientHosts(clientConfig), 'Restart WildFly', controller =>
+    restartOn(controller, wildflyConfig, options), { retry: false });
+}
+
+export {
+  getLocalServer,
+  isListening,
+  readServerState,
+  startServer,
+  stopServer,
+  readServerStates,
+  suspendServers,
+  resumeServers,
+  restartServer
+};
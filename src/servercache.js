@@ -0,0 +1,173 @@
+import fs from 'fs';
+import path from 'path';
+import chalk from 'chalk';
+
+import { getLocalServer, isListening, startServer, stopServer } from './server.js';
+import { formatSize } from './output.js';
+
+/**
+ * Subdirectories of a directory, none when it doesn't exist
+ */
+function listDirs(dir) {
+  if (!fs.existsSync(dir)) {
+    return [];
+  }
+  return fs.readdirSync(dir, { withFileTypes: true })
+    .filter(entry => entry.isDirectory())
+    .map(entry => path.join(dir, entry.name));
+}
+
+/**
+ * Size of a file or of a whole directory tree
+ */
+function getSize(target) {
+  const stat = fs.lstatSync(target);
+  if (!stat.isDirectory()) {
+    return stat.size;
+  }
+  return fs.readdirSync(target).reduce((total, name) => total + getSize(path.join(target, name)), 0);
+}
+
+/**
+ * Directories of an install holding per-server state: <mode> itself, and each
+ * domain server under domain/servers
+ */
+function getServerDirs(root, mode) {
+  const base = path.join(root, mode);
+  return mode === 'domain' ? [base, ...listDirs(path.join(base, 'servers'))] : [base];
+}
+
+/**
+ * Content hashes any config file of the install refers to: deployments and deployment
+ * overlays of every <mode>/configuration/*.xml, since a stopped server doesn't tell
+ * which one it runs with (-c standalone-full.xml). Null when a file can't be read
+ */
+function readReferencedHashes(root, mode) {
+  const dir = path.join(root, mode, 'configuration');
+  try {
+    const files = fs.readdirSync(dir).filter(name => name.endsWith('.xml'));
+    if (files.length === 0) {
+      return null;
+    }
+    const hashes = new Set();
+    for (const file of files) {
+      const text = fs.readFileSync(path.join(dir, file), 'utf8');
+      for (const [, hash] of text.matchAll(/\bsha1="([0-9a-fA-F]{40})"/g)) {
+        hashes.add(hash.toLowerCase());
+      }
+    }
+    return hashes;
+  } catch (error) {
+    return null;
+  }
+}
+
+/**
+ * Everything clean-cache removes: [{path, kind}]
+ * - tmp: contents of the tmp directories (vfs mounts, exploded archives, compiled JSPs)
+ * - content: data/content entries no config file refers to; skipped when the
+ *   configs can't be read, as then every entry would look orphaned
+ * - exploded: exploded deployment directories the scanner has undeployed, with their marker
+ */
+function findCacheEntries(wildflyConfig) {
+  const { root, mode } = wildflyConfig;
+  const serverDirs = getServerDirs(root, mode);
+  const entries = [];
+
+  for (const dir of serverDirs) {
+    const tmp = path.join(dir, 'tmp');
+    if (fs.existsSync(tmp)) {
+      entries.push(...fs.readdirSync(tmp).map(name => ({ path: path.join(tmp, name), kind: 'tmp' })));
+    }
+  }
+
+  const hashes = readReferencedHashes(root, mode);
+  if (!hashes) {
+    console.log(chalk.yellow(`Deployment content left alone, the configs in ${path.join(root, mode, 'configuration')} could not be read`));
+  } else {
+    // data/content/<first two hex digits of the sha1>/<the rest>/content
+    for (const prefix of serverDirs.flatMap(dir => listDirs(path.join(dir, 'data', 'content')))) {
+      for (const entry of listDirs(prefix)) {
+        if (!hashes.has((path.basename(prefix) + path.basename(entry)).toLowerCase())) {
+          entries.push({ path: entry, kind: 'content' });
+        }
+      }
+    }
+  }
+
+  if (mode === 'standalone') {
+    for (const dir of listDirs(path.join(root, 'standalone', 'deployments'))) {
+      if (fs.existsSync(`${dir}.undeployed`)) {
+        entries.push({ path: dir, kind: 'exploded' }, { path: `${dir}.undeployed`, kind: 'exploded' });
+      }
+    }
+  }
+  return entries.map(entry => ({ ...entry, size: getSize(entry.path) }));
+}
+
+/**
+ * Print what is (or would be) removed, per kind
+ */
+function showCacheEntries(root, entries) {
+  const labels = { tmp: 'Temp files', content: 'Orphaned content', exploded: 'Undeployed exploded deployments' };
+  for (const [kind, label] of Object.entries(labels)) {
+    const matching = entries.filter(entry => entry.kind === kind);
+    if (matching.length === 0) continue;
+    const size = matching.reduce((total, entry) => total + entry.size, 0);
+    console.log(chalk.yellow(`${label}:`), `${matching.length} (${formatSize(size)})`);
+    matching.forEach(entry => console.log(chalk.gray(`  ${path.relative(root, entry.path)}`)));
+  }
+}
+
+/**
+ * Wipe the local WildFly's tmp directories, deployment content no deployment uses
+ * and undeployed exploded deployments, the usual cure for a server running stale
+ * classes. A running server is only touched with --stop, and started again after
+ */
+async function cleanServerCache(detection, options = {}) {
+  const wildflyConfig = await getLocalServer(detection);
+  const { root, mode, management } = wildflyConfig;
+
+  console.log(chalk.blue('=== Clean WildFly Cache ==='));
+  console.log(chalk.yellow('WildFly Root:'), root);
+  console.log(chalk.yellow('Mode:'), mode);
+
+  const running = await isListening(management);
+  if (running && !options.stop && !options.dryRun) {
+    throw new Error('WildFly is running; use --stop to stop it, clean and start it again');
+  }
+
+  if (running && !options.dryRun) {
+    console.log('');
+    if (!(await stopServer(detection))) {
+      return false;
+    }
+  }
+  console.log('');
+
+  const entries = findCacheEntries(wildflyConfig);
+  if (entries.length === 0) {
+    console.log(chalk.green('Nothing to clean'));
+  } else {
+    showCacheEntries(root, entries);
+  }
+  if (options.dryRun) {
+    console.log(chalk.gray(`\nDry run, nothing removed${running ? ' (WildFly is running, clean with --stop)' : ''}`));
+    return true;
+  }
+
+  entries.forEach(entry => fs.rmSync(entry.path, { recursive: true, force: true }));
+  if (entries.length > 0) {
+    console.log(chalk.green(`Removed ${formatSize(entries.reduce((total, entry) => total + entry.size, 0))}`));
+  }
+
+  if (running && options.start !== false) {
+    console.log('');
+    return startServer(detection);
+  }
+  return true;
+}
+
+export {
+  cleanServerCache
+};
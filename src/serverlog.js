@@ -0,0 +1,112 @@
+import fs from 'fs';
+import chalk from 'chalk';
+
+import { runRemote, shellQuote, getSudoPrefix } from './remote.js';
+import { sleep } from './health.js';
+
+// WildFly message ids marking the start and outcome of a deployment
+const STARTING_DEPLOYMENT = 'WFLYSRV0027';
+const DEPLOYED = 'WFLYSRV0010';
+const FAILURE_CODES = ['WFLYSRV0021', 'WFLYCTL0013', 'MSC000001'];
+
+const LOG_TAIL_LINES = 5000;
+const EXCERPT_LINES = 40;
+
+// The scanner result is already known when the log is checked, so its messages should follow shortly
+const LOG_TIMEOUT = 30;
+
+/**
+ * Path of the server.log written by a standalone server
+ */
+function getServerLogPath(wildflyPath, mode, serverLog) {
+  return serverLog || `${wildflyPath}/${mode}/log/server.log`;
+}
+
+/**
+ * Find the outcome of the latest deployment of an artifact in server.log lines
+ * Only messages after the last "Starting deployment" of the artifact are considered
+ */
+function parseDeploymentLog(lines, artifactName) {
+  const quoted = `"${artifactName}"`;
+  const start = lines.findLastIndex(line => line.includes(STARTING_DEPLOYMENT) && line.includes(quoted));
+  if (start === -1) {
+    return null;
+  }
+
+  for (let i = start + 1; i < lines.length; i++) {
+    const line = lines[i];
+    if (line.includes(DEPLOYED) && line.includes(quoted)) {
+      return { status: 'deployed', details: line };
+    }
+    if (FAILURE_CODES.some(code => line.includes(code)) && line.includes(artifactName)) {
+      return { status: 'failed', details: lines.slice(i, i + EXCERPT_LINES).join('\n') };
+    }
+  }
+
+  return null;
+}
+
+/**
+ * Poll a log reader until the latest deployment of the artifact has an outcome
+ */
+async function waitForLogResult(readLog, artifactName, timeout = LOG_TIMEOUT) {
+  const deadline = Date.now() + timeout * 1000;
+
+  while (Date.now() < deadline) {
+    const result = parseDeploymentLog((await readLog()).split('\n'), artifactName);
+    if (result) {
+      return result;
+    }
+    await sleep(2000);
+  }
+
+  return { status: 'timeout', details: '' };
+}
+
+/**
+ * Read the end of a local server.log
+ */
+function readLocalLog(logPath) {
+  if (!fs.existsSync(logPath)) {
+    return '';
+  }
+  return fs.readFileSync(logPath, 'utf8').split('\n').slice(-LOG_TAIL_LINES).join('\n');
+}
+
+/**
+ * Read the end of a remote server.log, starting at the artifact's last "Starting deployment"
+ */
+async function readRemoteLog(clientConfig, logPath, artifactName) {
+  const sudo = getSudoPrefix(clientConfig);
+  const awk = 'index($0, s) && index($0, n) { buf = "" } { buf = buf $0 "\\n" } END { printf "%s", buf }';
+  return await runRemote(clientConfig,
+    `${sudo}tail -n ${LOG_TAIL_LINES} ${shellQuote(logPath)} | awk -v s=${STARTING_DEPLOYMENT} -v n=${shellQuote(`"${artifactName}"`)} ${shellQuote(awk)}`);
+}
+
+/**
+ * Print the outcome found in server.log, including the failure stack trace
+ */
+function showLogResult(artifactName, result, logPath) {
+  switch (result.status) {
+    case 'deployed':
+      console.log(chalk.green(`server.log: ${result.details.trim()}`));
+      break;
+    case 'failed':
+      console.log(chalk.red(`server.log reports a failure for ${artifactName}:`));
+      console.log(chalk.red(result.details.split('\n').map(line => `  ${line}`).join('\n')));
+      break;
+    case 'timeout':
+      console.log(chalk.yellow(`No deployment messages for ${artifactName} in ${logPath}`));
+      break;
+  }
+}
+
+export {
+  LOG_TIMEOUT,
+  getServerLogPath,
+  parseDeploymentLog,
+  waitForLogResult,
+  readLocalLog,
+  readRemoteLog,
+  showLogResult
+};
@@ -0,0 +1,156 @@
+import fs from 'fs';
+import path from 'path';
+import { XMLParser } from 'fast-xml-parser';
+
+import { runRemote, shellQuote, getSudoPrefix } from './remote.js';
+
+// Elements that may repeat, kept as arrays even when there is only one
+const REPEATED = new Set(['profile', 'subsystem', 'socket-binding-group', 'socket-binding', 'deployment-scanner', 'server-group', 'server']);
+
+const parser = new XMLParser({
+  ignoreAttributes: false,
+  attributeNamePrefix: '',
+  parseTagValue: false,
+  removeNSPrefix: true,
+  isArray: (name, jpath, isLeaf, isAttribute) => !isAttribute && REPEATED.has(name)
+});
+
+/**
+ * Value of a WildFly expression such as ${jboss.http.port:8080}: the first of its
+ * properties that is set, else its default. Plain values are returned as they are
+ */
+function resolveExpression(value, properties = {}) {
+  if (typeof value !== 'string') {
+    return value;
+  }
+  return value.replace(/\$\{([^}:]+)(?::([^}]*))?\}/g, (match, names, fallback) => {
+    const name = names.split(',').find(candidate => properties[candidate] !== undefined);
+    return name ? properties[name] : fallback ?? match;
+  });
+}
+
+function toNumber(value, properties) {
+  const resolved = Number(resolveExpression(value, properties));
+  return Number.isFinite(resolved) ? resolved : null;
+}
+
+/**
+ * Port of a named socket binding of a socket binding group, offset included
+ */
+function getBindingPort(group, name, properties) {
+  const binding = (group?.['socket-binding'] || []).find(entry => entry.name === name);
+  if (!binding) {
+    return null;
+  }
+  const port = toNumber(binding.port, properties);
+  return port === null ? null : port + (toNumber(group['port-offset'] ?? 0, properties) ?? 0);
+}
+
+/**
+ * What jmw needs from a standalone.xml or domain.xml:
+ * {scanners: [{name, path, relativeTo, enabled, interval, timeout}], serverGroups: [{name, profile, socketBindingGroup}],
+ *  offset, ports: {managementHttp, managementHttps, http, https}, socketBindingGroups: {name: {http, https}}}
+ * socketBindingGroups (domain.xml) don't include the offsets of the servers using them
+ * properties stand in for -D system properties the server was started with
+ */
+function readServerXml(text, properties = {}) {
+  const xml = parser.parse(text);
+  const root = xml.server ?? xml.domain ?? {};
+  const profiles = xml.server ? [root.profile?.[0] ?? {}] : root.profiles?.profile ?? [];
+
+  const scanners = profiles
+    .flatMap(profile => profile.subsystem || [])
+    .flatMap(subsystem => subsystem['deployment-scanner'] || [])
+    .map(scanner => ({
+      name: scanner.name || 'default',
+      path: scanner.path,
+      relativeTo: scanner['relative-to'] ?? null,
+      enabled: resolveExpression(scanner['scan-enabled'] ?? 'true', properties) !== 'false',
+      interval: toNumber(scanner['scan-interval'] ?? 5000, properties),
+      timeout: toNumber(scanner['deployment-timeout'] ?? 600, properties)
+    }));
+
+  const serverGroups = (root['server-groups']?.['server-group'] || []).map(group => ({
+    name: group.name,
+    profile: group.profile,
+    socketBindingGroup: group['socket-binding-group']?.ref ?? null
+  }));
+
+  const socketBindingGroups = Object.fromEntries((root['socket-binding-groups']?.['socket-binding-group'] || []).map(group => [
+    group.name,
+    { http: getBindingPort(group, 'http', properties), https: getBindingPort(group, 'https', properties) }
+  ]));
+
+  const group = xml.server ? root['socket-binding-group']?.[0] : null;
+  return {
+    scanners,
+    serverGroups,
+    socketBindingGroups,
+    offset: group ? toNumber(group['port-offset'] ?? 0, properties) ?? 0 : 0,
+    ports: {
+      managementHttp: getBindingPort(group, 'management-http', properties),
+      managementHttps: getBindingPort(group, 'management-https', properties),
+      http: getBindingPort(group, 'http', properties),
+      https: getBindingPort(group, 'https', properties)
+    }
+  };
+}
+
+/**
+ * Management port and servers of a domain host.xml: {managementPort, servers: [{name, group, offset}]}
+ */
+function readHostXml(text, properties = {}) {
+  const host = parser.parse(text).host ?? {};
+  const socket = host.management?.['management-interfaces']?.['http-interface']?.socket;
+  return {
+    managementPort: socket ? toNumber(socket.port, properties) : null,
+    servers: (host.servers?.server || []).map(server => ({
+      name: server.name,
+      group: server.group,
+      offset: toNumber(server['socket-bindings']?.['port-offset'] ?? 0, properties) ?? 0
+    }))
+  };
+}
+
+/**
+ * Text of a server config file, locally or on a client host; null when missing
+ */
+async function readConfigFile(filePath, hostConfig = null) {
+  if (!hostConfig) {
+    return fs.existsSync(filePath) ? fs.readFileSync(filePath, 'utf8') : null;
+  }
+  const output = await runRemote(hostConfig, `${getSudoPrefix(hostConfig)}cat ${shellQuote(filePath)} 2>/dev/null || echo __JMW_MISSING__`);
+  return output.trim() === '__JMW_MISSING__' ? null : output;
+}
+
+/**
+ * Parsed server config (standalone.xml or domain.xml unless server_config names
+ * another) of the local install or a client host:
+ * {file, config, hostFile, host} where host is only read in domain mode
+ */
+async function loadServerXml(wildflyConfig, hostConfig = null, properties = {}) {
+  const root = hostConfig ? hostConfig.wildfly_path : wildflyConfig.root;
+  // Remote paths are POSIX whatever jmw runs on
+  const join = hostConfig ? path.posix.join : path.join;
+  const file = join(root, wildflyConfig.mode, 'configuration', wildflyConfig.serverConfig || `${wildflyConfig.mode}.xml`);
+
+  const text = await readConfigFile(file, hostConfig);
+  if (text === null) {
+    throw new Error(`${file} not found`);
+  }
+  const result = { file, config: readServerXml(text, properties), hostFile: null, host: null };
+
+  if (wildflyConfig.mode === 'domain') {
+    result.hostFile = join(root, 'domain', 'configuration', 'host.xml');
+    const hostText = await readConfigFile(result.hostFile, hostConfig);
+    result.host = hostText === null ? null : readHostXml(hostText, properties);
+  }
+  return result;
+}
+
+export {
+  resolveExpression,
+  readServerXml,
+  readHostXml,
+  loadServerXml
+};
@@ -0,0 +1,143 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import chalk from 'chalk';
+
+import { getMavenEnv, getMavenSettingsArgs } from './builder.js';
+import { runCommand } from './process.js';
+import { getMavenCommand } from './maven.js';
+import { showProgress, endProgress } from './output.js';
+import { emitProgress } from './progress.js';
+
+const CLASSIFIERS = ['sources', 'javadoc'];
+
+/**
+ * Download sources and javadoc jars for a module's dependencies
+ */
+async function fetchSources(detection, options = {}) {
+  const { projectConfig, module: moduleInfo } = detection;
+  const jobs = options.jobs || os.cpus().length;
+  const cwd = moduleInfo.isMultiModule ? moduleInfo.reactorRoot : moduleInfo.path;
+  const env = getMavenEnv(projectConfig);
+  const settingsArgs = getMavenSettingsArgs(projectConfig);
+
+  console.log(chalk.blue('=== Dependency Sources ==='));
+  console.log('Resolving dependency list...');
+
+  const dependencies = await listDependencies(moduleInfo, cwd, env, settingsArgs);
+  const missing = dependencies.filter(dep => CLASSIFIERS.some(c => !hasClassifier(dep, c)));
+
+  console.log(`Dependencies: ${dependencies.length}, missing sources/javadoc: ${missing.length}`);
+
+  if (options.missingOnly && missing.length === 0) {
+    console.log(chalk.green('All sources and javadocs already present'));
+    return;
+  }
+
+  const args = [
+    'dependency:sources',
+    'dependency:resolve',
+    '-Dclassifier=javadoc',
+    `-Dmaven.artifact.threads=${jobs}`,
+    ...settingsArgs,
+    ...reactorArgs(moduleInfo)
+  ];
+
+  if (options.missingOnly) {
+    args.push(`-DincludeArtifactIds=${[...new Set(missing.map(dep => dep.artifactId))].join(',')}`);
+  }
+
+  const command = getMavenCommand(moduleInfo.reactorRoot);
+  console.log(chalk.yellow('Command:'), command, args.join(' '));
+  console.log('');
+
+  // Each missing dependency may download up to one jar per classifier
+  const expected = missing.length * CLASSIFIERS.length;
+  let downloaded = 0;
+
+  await runCommand(command, args, {
+    cwd,
+    env,
+    onLine: line => {
+      const match = line.match(/Downloaded from \S+: \S+\/([^/\s]+-(?:sources|javadoc)\.jar)/);
+      if (match) {
+        downloaded++;
+        showProgress(downloaded, expected, `${downloaded}/${expected} ${match[1]}`);
+        emitProgress('sources', expected > 0 ? (downloaded / expected) * 100 : null, match[1]);
+      } else if (line.includes('[ERROR]')) {
+        process.stdout.write('\n' + line + '\n');
+      }
+    }
+  });
+
+  endProgress();
+
+  // Not every artifact publishes sources/javadoc, so re-check what actually landed
+  for (const classifier of CLASSIFIERS) {
+    const present = dependencies.filter(dep => hasClassifier(dep, classifier)).length;
+    const color = present === dependencies.length ? chalk.green : chalk.yellow;
+    console.log(color(`${classifier}: ${present}/${dependencies.length}`));
+  }
+}
+
+/**
+ * List resolved dependencies of the module via dependency:list
+ */
+async function listDependencies(moduleInfo, cwd, env, settingsArgs = []) {
+  const outputFile = path.join(os.tmpdir(), `jmw-deps-${process.pid}.txt`);
+
+  try {
+    await runCommand(getMavenCommand(moduleInfo.reactorRoot), [
+      '-q',
+      'dependency:list',
+      `-DoutputFile=${outputFile}`,
+      '-DappendOutput=true',
+      ...settingsArgs,
+      ...reactorArgs(moduleInfo)
+    ], { cwd, env, onLine: () => {} });
+
+    return parseDependencyList(fs.readFileSync(outputFile, 'utf8'));
+  } finally {
+    fs.rmSync(outputFile, { force: true });
+  }
+}
+
+/**
+ * Parse dependency:list output lines like "   group:artifact:jar[:classifier]:version:scope"
+ */
+function parseDependencyList(content) {
+  const seen = new Map();
+
+  for (const line of content.split('\n')) {
+    const coords = line.trim().split(/\s/)[0].split(':');
+    if (coords.length < 5) continue;
+
+    const [groupId, artifactId] = coords;
+    const version = coords[coords.length - 2];
+    seen.set(`${groupId}:${artifactId}:${version}`, { groupId, artifactId, version });
+  }
+
+  return Array.from(seen.values());
+}
+
+/**
+ * Check local repository for a classifier jar of a dependency
+ */
+function hasClassifier(dep, classifier) {
+  const dir = path.join(os.homedir(), '.m2', 'repository', ...dep.groupId.split('.'), dep.artifactId, dep.version);
+  return fs.existsSync(path.join(dir, `${dep.artifactId}-${dep.version}-${classifier}.jar`));
+}
+
+/**
+ * Reactor selection for single-repo projects
+ */
+function reactorArgs(moduleInfo) {
+  return moduleInfo.isMultiModule ? ['-pl', moduleInfo.relativePath, '-am'] : [];
+}
+
+export {
+  fetchSources,
+  listDependencies,
+  parseDependencyList,
+  hasClassifier
+};
@@ -0,0 +1,304 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import { spawn } from 'child_process';
+import { $ } from 'bun';
+
+import chalk from 'chalk';
+
+import { getConfigDir } from './config.js';
+import { lookupSshHost } from './sshconfig.js';
+import { askSecret } from './confirm.js';
+import { readKeychain, writeKeychain, deleteKeychain } from './keychain.js';
+import { getRetryPolicy, withRetry } from './retry.js';
+
+// Default identities OpenSSH tries, in order
+const DEFAULT_IDENTITIES = ['id_ed25519', 'id_ecdsa', 'id_rsa'];
+
+// Keep the authenticated master connection open between commands of one run
+const CONTROL_PERSIST_SECONDS = 60;
+
+// ssh errors of a connection that couldn't be set up (as opposed to one dropped mid-command),
+// so the command never ran and can be sent again
+const CONNECT_FAILURE = /Connection (refused|timed out)|Operation timed out|No route to host|Network is unreachable|kex_exchange_identification/i;
+
+// Password of each destination that needed password auth this run (null: keys work)
+const sessions = new Map();
+
+/**
+ * Build user@host destination for a client
+ */
+function getDestination(clientConfig) {
+  return clientConfig.user ? `${clientConfig.user}@${clientConfig.host}` : clientConfig.host;
+}
+
+/**
+ * Resolve how a client authenticates
+ * Order: identity_file from config, IdentityFile from ~/.ssh/config, ssh-agent,
+ * then default ~/.ssh identities. Only a configured identity_file is forced on
+ * OpenSSH; otherwise it tries the agent and every default key itself
+ */
+function resolveAuth(clientConfig) {
+  if (clientConfig.identity_file) {
+    if (!fs.existsSync(clientConfig.identity_file)) {
+      throw new Error(`SSH identity file not found: ${clientConfig.identity_file}`);
+    }
+    return { method: 'key', identityFile: clientConfig.identity_file };
+  }
+
+  // Let OpenSSH apply the host's own IdentityFile rather than overriding it
+  const hostConfig = lookupSshHost(clientConfig.host);
+  if (hostConfig.identityFiles.length > 0) {
+    return { method: 'ssh-config', identityFile: hostConfig.identityFiles[0] };
+  }
+
+  if (clientConfig.use_agent !== false && process.env.SSH_AUTH_SOCK) {
+    return { method: 'agent' };
+  }
+
+  const sshDir = path.join(os.homedir(), '.ssh');
+  const identityFiles = DEFAULT_IDENTITIES.map(name => path.join(sshDir, name)).filter(file => fs.existsSync(file));
+  return identityFiles.length > 0 ? { method: 'default-keys', identityFiles } : { method: 'default' };
+}
+
+/**
+ * Describe authentication for plans and error messages
+ */
+function describeAuth(clientConfig) {
+  if (sessions.get(getDestination(clientConfig))) {
+    return 'password';
+  }
+
+  const auth = resolveAuth(clientConfig);
+  switch (auth.method) {
+    case 'key':
+      return `key ${auth.identityFile}`;
+    case 'ssh-config':
+      return `key ${auth.identityFile} (from ~/.ssh/config)`;
+    case 'agent':
+      return 'ssh-agent';
+    case 'default-keys':
+      return `keys ${auth.identityFiles.join(', ')}`;
+    default:
+      return 'OpenSSH defaults';
+  }
+}
+
+/**
+ * Describe how the client host is reached, including ~/.ssh/config aliases and jump hosts
+ */
+function describeRoute(clientConfig) {
+  const hostConfig = lookupSshHost(clientConfig.host);
+  const user = clientConfig.user || hostConfig.user;
+  let route = user ? `${user}@${hostConfig.hostName}` : hostConfig.hostName;
+
+  if (hostConfig.hostName !== clientConfig.host) {
+    route += ` (alias ${clientConfig.host})`;
+  }
+  if (clientConfig.bastion) {
+    route += ` via ${getDestination(clientConfig.bastion)} (bastion)`;
+  } else if (hostConfig.proxyJump && hostConfig.proxyJump !== 'none') {
+    route += ` via ${hostConfig.proxyJump}`;
+  }
+  return route;
+}
+
+/**
+ * OpenSSH options for a client session
+ * Host aliases, per-host users and ProxyJump in ~/.ssh/config are applied by OpenSSH itself
+ */
+function getSshArgs(clientConfig) {
+  const auth = resolveAuth(clientConfig);
+  const controlDir = path.join(getConfigDir(), 'ssh');
+  fs.mkdirSync(controlDir, { recursive: true, mode: 0o700 });
+
+  const args = [
+    '-o', 'ControlMaster=auto',
+    '-o', `ControlPath=${path.join(controlDir, '%C')}`,
+    '-o', `ControlPersist=${CONTROL_PERSIST_SECONDS}`
+  ];
+
+  if (auth.method === 'key') {
+    args.push('-i', auth.identityFile, '-o', 'IdentitiesOnly=yes');
+  }
+  if (clientConfig.use_agent === false) {
+    args.push('-o', 'IdentityAgent=none');
+  }
+  if (clientConfig.port) {
+    args.push('-p', String(clientConfig.port));
+  }
+  if (clientConfig.retry?.timeout) {
+    args.push('-o', `ConnectTimeout=${clientConfig.retry.timeout}`);
+  }
+  if (clientConfig.bastion) {
+    args.push('-o', `ProxyCommand=${getBastionCommand(clientConfig.bastion)}`);
+  }
+
+  return args;
+}
+
+/**
+ * ProxyCommand tunnelling through a bastion host ({host, user, key, port})
+ * Used instead of ProxyJump so the bastion can have its own key
+ */
+function getBastionCommand(bastion) {
+  if (!bastion.host) {
+    throw new Error('bastion requires a host');
+  }
+  if (bastion.key && !fs.existsSync(bastion.key)) {
+    throw new Error(`Bastion key not found: ${bastion.key}`);
+  }
+
+  const quote = value => `'${String(value).replace(/'/g, `'\\''`)}'`;
+  const args = ['ssh'];
+  if (bastion.key) args.push('-i', quote(bastion.key), '-o', 'IdentitiesOnly=yes');
+  if (bastion.port) args.push('-p', String(bastion.port));
+  args.push('-W', '%h:%p', quote(getDestination(bastion)));
+  return args.join(' ');
+}
+
+/**
+ * Run a command over SSH and return its stdout
+ * Connections that can't be set up are retried with the client's retry policy
+ */
+async function sshExec(clientConfig, command, input = null) {
+  const run = async () => {
+    await ensureSession(clientConfig);
+    const env = getSshEnv(clientConfig);
+    if (input !== null) {
+      return await $`ssh ${getSshArgs(clientConfig)} ${getDestination(clientConfig)} ${command} < ${Buffer.from(input)}`.env(env).quiet().text();
+    }
+    return await $`ssh ${getSshArgs(clientConfig)} ${getDestination(clientConfig)} ${command}`.env(env).quiet().text();
+  };
+  return withRetry(run, getRetryPolicy(clientConfig.retry), isConnectFailure, `SSH to ${getDestination(clientConfig)}`);
+}
+
+/**
+ * Run a command over SSH without throwing, returning { exitCode, stdout, stderr }
+ */
+async function sshTry(clientConfig, command, extraArgs = []) {
+  const result = await $`ssh ${getSshArgs(clientConfig)} ${extraArgs} ${getDestination(clientConfig)} ${command}`.env(getSshEnv(clientConfig)).quiet().nothrow();
+  return {
+    exitCode: result.exitCode,
+    stdout: result.stdout.toString(),
+    stderr: result.stderr.toString()
+  };
+}
+
+/**
+ * Spawn an SSH command as a child process for streaming
+ * Call ensureSession first so password fallback has happened
+ */
+function sshSpawn(clientConfig, command, stdio) {
+  return spawn('ssh', [...getSshArgs(clientConfig), getDestination(clientConfig), command], { stdio, env: getSshEnv(clientConfig) });
+}
+
+/**
+ * Whether an ssh result is a rejected login rather than a network failure
+ */
+function isAuthFailure(result) {
+  return result.exitCode === 255 && /Permission denied/.test(result.stderr);
+}
+
+/**
+ * Whether a failed sshExec never got a connection
+ */
+function isConnectFailure(error) {
+  return error.exitCode === 255 && CONNECT_FAILURE.test(error.stderr?.toString() || '');
+}
+
+/**
+ * Make sure a destination can be logged into, once per run
+ * When key/agent auth is rejected, fall back to a password (from the OS keychain
+ * with `keychain: true`, otherwise prompted) handed to ssh through SSH_ASKPASS
+ */
+async function ensureSession(clientConfig) {
+  const destination = getDestination(clientConfig);
+  if (sessions.has(destination)) {
+    return;
+  }
+
+  const probe = await sshTry(clientConfig, 'true', ['-o', 'BatchMode=yes']);
+  if (!isAuthFailure(probe) || clientConfig.password_auth === false) {
+    // Unreachable hosts are probed again next time
+    if (probe.exitCode !== 255) {
+      sessions.set(destination, null);
+    }
+    return;
+  }
+
+  const account = `ssh:${destination}`;
+  let password = clientConfig.keychain ? await readKeychain(account) : null;
+  const stored = !!password;
+  if (!password) {
+    console.log(chalk.yellow(`Key authentication to ${destination} was rejected`));
+    password = await askSecret(`Password for ${destination}: `);
+  }
+
+  sessions.set(destination, password);
+  const login = await sshTry(clientConfig, 'true', [
+    '-o', 'PreferredAuthentications=keyboard-interactive,password',
+    '-o', 'NumberOfPasswordPrompts=1'
+  ]);
+  if (login.exitCode !== 0) {
+    sessions.delete(destination);
+    if (stored) {
+      await deleteKeychain(account);
+    }
+    throw new Error(`Password authentication to ${destination} failed`);
+  }
+
+  if (clientConfig.keychain && !stored) {
+    await writeKeychain(account, password);
+  }
+}
+
+/**
+ * Environment for ssh processes; hosts using passwords get an askpass helper
+ * that answers password and keyboard-interactive prompts
+ */
+function getSshEnv(clientConfig) {
+  const password = sessions.get(getDestination(clientConfig));
+  if (!password) {
+    return process.env;
+  }
+
+  return {
+    ...process.env,
+    SSH_ASKPASS: getAskpassPath(),
+    SSH_ASKPASS_REQUIRE: 'force',
+    JMW_SSH_PASSWORD: password
+  };
+}
+
+/**
+ * Askpass helper printing $JMW_SSH_PASSWORD as is. On Windows PowerShell reads it,
+ * as cmd's echo would expand %...% and run whatever follows & or | in the password
+ */
+function getAskpassPath() {
+  const windows = process.platform === 'win32';
+  const askpassPath = path.join(getConfigDir(), 'ssh', windows ? 'askpass.cmd' : 'askpass.sh');
+  const script = windows
+    ? '@powershell -NoProfile -NonInteractive -Command "[Console]::Out.WriteLine($env:JMW_SSH_PASSWORD)"\r\n'
+    : '#!/bin/sh\nprintf \'%s\\n\' "$JMW_SSH_PASSWORD"\n';
+  // Rewritten when it differs, replacing helpers of older versions
+  if (!fs.existsSync(askpassPath) || fs.readFileSync(askpassPath, 'utf8') !== script) {
+    fs.mkdirSync(path.dirname(askpassPath), { recursive: true, mode: 0o700 });
+    fs.writeFileSync(askpassPath, script, { mode: 0o700 });
+  }
+  return askpassPath;
+}
+
+export {
+  getDestination,
+  resolveAuth,
+  describeAuth,
+  describeRoute,
+  getSshArgs,
+  getBastionCommand,
+  sshExec,
+  sshTry,
+  sshSpawn,
+  ensureSession,
+  isAuthFailure
+};
@@ -0,0 +1,111 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+
+/**
+ * Parse an OpenSSH client config into ordered Host blocks
+ * Include directives are expanded relative to ~/.ssh; Match blocks are skipped
+ */
+function parseSshConfig(configPath = path.join(os.homedir(), '.ssh', 'config'), depth = 0) {
+  if (depth > 8 || !fs.existsSync(configPath)) {
+    return [];
+  }
+
+  const blocks = [];
+  let current = { patterns: ['*'], options: {} };
+  blocks.push(current);
+
+  for (const rawLine of fs.readFileSync(configPath, 'utf8').split(/\r?\n/)) {
+    const line = rawLine.trim();
+    if (!line || line.startsWith('#')) continue;
+
+    const match = line.match(/^(\S+?)\s*(?:=\s*|\s+)(.+)$/);
+    if (!match) continue;
+
+    const key = match[1].toLowerCase();
+    const value = match[2].replace(/^"(.*)"$/, '$1');
+
+    if (key === 'host') {
+      current = { patterns: value.split(/\s+/), options: {} };
+      blocks.push(current);
+    } else if (key === 'match') {
+      // Match criteria are not evaluated; ignore the block's options
+      current = { patterns: [], options: {} };
+      blocks.push(current);
+    } else if (key === 'include') {
+      for (const included of expandInclude(value)) {
+        blocks.push(...parseSshConfig(included, depth + 1));
+      }
+    } else {
+      (current.options[key] ||= []).push(value);
+    }
+  }
+
+  return blocks;
+}
+
+function expandInclude(value) {
+  const sshDir = path.join(os.homedir(), '.ssh');
+  return value.split(/\s+/).flatMap(pattern => {
+    const expanded = pattern.replace(/^~/, os.homedir());
+    const full = path.isAbsolute(expanded) ? expanded : path.join(sshDir, expanded);
+    if (!full.includes('*')) return [full];
+
+    const dir = path.dirname(full);
+    const regex = globToRegex(path.basename(full));
+    return fs.existsSync(dir) ? fs.readdirSync(dir).filter(f => regex.test(f)).sort().map(f => path.join(dir, f)) : [];
+  });
+}
+
+function globToRegex(glob) {
+  const escaped = glob.replace(/[.+^${}()|[\]\\]/g, '\\$&').replace(/\*/g, '.*').replace(/\?/g, '.');
+  return new RegExp(`^${escaped}$`);
+}
+
+/**
+ * Check host against a Host line's patterns (wildcards, !negation)
+ */
+function matchesHost(patterns, host) {
+  let matched = false;
+  for (const pattern of patterns) {
+    if (pattern.startsWith('!')) {
+      if (globToRegex(pattern.slice(1)).test(host)) return false;
+    } else if (globToRegex(pattern).test(host)) {
+      matched = true;
+    }
+  }
+  return matched;
+}
+
+/**
+ * Resolve effective options for a host alias
+ * Like OpenSSH, the first value found wins (except IdentityFile, which accumulates)
+ */
+function lookupSshHost(host, blocks = parseSshConfig()) {
+  const resolved = { identityFiles: [] };
+
+  for (const block of blocks) {
+    if (!matchesHost(block.patterns, host)) continue;
+
+    for (const [key, values] of Object.entries(block.options)) {
+      if (key === 'identityfile') {
+        resolved.identityFiles.push(...values.map(v => v.replace(/^~/, os.homedir()).replace(/%d/g, os.homedir())));
+      } else if (resolved[key] === undefined) {
+        resolved[key] = values[0];
+      }
+    }
+  }
+
+  return {
+    hostName: resolved.hostname?.replace(/%h/g, host) || host,
+    user: resolved.user,
+    port: resolved.port,
+    proxyJump: resolved.proxyjump,
+    identityFiles: resolved.identityFiles
+  };
+}
+
+export {
+  parseSshConfig,
+  lookupSshHost
+};
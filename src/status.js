@@ -0,0 +1,120 @@
+import chalk from 'chalk';
+
+import { requireClientConfig, getClientHosts } from './config.js';
+import { getWildflyConfig } from './deployer.js';
+import { createController, forEachController } from './controller.js';
+import { readServerStates } from './server.js';
+import { readDeployments, isModuleDeployment } from './deployments.js';
+import { formatSize } from './output.js';
+
+// Heap use above this share of the maximum is flagged
+const HEAP_WARNING_RATIO = 0.85;
+
+/**
+ * JVM metrics of a server from its platform MBeans: {heapUsed, heapMax, threads, peakThreads, uptime}
+ */
+async function readMetrics(controller, address) {
+  const mbean = type => [...address, { 'core-service': 'platform-mbean' }, { type }];
+  const heap = await controller.execute(mbean('memory'), 'read-attribute', { name: 'heap-memory-usage' });
+  const threading = await controller.execute(mbean('threading'), 'read-resource', { 'include-runtime': true });
+  const uptime = await controller.execute(mbean('runtime'), 'read-attribute', { name: 'uptime' });
+  return {
+    heapUsed: heap.used,
+    heapMax: heap.max > 0 ? heap.max : heap.committed,
+    threads: threading['thread-count'],
+    peakThreads: threading['peak-thread-count'],
+    uptime
+  };
+}
+
+/**
+ * 3d 4h, 2h 5m or 12m 3s
+ */
+function formatUptime(ms) {
+  const seconds = Math.floor(ms / 1000);
+  const [d, h, m, s] = [Math.floor(seconds / 86400), Math.floor(seconds / 3600) % 24, Math.floor(seconds / 60) % 60, seconds % 60];
+  if (d > 0) return `${d}d ${h}h`;
+  if (h > 0) return `${h}h ${m}m`;
+  return `${m}m ${s}s`;
+}
+
+/**
+ * Print state and metrics of each server a controller reaches, and the current module's deployments
+ */
+async function showStatusOn(controller, wildflyConfig, moduleInfo) {
+  let states;
+  try {
+    states = await readServerStates(controller, wildflyConfig);
+  } catch (error) {
+    console.log(chalk.yellow('State:'), chalk.red('not running'), chalk.gray(`(${error.message})`));
+    return false;
+  }
+  if (states.length === 0) {
+    console.log(chalk.yellow('State:'), chalk.red(`no running servers${wildflyConfig.serverGroup ? ` in ${wildflyConfig.serverGroup}` : ''}`));
+    return false;
+  }
+
+  const version = await controller.execute([], 'read-attribute', { name: 'product-version' }).catch(() => null);
+  if (version) {
+    console.log(chalk.yellow('Version:'), version);
+  }
+
+  let healthy = true;
+  for (const { label, address, state } of states) {
+    const indent = label ? '  ' : '';
+    if (label) {
+      console.log(chalk.white.bold(label));
+    }
+    console.log(`${indent}${chalk.yellow('State:')} ${state === 'running' ? chalk.green(state) : chalk.yellow(state)}`);
+    healthy = healthy && state === 'running';
+
+    let metrics;
+    try {
+      metrics = await readMetrics(controller, address);
+    } catch (error) {
+      console.log(`${indent}${chalk.gray(`Metrics unavailable: ${error.message}`)}`);
+      continue;
+    }
+    const ratio = metrics.heapMax > 0 ? metrics.heapUsed / metrics.heapMax : 0;
+    const heap = `${formatSize(metrics.heapUsed)} of ${formatSize(metrics.heapMax)} (${Math.round(ratio * 100)}%)`;
+    console.log(`${indent}${chalk.yellow('Uptime:')} ${formatUptime(metrics.uptime)}`);
+    console.log(`${indent}${chalk.yellow('Heap:')} ${ratio >= HEAP_WARNING_RATIO ? chalk.red(`${heap}, close to the maximum`) : heap}`);
+    console.log(`${indent}${chalk.yellow('Threads:')} ${metrics.threads} ${chalk.gray(`(peak ${metrics.peakThreads})`)}`);
+  }
+
+  const deployments = (await readDeployments(controller, wildflyConfig.mode).catch(() => []))
+    .filter(deployment => isModuleDeployment(deployment, moduleInfo));
+  for (const deployment of deployments) {
+    const state = deployment.enabled ? chalk.green('enabled') : chalk.yellow('disabled');
+    console.log(`${chalk.yellow('Deployment:')} ${deployment.name} ${state}${deployment.group ? chalk.gray(` in ${deployment.group}`) : ''}`);
+  }
+  if (deployments.length === 0 && !moduleInfo.isGlobalModule) {
+    console.log(chalk.yellow('Deployment:'), chalk.gray(`${moduleInfo.artifactId} is not deployed`));
+  }
+  return healthy;
+}
+
+/**
+ * Show the state, JVM metrics and current module deployment of the local WildFly
+ * or of a client's hosts
+ */
+async function showStatus(detection, options = {}) {
+  const { projectConfig, module: moduleInfo } = detection;
+
+  if (!options.client) {
+    const wildflyConfig = getWildflyConfig(projectConfig, null);
+    const controller = await createController(wildflyConfig);
+    console.log(chalk.blue(`=== Status (local, ${controller.target}) ===`));
+    return showStatusOn(controller, wildflyConfig, moduleInfo);
+  }
+
+  const clientConfig = requireClientConfig(projectConfig, options.client, options.env);
+  const wildflyConfig = getWildflyConfig(projectConfig, clientConfig);
+  return forEachController(wildflyConfig, getClientHosts(clientConfig), 'Status', controller =>
+    showStatusOn(controller, wildflyConfig, moduleInfo));
+}
+
+export {
+  readMetrics,
+  showStatus
+};
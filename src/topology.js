@@ -0,0 +1,90 @@
+import chalk from 'chalk';
+
+import { requireClientConfig, getClientHosts } from './config.js';
+import { getWildflyConfig } from './deployer.js';
+import { createController } from './controller.js';
+import { symbol } from './output.js';
+
+/**
+ * Hosts of a domain with their servers: [{host, state, primary, servers: [{name, group, status, autoStart}]}]
+ */
+async function readTopology(controller) {
+  const hosts = [];
+  for (const host of await controller.execute([], 'read-children-names', { 'child-type': 'host' })) {
+    const info = await controller.execute([{ host }], 'read-resource', { 'include-runtime': true });
+    const configs = await controller.execute([{ host }, { 'server-config': '*' }], 'read-resource', { 'include-runtime': true });
+    hosts.push({
+      host,
+      state: info['host-state'] ?? null,
+      primary: Boolean(info.master ?? info['domain-controller']?.local),
+      servers: (configs || []).map(({ address, result }) => ({
+        name: address[address.length - 1]['server-config'],
+        group: result.group,
+        status: result.status ?? null,
+        autoStart: result['auto-start'] !== false
+      })).sort((a, b) => a.name.localeCompare(b.name))
+    });
+  }
+  return hosts.sort((a, b) => Number(b.primary) - Number(a.primary) || a.host.localeCompare(b.host));
+}
+
+/**
+ * Print hosts and servers; servers of the deploy target group are marked, and stopped
+ * ones among them called out as they won't get the artifact until started
+ */
+function showTopology(hosts, serverGroup) {
+  const colorStatus = status => status === 'STARTED' ? chalk.green(status) : chalk.yellow(status || 'unknown');
+
+  for (const { host, state, primary, servers } of hosts) {
+    const hostState = state === 'running' ? chalk.green(state) : chalk.yellow(state || 'unknown');
+    console.log(`  ${chalk.white.bold(host)}${primary ? chalk.gray(' (domain controller)') : ''}  ${hostState}`);
+    if (servers.length === 0) {
+      console.log(chalk.gray('      no servers'));
+    }
+    const width = Math.max(0, ...servers.map(server => server.name.length));
+    for (const server of servers) {
+      const target = serverGroup && server.group === serverGroup;
+      const marker = target ? chalk.green(`${symbol('arrow')} `) : '  ';
+      const autoStart = server.autoStart ? '' : chalk.gray('  (no auto-start)');
+      console.log(`    ${marker}${server.name.padEnd(width)}  ${server.group.padEnd(20)} ${colorStatus(server.status)}${autoStart}`);
+    }
+  }
+
+  if (!serverGroup) {
+    return;
+  }
+  const targets = hosts.flatMap(({ host, servers }) => servers.filter(server => server.group === serverGroup).map(server => ({ host, ...server })));
+  const down = targets.filter(server => server.status !== 'STARTED');
+  console.log('');
+  if (targets.length === 0) {
+    console.log(chalk.yellow(`No servers in ${serverGroup}, a deploy reaches nobody`));
+  } else if (down.length > 0) {
+    console.log(chalk.yellow(`${serverGroup}: ${targets.length - down.length} of ${targets.length} server(s) started; not started: ${down.map(server => `${server.host}/${server.name}`).join(', ')}`));
+  } else {
+    console.log(chalk.green(`${serverGroup}: all ${targets.length} server(s) started`));
+  }
+}
+
+/**
+ * Show the domain topology of the local WildFly or of a client, through the domain controller
+ */
+async function showDomainTopology(detection, options = {}) {
+  const { projectConfig } = detection;
+  const clientConfig = options.client ? requireClientConfig(projectConfig, options.client, options.env) : null;
+  const wildflyConfig = getWildflyConfig(projectConfig, clientConfig);
+  if (wildflyConfig.mode !== 'domain') {
+    throw new Error('Topology needs domain mode (mode: domain)');
+  }
+  const serverGroup = options.serverGroup || wildflyConfig.serverGroup;
+
+  const hostConfig = clientConfig ? getClientHosts(clientConfig)[0] : null;
+  const controller = await createController(wildflyConfig, hostConfig);
+  console.log(chalk.blue(`=== Domain Topology (${hostConfig ? hostConfig.host : 'local'}, ${controller.target}) ===`));
+  showTopology(await readTopology(controller), serverGroup);
+  return true;
+}
+
+export {
+  readTopology,
+  showDomainTopology
+};
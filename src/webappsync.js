@@ -0,0 +1,198 @@
+import fs from 'fs';
+import path from 'path';
+import chalk from 'chalk';
+
+import { requireClientConfig, getClientHosts } from './config.js';
+import { getWildflyConfig } from './deployer.js';
+import { hashLocalDirectory } from './globalmodule.js';
+import { sha256File } from './history.js';
+import { runRemote, shellQuote, getSudoPrefix } from './remote.js';
+import { executeOperations } from './outbox.js';
+import { confirmAction } from './confirm.js';
+
+const WEBAPP_DIR = path.join('src', 'main', 'webapp');
+
+/**
+ * Pick the exploded deployment directory (<artifactId>[-version].war) among deployment names
+ */
+function pickExplodedDeployment(names, artifactId, location) {
+  const matches = names.filter(name =>
+    name === `${artifactId}.war` || (name.startsWith(`${artifactId}-`) && name.endsWith('.war')));
+
+  if (matches.length === 0) {
+    throw new Error(`No exploded deployment of ${artifactId} in ${location} (deploy it exploded or pass --target)`);
+  }
+  if (matches.length > 1) {
+    throw new Error(`Several exploded deployments of ${artifactId} in ${location}: ${matches.join(', ')} (pass --target)`);
+  }
+  return matches[0];
+}
+
+/**
+ * Checksums of the given files in a local directory (missing files are left out)
+ */
+async function hashLocalFiles(dir, files) {
+  const hashes = new Map();
+  for (const file of files) {
+    const full = path.join(dir, file);
+    if (fs.existsSync(full)) {
+      hashes.set(file, await sha256File(full));
+    }
+  }
+  return hashes;
+}
+
+/**
+ * Checksums of the given files in a remote directory (missing files are left out)
+ * Only files present in the webapp source are hashed, not the whole deployment
+ */
+async function hashRemoteFiles(clientConfig, dir, files) {
+  const output = await runRemote(clientConfig,
+    `cd ${shellQuote(dir)} && sha256sum -- ${files.map(file => shellQuote(`./${file}`)).join(' ')} 2>/dev/null; true`);
+
+  const hashes = new Map();
+  for (const line of output.split('\n')) {
+    const match = line.match(/^([0-9a-f]{64})\s+\.\/(.+)$/);
+    if (match) {
+      hashes.set(match[2], match[1]);
+    }
+  }
+  return hashes;
+}
+
+/**
+ * Files whose source differs from the deployed copy
+ */
+function changedFiles(local, deployed) {
+  return [...local.keys()].filter(file => deployed.get(file) !== local.get(file)).sort();
+}
+
+function showChanges(files, deployed) {
+  files.forEach(file => console.log(deployed.has(file) ? chalk.yellow(`  ~ ${file}`) : chalk.green(`  + ${file}`)));
+  console.log(`${files.length} file(s) changed`);
+}
+
+/**
+ * Copy changed webapp resources (JSP, XHTML, static files) into an exploded deployment,
+ * locally or on a client's hosts, without a Maven build
+ */
+async function syncWebapp(detection, options = {}) {
+  const { projectConfig, module: moduleInfo } = detection;
+  const webappDir = path.join(moduleInfo.path, WEBAPP_DIR);
+
+  if (!fs.existsSync(webappDir)) {
+    throw new Error(`${moduleInfo.artifactId} has no ${WEBAPP_DIR} directory`);
+  }
+
+  const clientConfig = options.client ? requireClientConfig(projectConfig, options.client, options.env) : null;
+  const wildflyConfig = getWildflyConfig(projectConfig, clientConfig);
+  if (wildflyConfig.mode !== 'standalone') {
+    throw new Error('Exploded deployments can only be synced on standalone servers');
+  }
+
+  console.log(chalk.blue('=== Webapp Sync ==='));
+  console.log(`Source: ${webappDir}`);
+
+  const start = Date.now();
+  const local = await hashLocalDirectory(webappDir);
+
+  const completed = clientConfig
+    ? await syncRemote(webappDir, local, detection, clientConfig, options)
+    : await syncLocal(webappDir, local, wildflyConfig, moduleInfo, options);
+
+  if (completed) {
+    console.log(chalk.gray(`Done in ${Date.now() - start}ms`));
+  }
+  return completed;
+}
+
+async function syncLocal(webappDir, local, wildflyConfig, moduleInfo, options) {
+  const deploymentsDir = path.join(wildflyConfig.root, 'standalone', 'deployments');
+  const names = fs.existsSync(deploymentsDir)
+    ? fs.readdirSync(deploymentsDir, { withFileTypes: true }).filter(entry => entry.isDirectory()).map(entry => entry.name)
+    : [];
+  const targetDir = path.join(deploymentsDir, options.target || pickExplodedDeployment(names, moduleInfo.artifactId, deploymentsDir));
+
+  console.log(`Target: ${targetDir}`);
+  console.log('');
+
+  const deployed = await hashLocalFiles(targetDir, [...local.keys()]);
+  const files = changedFiles(local, deployed);
+  showChanges(files, deployed);
+
+  if (files.length === 0 || options.dryRun) {
+    return true;
+  }
+
+  for (const file of files) {
+    const dest = path.join(targetDir, file);
+    fs.mkdirSync(path.dirname(dest), { recursive: true });
+    fs.copyFileSync(path.join(webappDir, file), dest);
+  }
+  console.log(chalk.green(`Synced ${files.length} file(s)`));
+  return true;
+}
+
+async function syncRemote(webappDir, local, detection, clientConfig, options) {
+  const { project, module: moduleInfo } = detection;
+  const clientName = options.client;
+  const plans = [];
+
+  for (const hostConfig of getClientHosts(clientConfig)) {
+    const deploymentsPath = `${hostConfig.wildfly_path}/standalone/deployments`;
+    let target = options.target;
+    if (!target) {
+      const output = await runRemote(hostConfig, `find ${shellQuote(deploymentsPath)} -mindepth 1 -maxdepth 1 -type d -printf '%f\\n'`);
+      target = pickExplodedDeployment(output.split('\n').filter(Boolean), moduleInfo.artifactId, `${hostConfig.host}:${deploymentsPath}`);
+    }
+    const remoteDir = `${deploymentsPath}/${target}`;
+
+    console.log('');
+    console.log(chalk.blue(`--- ${hostConfig.host}:${remoteDir} ---`));
+    const deployed = await hashRemoteFiles(hostConfig, remoteDir, [...local.keys()]);
+    const files = changedFiles(local, deployed);
+    showChanges(files, deployed);
+
+    if (files.length > 0) {
+      plans.push({ hostConfig, remoteDir, files });
+    }
+  }
+  console.log('');
+
+  if (plans.length === 0 || options.dryRun) {
+    return true;
+  }
+
+  const confirmed = await confirmAction(detection.confirmations, 'sync', {
+    message: `Sync webapp to ${plans.length} host(s)?`,
+    environment: [clientConfig.environment, clientName],
+    expected: clientName
+  });
+  if (!confirmed) {
+    console.log(chalk.red('Sync cancelled'));
+    return false;
+  }
+
+  let completed = true;
+  for (const { hostConfig, remoteDir, files } of plans) {
+    const dirs = [...new Set(files.map(file => path.posix.dirname(`${remoteDir}/${file}`)))];
+    const operations = [
+      { type: 'exec', command: `${getSudoPrefix(hostConfig)}mkdir -p ${dirs.map(shellQuote).join(' ')}`, description: 'Create webapp directories' },
+      ...files.map(file => ({ type: 'upload', source: path.join(webappDir, file), dest: `${remoteDir}/${file}` }))
+    ].map(op => ({ ...op, project, client: clientName, env: clientConfig.environment, host: hostConfig.host }));
+
+    console.log(chalk.blue(`--- ${hostConfig.host} ---`));
+    if (await executeOperations(operations, hostConfig)) {
+      console.log(chalk.green(`Synced ${files.length} file(s)`));
+    } else {
+      completed = false;
+    }
+  }
+
+  return completed;
+}
+
+export {
+  syncWebapp,
+  pickExplodedDeployment
+};
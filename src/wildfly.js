@@ -0,0 +1,129 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import chalk from 'chalk';
+
+import { runRemote, shellQuote } from './remote.js';
+import { createManagementClient } from './mgmt.js';
+
+/**
+ * Product version from a WildFly version.txt ("WildFly Full - Version 26.1.3.Final")
+ */
+function parseVersionText(text) {
+  return text.match(/Version\s+(\S+)/i)?.[1] ?? null;
+}
+
+/**
+ * Version of the local WildFly installation, or null if unknown
+ */
+function readLocalVersion(wildflyRoot) {
+  const versionFile = path.join(wildflyRoot, 'version.txt');
+  return fs.existsSync(versionFile) ? parseVersionText(fs.readFileSync(versionFile, 'utf8')) : null;
+}
+
+/**
+ * WildFly installations on this machine, for suggesting a wildfly_root:
+ * JBOSS_HOME, wildfly* directories in /opt and the home directory, and the
+ * jboss.home.dir of running servers. Returns [{root, version, source}]
+ */
+function findWildflyInstalls() {
+  const candidates = [];
+  if (process.env.JBOSS_HOME) {
+    candidates.push({ root: process.env.JBOSS_HOME, source: 'JBOSS_HOME' });
+  }
+  for (const dir of ['/opt', os.homedir()]) {
+    let entries = [];
+    try {
+      entries = fs.readdirSync(dir).filter(name => name.toLowerCase().startsWith('wildfly')).sort();
+    } catch (error) {
+      continue;
+    }
+    entries.forEach(name => candidates.push({ root: path.join(dir, name), source: dir }));
+  }
+  if (process.platform !== 'win32') {
+    const result = Bun.spawnSync(['ps', '-eo', 'args'], { stdout: 'pipe', stderr: 'ignore' });
+    if (result.exitCode === 0) {
+      for (const [, root] of result.stdout.toString().matchAll(/-Djboss\.home\.dir=(\S+)/g)) {
+        candidates.push({ root, source: 'running server' });
+      }
+    }
+  }
+
+  const cli = process.platform === 'win32' ? 'jboss-cli.bat' : 'jboss-cli.sh';
+  const installs = new Map();
+  for (const { root, source } of candidates) {
+    const resolved = path.resolve(root);
+    if (!installs.has(resolved) && fs.existsSync(path.join(resolved, 'bin', cli))) {
+      installs.set(resolved, { root: resolved, version: readLocalVersion(resolved), source });
+    }
+  }
+  return [...installs.values()];
+}
+
+/**
+ * One line per WildFly installation found, for hints about a missing wildfly_root
+ */
+function describeWildflyInstalls(installs) {
+  return installs.map(({ root, version, source }) => `${root}${version ? ` (${version})` : ''}, from ${source}`);
+}
+
+/**
+ * Version of a remote WildFly, through the management API when configured,
+ * otherwise from version.txt over SSH. Null if it can't be read
+ */
+async function readRemoteVersion(hostConfig, mgmtConfig) {
+  try {
+    if (mgmtConfig) {
+      const client = createManagementClient(mgmtConfig, hostConfig.host);
+      return await client.readAttribute([], 'product-version');
+    }
+    return parseVersionText(await runRemote(hostConfig, `cat ${shellQuote(`${hostConfig.wildfly_path}/version.txt`)}`));
+  } catch (error) {
+    return null;
+  }
+}
+
+/**
+ * Whether a version satisfies an expected version; "26.1" matches "26.1.3.Final"
+ */
+function versionMatches(version, expected) {
+  return version === expected || version.startsWith(`${expected}.`);
+}
+
+/**
+ * Print remote WildFly versions and warn when they differ from expected_version
+ * or from the local installation the artifact was built/tested against
+ * Returns the number of warnings
+ */
+async function checkWildflyVersions(projectConfig, clientConfig, hostConfigs, mgmtConfig) {
+  const expected = clientConfig.expected_version ?? projectConfig.expected_version;
+  const local = projectConfig.wildfly_root ? readLocalVersion(projectConfig.wildfly_root) : null;
+  let warnings = 0;
+
+  for (const hostConfig of hostConfigs) {
+    const version = await readRemoteVersion(hostConfig, mgmtConfig);
+    console.log(chalk.yellow('WildFly:'), `${version ?? 'unknown'}${hostConfigs.length > 1 ? ` (${hostConfig.host})` : ''}`);
+    if (!version) {
+      continue;
+    }
+
+    if (expected && !versionMatches(version, String(expected))) {
+      console.log(chalk.red(`  Warning: ${hostConfig.host} runs WildFly ${version}, expected ${expected}`));
+      warnings++;
+    } else if (!expected && local && version !== local) {
+      console.log(chalk.red(`  Warning: ${hostConfig.host} runs WildFly ${version}, local is ${local}`));
+      warnings++;
+    }
+  }
+
+  return warnings;
+}
+
+export {
+  parseVersionText,
+  readLocalVersion,
+  readRemoteVersion,
+  findWildflyInstalls,
+  describeWildflyInstalls,
+  checkWildflyVersions
+};
@@ -0,0 +1,296 @@
+import fs from 'fs';
+import os from 'os';
+import path from 'path';
+import chalk from 'chalk';
+
+import { loadConfig, findConfigPaths, collectConfigSources, getConfigDir } from './config.js';
+import { getModuleMap, getReactorModules, isWithin, parsePom, asArray } from './detector.js';
+import { locateKey } from './schema.js';
+import { ask, confirm, select, isInteractive } from './confirm.js';
+import { findWildflyInstalls, describeWildflyInstalls } from './wildfly.js';
+
+// How deep below the scanned directory Maven roots are looked for
+const SCAN_DEPTH = 4;
+const SCAN_SKIP = new Set(['node_modules', 'target', 'build', 'dist']);
+
+/**
+ * Ask how the current module deploys and add it to the project's modules map
+ * Returns false when the module is already listed or the wizard was abandoned
+ */
+async function addModuleInteractively(detection) {
+  const { project, projectConfig, module: moduleInfo } = detection;
+  const name = moduleInfo.artifactId;
+
+  if (Object.hasOwn(getModuleMap(projectConfig), name)) {
+    console.log(chalk.yellow(`${name} is already in the modules map of ${project}`));
+    return false;
+  }
+
+  const type = (await ask(`Deploy ${name} as a [n]ormal deployment or a [g]lobal module? (n) `)).trim().toLowerCase();
+  let deploymentPath = '';
+  if (type.startsWith('g')) {
+    const suggested = `modules/${name.toLowerCase()}/main`;
+    deploymentPath = (await ask(`Module path under the WildFly root (${suggested}): `)).trim() || suggested;
+  } else if (type && !type.startsWith('n')) {
+    console.log(chalk.red('Cancelled'));
+    return false;
+  }
+
+  const entry = `${yamlKey(name)}: ${deploymentPath || '""'}`;
+  const source = findProjectSource(project);
+  if (!source) {
+    console.log(chalk.yellow(`Could not find a YAML file defining project ${project}; add this under its modules:`));
+    console.log(`  ${entry}`);
+    return false;
+  }
+
+  const updated = insertModuleEntry(source.text, project, entry);
+  if (updated === null) {
+    console.log(chalk.yellow(`modules of ${project} in ${source.file} is not a block map; add this to it:`));
+    console.log(`  ${entry}`);
+    return false;
+  }
+
+  fs.writeFileSync(source.file, updated);
+  console.log(chalk.green(`Added ${entry} to ${source.file}`));
+  return true;
+}
+
+/**
+ * The YAML config file with the highest precedence that defines a project
+ */
+function findProjectSource(project) {
+  return collectConfigSources()
+    .filter(source => ['.yaml', '.yml'].includes(path.extname(source.file).toLowerCase()))
+    .reverse()
+    .find(source => locateKey(source.text, ['projects', project])?.depth === 2);
+}
+
+/**
+ * Insert a line into a project's modules map, creating the map if needed,
+ * leaving the rest of the file (comments, formatting, line endings) untouched
+ * Returns null when modules is written in flow style
+ */
+function insertModuleEntry(text, project, entry) {
+  const eol = text.includes('\r\n') ? '\r\n' : '\n';
+  const lines = text.split(/\r?\n/);
+  const modules = locateKey(text, ['projects', project, 'modules']);
+
+  if (modules?.depth === 3) {
+    const line = lines[modules.line - 1];
+    if (line.slice(line.indexOf(':') + 1).replace(/#.*/, '').trim()) {
+      return null;
+    }
+    const indent = childIndent(lines, modules.line, modules.column - 1) ?? modules.column + 1;
+    lines.splice(modules.line, 0, ' '.repeat(indent) + entry);
+    return lines.join(eol);
+  }
+
+  const projectLocation = locateKey(text, ['projects', project]);
+  const indent = childIndent(lines, projectLocation.line, projectLocation.column - 1) ?? projectLocation.column + 1;
+  lines.splice(projectLocation.line, 0, ' '.repeat(indent) + 'modules:', ' '.repeat(indent + 2) + entry);
+  return lines.join(eol);
+}
+
+/**
+ * Add a key as the first child of a block map (e.g. a client) in the YAML file with
+ * the highest precedence that defines the map, leaving the rest of the file untouched
+ * Returns the file written, or null when no file defines the map as a block, or the key is already set
+ */
+function addConfigEntry(keyPath, key, value) {
+  const source = collectConfigSources()
+    .filter(source => ['.yaml', '.yml'].includes(path.extname(source.file).toLowerCase()))
+    .reverse()
+    .find(source => locateKey(source.text, keyPath)?.depth === keyPath.length);
+  if (!source || locateKey(source.text, [...keyPath, key])?.depth === keyPath.length + 1) {
+    return null;
+  }
+
+  const eol = source.text.includes('\r\n') ? '\r\n' : '\n';
+  const lines = source.text.split(/\r?\n/);
+  const parent = locateKey(source.text, keyPath);
+  const line = lines[parent.line - 1];
+  if (line.slice(line.indexOf(':') + 1).replace(/#.*/, '').trim()) {
+    return null;
+  }
+  const indent = childIndent(lines, parent.line, parent.column - 1) ?? parent.column + 1;
+  lines.splice(parent.line, 0, `${' '.repeat(indent)}${yamlKey(key)}: ${value}`);
+  fs.writeFileSync(source.file, lines.join(eol));
+  return source.file;
+}
+
+/**
+ * Indentation of the first child line after a key line, or null if it has none
+ */
+function childIndent(lines, keyLine, keyIndent) {
+  for (const line of lines.slice(keyLine)) {
+    const indent = line.search(/\S/);
+    if (indent === -1 || line.trimStart().startsWith('#')) continue;
+    return indent > keyIndent ? indent : null;
+  }
+  return null;
+}
+
+/**
+ * Find Maven roots (a pom.xml whose parent directory has none) below a directory
+ */
+function findMavenRoots(dir, depth = 0) {
+  if (fs.existsSync(path.join(dir, 'pom.xml'))) {
+    return [dir];
+  }
+  if (depth >= SCAN_DEPTH) {
+    return [];
+  }
+
+  let entries;
+  try {
+    entries = fs.readdirSync(dir, { withFileTypes: true });
+  } catch (error) {
+    return [];
+  }
+  return entries
+    .filter(entry => entry.isDirectory() && !entry.name.startsWith('.') && !SCAN_SKIP.has(entry.name))
+    .sort((a, b) => a.name.localeCompare(b.name))
+    .flatMap(entry => findMavenRoots(path.join(dir, entry.name), depth + 1));
+}
+
+/**
+ * Project entry proposed for a Maven root: aggregators build their modules
+ * together, each POM profile becomes a Maven profile of the same name, and
+ * the local WildFly is used when exactly one was found
+ */
+function proposeProject(root, wildflyRoot) {
+  const pom = parsePom(path.join(root, 'pom.xml'));
+  const profiles = asArray(pom.project?.profiles?.profile).map(profile => profile.id).filter(Boolean);
+  const home = os.homedir();
+
+  return {
+    name: path.basename(root).toLowerCase().replace(/[^a-z0-9_-]+/g, '-'),
+    settings: {
+      base_path: isWithin(root, home) && root !== home ? '~' + root.slice(home.length).split(path.sep).join('/') : root,
+      single_repo: asArray(pom.project?.modules?.module).length > 0,
+      skip_tests: true,
+      ...(wildflyRoot ? { wildfly_root: wildflyRoot } : {}),
+      ...(profiles.length > 0 ? { maven_profiles: Object.fromEntries(profiles.map(id => [id, [id]])) } : {})
+    }
+  };
+}
+
+/**
+ * Render a proposed project as YAML lines at the given indentation
+ */
+function renderProject(proposal, indent) {
+  const pad = ' '.repeat(indent);
+  const lines = [`${pad}${yamlKey(proposal.name)}:`];
+  for (const [key, value] of Object.entries(proposal.settings)) {
+    if (key === 'maven_profiles') {
+      lines.push(`${pad}  ${key}:`);
+      Object.entries(value).forEach(([id, list]) => lines.push(`${pad}    ${yamlKey(id)}: [${list.map(yamlKey).join(', ')}]`));
+    } else {
+      lines.push(`${pad}  ${key}: ${yamlValue(value)}`);
+    }
+  }
+  return lines;
+}
+
+/**
+ * Find Maven projects below a directory that no configured project covers,
+ * and append them to the personal config after confirmation
+ */
+async function scanWorkspace(dir) {
+  const configured = Object.values(loadConfig().projects || {}).map(project => project.base_path).filter(Boolean);
+  const roots = findMavenRoots(path.resolve(dir))
+    .filter(root => !configured.some(basePath => isWithin(root, path.resolve(basePath))));
+
+  if (roots.length === 0) {
+    console.log(chalk.yellow('No unconfigured Maven projects found'));
+    return false;
+  }
+
+  const installs = findWildflyInstalls();
+  const proposals = roots.map(root => proposeProject(root, installs.length === 1 ? installs[0].root : null));
+  proposals.forEach(proposal => {
+    console.log(renderProject(proposal, 2).join('\n'));
+    console.log('');
+  });
+
+  const configPath = findConfigPaths().filter(file => ['.yaml', '.yml'].includes(path.extname(file).toLowerCase())).pop()
+    ?? path.join(getConfigDir(), 'config.yaml');
+  if (!(await confirm(`Add ${proposals.length} project(s) to ${configPath}?`))) {
+    console.log(chalk.red('Cancelled'));
+    return false;
+  }
+
+  const text = fs.existsSync(configPath) ? fs.readFileSync(configPath, 'utf8') : 'projects:\n';
+  const eol = text.includes('\r\n') ? '\r\n' : '\n';
+  const lines = text.split(/\r?\n/);
+  const projects = locateKey(text, ['projects']);
+
+  if (projects) {
+    const indent = childIndent(lines, projects.line, projects.column - 1) ?? projects.column + 1;
+    lines.splice(projects.line, 0, ...proposals.flatMap(proposal => renderProject(proposal, indent)));
+  } else {
+    if (lines[lines.length - 1] === '') lines.pop();
+    lines.push('projects:', ...proposals.flatMap(proposal => renderProject(proposal, 2)), '');
+  }
+
+  fs.mkdirSync(path.dirname(configPath), { recursive: true });
+  fs.writeFileSync(configPath, lines.join(eol));
+  console.log(chalk.green(`Added ${proposals.map(proposal => proposal.name).join(', ')} to ${configPath}`));
+  if (installs.length === 1) {
+    console.log(chalk.gray('Set clients for each before deploying'));
+  } else {
+    console.log(chalk.gray('Set wildfly_root and clients for each before deploying'));
+    describeWildflyInstalls(installs).forEach(line => console.log(chalk.gray(`  WildFly found at ${line}`)));
+  }
+  return true;
+}
+
+/**
+ * Let the user pick a module below an aggregator POM, filtering the list by typing
+ * part of a name. Returns the module directory, 'all' to build every child, 'self'
+ * to only install the aggregator POM, or null when cancelled
+ */
+async function pickModule(detection) {
+  const aggregator = detection.module.path;
+  const candidates = getReactorModules(detection.pomPath)
+    .filter(module => module.packaging !== 'pom')
+    .map(module => ({ ...module, label: path.relative(aggregator, module.path) }));
+
+  if (!isInteractive()) {
+    const modules = candidates.length > 0 ? `: ${candidates.map(module => module.label).join(', ')}` : '';
+    throw new Error(`${detection.module.artifactId} is an aggregator, run jmw in one of its modules${modules}`);
+  }
+
+  // Children that are all aggregators themselves leave only the two whole-tree choices
+  return select(`Module of ${detection.module.artifactId}`, [
+    ...candidates.map(module => ({ label: module.label, value: module.path, hint: `(${module.packaging})` })),
+    { label: 'all of them', value: 'all' },
+    { label: 'only the aggregator POM', value: 'self', hint: '(-N)' }
+  ]);
+}
+
+function yamlKey(name) {
+  return /^[A-Za-z0-9_.-]+$/.test(name) ? name : JSON.stringify(name);
+}
+
+/**
+ * YAML scalar for a setting; strings that aren't plainly safe (spaces, '#', ': ',
+ * Windows backslashes, values YAML would read as booleans or numbers) are single-quoted
+ */
+function yamlValue(value) {
+  if (typeof value !== 'string') {
+    return String(value);
+  }
+  const plain = /^[A-Za-z0-9_.\/~-]+$/.test(value) && !/^(true|false|yes|no|on|off|null|~|[-+]?[0-9.]+)$/i.test(value);
+  return plain ? value : `'${value.replace(/'/g, "''")}'`;
+}
+
+export {
+  addModuleInteractively,
+  addConfigEntry,
+  insertModuleEntry,
+  findMavenRoots,
+  scanWorkspace,
+  pickModule
+};
@@ -0,0 +1,78 @@
+import fs from 'fs';
+import zlib from 'zlib';
+
+const EOCD_SIGNATURE = 0x06054b50;
+const CENTRAL_SIGNATURE = 0x02014b50;
+
+/**
+ * Read the central directory of a JAR/WAR/EAR
+ * Returns entries with name, crc32, sizes, DOS timestamp and local header offset
+ */
+function readZipEntries(zipPath) {
+  const buffer = fs.readFileSync(zipPath);
+
+  // End of central directory is within the last 64KB + 22 bytes
+  let eocd = -1;
+  for (let i = buffer.length - 22; i >= Math.max(0, buffer.length - 65557); i--) {
+    if (buffer.readUInt32LE(i) === EOCD_SIGNATURE) {
+      eocd = i;
+      break;
+    }
+  }
+
+  if (eocd === -1) {
+    throw new Error(`Not a zip archive: ${zipPath}`);
+  }
+
+  const count = buffer.readUInt16LE(eocd + 10);
+  let offset = buffer.readUInt32LE(eocd + 16);
+  const entries = [];
+
+  for (let i = 0; i < count; i++) {
+    if (buffer.readUInt32LE(offset) !== CENTRAL_SIGNATURE) {
+      throw new Error(`Corrupt central directory in ${zipPath}`);
+    }
+
+    const nameLength = buffer.readUInt16LE(offset + 28);
+    const extraLength = buffer.readUInt16LE(offset + 30);
+    const commentLength = buffer.readUInt16LE(offset + 32);
+
+    entries.push({
+      name: buffer.toString('utf8', offset + 46, offset + 46 + nameLength),
+      method: buffer.readUInt16LE(offset + 10),
+      dosTime: buffer.readUInt16LE(offset + 12),
+      dosDate: buffer.readUInt16LE(offset + 14),
+      crc32: buffer.readUInt32LE(offset + 16),
+      compressedSize: buffer.readUInt32LE(offset + 20),
+      size: buffer.readUInt32LE(offset + 24),
+      localOffset: buffer.readUInt32LE(offset + 42)
+    });
+
+    offset += 46 + nameLength + extraLength + commentLength;
+  }
+
+  return entries;
+}
+
+/**
+ * Read the uncompressed content of a single zip entry
+ */
+function readZipEntry(zipPath, entryName) {
+  const entry = readZipEntries(zipPath).find(e => e.name === entryName);
+  if (!entry) {
+    return null;
+  }
+
+  const buffer = fs.readFileSync(zipPath);
+  const nameLength = buffer.readUInt16LE(entry.localOffset + 26);
+  const extraLength = buffer.readUInt16LE(entry.localOffset + 28);
+  const start = entry.localOffset + 30 + nameLength + extraLength;
+  const data = buffer.subarray(start, start + entry.compressedSize);
+
+  return entry.method === 8 ? zlib.inflateRawSync(data) : Buffer.from(data);
+}
+
+export {
+  readZipEntries,
+  readZipEntry
+};